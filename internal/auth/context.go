@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultContext is the context `deploys config set` edits when none is
+// active yet.
+const DefaultContext = "default"
+
+// Context bundles the defaults a command falls back to: the api endpoint and
+// account (which pick the stored login), and the project and location that
+// fill an unset -project/-location.
+type Context struct {
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Account  string `json:"account,omitempty" yaml:"account,omitempty"`
+	Project  string `json:"project,omitempty" yaml:"project,omitempty"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// ContextKeys are the settings a context holds, in display order.
+var ContextKeys = []string{"endpoint", "account", "project", "location"}
+
+// Get returns the setting named key (one of ContextKeys).
+func (c *Context) Get(key string) (string, error) {
+	p, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	return *p, nil
+}
+
+// Set changes the setting named key; an empty value clears it.
+func (c *Context) Set(key, value string) error {
+	p, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	*p = value
+	return nil
+}
+
+func (c *Context) field(key string) (*string, error) {
+	switch key {
+	case "endpoint":
+		return &c.Endpoint, nil
+	case "account":
+		return &c.Account, nil
+	case "project":
+		return &c.Project, nil
+	case "location":
+		return &c.Location, nil
+	}
+	return nil, fmt.Errorf("unknown setting %q (want endpoint, account, project, or location)", key)
+}
+
+// Contexts is the on-disk context store: the named contexts and which one is
+// current.
+type Contexts struct {
+	Version  int                 `json:"version"`
+	Current  string              `json:"current,omitempty"`
+	Contexts map[string]*Context `json:"contexts"`
+}
+
+// Names returns the context names, sorted.
+func (c *Contexts) Names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for n := range c.Contexts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contextsPath() (string, error) {
+	d, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "contexts.json"), nil
+}
+
+// LoadContexts reads the context store. A missing file is an empty store; a
+// malformed one is an error, like the credentials file.
+func LoadContexts() (*Contexts, error) {
+	path, err := contextsPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := readFileSecure(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Contexts{Version: schemaVersion, Contexts: map[string]*Context{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Contexts
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("contexts file %s is corrupt: %w (inspect or remove it)", path, err)
+	}
+	if c.Version > schemaVersion {
+		return nil, fmt.Errorf("contexts file %s is version %d; upgrade the deploys cli", path, c.Version)
+	}
+	if c.Contexts == nil {
+		c.Contexts = map[string]*Context{}
+	}
+	return &c, nil
+}
+
+// Save writes the context store atomically with 0600 permissions.
+func (c *Contexts) Save() error {
+	c.Version = schemaVersion
+	path, err := contextsPath()
+	if err != nil {
+		return err
+	}
+	if err := prepareDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// MutateContexts runs fn against the context store under an exclusive lock,
+// then saves.
+func MutateContexts(fn func(*Contexts) error) error {
+	unlock, err := acquireLock("contexts.json.lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c, err := LoadContexts()
+	if err != nil {
+		return err
+	}
+	if err := fn(c); err != nil {
+		return err
+	}
+	return c.Save()
+}
+
+// ActiveContext returns the context in effect: the one DEPLOYS_CONTEXT names,
+// else the current one. No active context is ("", nil, nil); a name that is
+// not in the store is an error, so a typo never silently targets nothing.
+func ActiveContext() (string, *Context, error) {
+	c, err := LoadContexts()
+	if err != nil {
+		return "", nil, err
+	}
+	name := os.Getenv("DEPLOYS_CONTEXT")
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("context %q not found (see 'deploys context list')", name)
+	}
+	return name, ctx, nil
+}
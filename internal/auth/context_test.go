@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActiveContext(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Setenv("DEPLOYS_CONTEXT", "")
+
+	// no store: no active context, not an error
+	if name, c, err := ActiveContext(); err != nil || name != "" || c != nil {
+		t.Fatalf("empty store = %q, %v, %v", name, c, err)
+	}
+
+	err := MutateContexts(func(c *Contexts) error {
+		c.Contexts["prod"] = &Context{Project: "acme", Location: "loc"}
+		c.Contexts["staging"] = &Context{Project: "acme-stg", Endpoint: "https://api.staging/"}
+		c.Current = "prod"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, c, err := ActiveContext()
+	if err != nil || name != "prod" || c.Project != "acme" {
+		t.Fatalf("current = %q, %+v, %v", name, c, err)
+	}
+
+	t.Setenv("DEPLOYS_CONTEXT", "staging")
+	name, c, err = ActiveContext()
+	if err != nil || name != "staging" || c.Endpoint != "https://api.staging/" {
+		t.Fatalf("DEPLOYS_CONTEXT = %q, %+v, %v", name, c, err)
+	}
+
+	// a typo is an error, never a silent fallback
+	t.Setenv("DEPLOYS_CONTEXT", "stagign")
+	if _, _, err := ActiveContext(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("unknown context err = %v", err)
+	}
+}
+
+func TestContextSet(t *testing.T) {
+	var c Context
+	if err := c.Set("location", "loc"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("location"); v != "loc" {
+		t.Errorf("location = %q", v)
+	}
+	if err := c.Set("region", "x"); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
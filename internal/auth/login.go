@@ -18,6 +18,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/deploys-app/deploys/internal/wire"
 )
 
 // loopbackRedirect is the registered redirect URI. It is port-less: the auth
@@ -517,7 +519,7 @@ func defaultOpenBrowser(u string) error {
 }
 
 func httpClient() *http.Client {
-	return &http.Client{Timeout: 20 * time.Second}
+	return &http.Client{Timeout: 20 * time.Second, Transport: wire.Transport(nil)}
 }
 
 func drain(resp *http.Response) {
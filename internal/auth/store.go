@@ -403,6 +403,13 @@ func readFileSecure(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// Lock takes the named lockfile in the config dir the way the stores here do,
+// for other files kept beside them (the operation journal). Call the returned
+// func to release it.
+func Lock(name string) (func(), error) {
+	return acquireLock(name)
+}
+
 // acquireLock takes an exclusive O_EXCL lockfile (portable, unlike flock, which
 // Go cannot do cross-platform). A lockfile whose mtime is older than lockStale is
 // treated as abandoned and reclaimed, so a crashed or interrupted command can't
@@ -434,7 +441,7 @@ func acquireLock(name string) (func(), error) {
 			continue
 		}
 		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("the credential store is locked (%s); another deploys process may be running — remove the lock file if not", lockPath)
+			return nil, fmt.Errorf("%s is held by another deploys process; remove it if none is running", lockPath)
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
@@ -0,0 +1,436 @@
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+// defaultPort is the port a new WebService listens on when the deploy names
+// none.
+const defaultPort = 8080
+
+type deploymentService struct{ a *API }
+
+// revisions returns a deployment's revisions, oldest first.
+func (st *State) revisions(project, location, name string) []*api.DeploymentItem {
+	var xs []*api.DeploymentItem
+	for _, d := range st.Deployments {
+		if d.Project == project && d.Location == location && d.Name == name {
+			xs = append(xs, d)
+		}
+	}
+	return xs
+}
+
+// deployment returns a deployment's latest revision.
+func (st *State) deployment(project, location, name string) *api.DeploymentItem {
+	xs := st.revisions(project, location, name)
+	if len(xs) == 0 {
+		return nil
+	}
+	return xs[len(xs)-1]
+}
+
+// latestDeployments returns the latest revision of each of a project's
+// deployments, in location (when given), sorted by name.
+func (st *State) latestDeployments(project, location string) []*api.DeploymentItem {
+	latest := map[string]*api.DeploymentItem{}
+	for _, d := range st.Deployments {
+		if d.Project != project || (location != "" && d.Location != location) {
+			continue
+		}
+		latest[d.Location+"/"+d.Name] = d
+	}
+	xs := slices.Collect(maps.Values(latest))
+	slices.SortFunc(xs, func(a, b *api.DeploymentItem) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Location, b.Location))
+	})
+	return xs
+}
+
+// release appends d as the deployment's next revision, rolled out at once.
+func (st *State) release(d *api.DeploymentItem, prev *api.DeploymentItem) {
+	d.Revision = 1
+	if prev != nil {
+		d.Revision = prev.Revision + 1
+	}
+	t := now()
+	d.Status = api.Success
+	d.Action = api.DeploymentActionDeploy
+	d.CreatedAt = t
+	d.CreatedBy = st.Email
+	d.SuccessAt = t
+	st.Deployments = append(st.Deployments, d)
+}
+
+// Deploy rolls a new revision: the request's fields over the previous
+// revision's, as the api merges them.
+func (s deploymentService) Deploy(_ context.Context, m *api.DeploymentDeploy) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		loc, err := st.checkLocation(m.Project, m.Location)
+		if err != nil {
+			return err
+		}
+		for _, g := range slices.Concat(m.EnvGroups, m.AddEnvGroups) {
+			if st.envGroup(m.Project, g) == nil {
+				return api.ErrEnvGroupNotFound
+			}
+		}
+
+		prev := st.deployment(m.Project, m.Location, m.Name)
+		d := clone(prev)
+		if d == nil {
+			if len(st.latestDeployments(m.Project, "")) >= st.project(m.Project).Quota.Deployments {
+				return api.ErrMaximumDeploymentReach
+			}
+			d = &api.DeploymentItem{
+				Project:     m.Project,
+				Location:    m.Location,
+				Name:        m.Name,
+				Type:        cmp.Or(m.Type, api.DeploymentTypeWebService),
+				MinReplicas: 1,
+				MaxReplicas: 1,
+			}
+			if d.Type == api.DeploymentTypeWebService {
+				d.Port = defaultPort
+			}
+		} else if m.Type != 0 && m.Type != d.Type {
+			return api.ErrTypeNotAllowChange
+		}
+
+		d.Image = m.Image
+		d.Site = m.Site
+		d.SiteManifestDigest = m.SiteManifestDigest
+		if m.Env != nil {
+			d.Env = maps.Clone(m.Env)
+		}
+		if len(m.AddEnv) > 0 || len(m.RemoveEnv) > 0 {
+			if d.Env == nil {
+				d.Env = map[string]string{}
+			}
+			maps.Copy(d.Env, m.AddEnv)
+			for _, k := range m.RemoveEnv {
+				delete(d.Env, k)
+			}
+		}
+		if m.EnvGroups != nil {
+			d.EnvGroups = slices.Clone(m.EnvGroups)
+		}
+		for _, g := range m.AddEnvGroups {
+			if !slices.Contains(d.EnvGroups, g) {
+				d.EnvGroups = append(d.EnvGroups, g)
+			}
+		}
+		d.EnvGroups = slices.DeleteFunc(d.EnvGroups, func(g string) bool { return slices.Contains(m.RemoveEnvGroups, g) })
+		if m.Command != nil {
+			d.Command = slices.Clone(m.Command)
+		}
+		if m.Args != nil {
+			d.Args = slices.Clone(m.Args)
+		}
+		if m.MountData != nil {
+			d.MountData = maps.Clone(m.MountData)
+		}
+		if m.Sidecars != nil {
+			d.Sidecars = *clone(&m.Sidecars)
+		}
+		if m.MinReplicas != nil {
+			d.MinReplicas = *m.MinReplicas
+		}
+		if m.MaxReplicas != nil {
+			d.MaxReplicas = *m.MaxReplicas
+		}
+		if d.MaxReplicas < d.MinReplicas {
+			return api.ErrReplicasInvalid
+		}
+		if m.Port != nil {
+			d.Port = *m.Port
+		}
+		if m.Protocol != nil {
+			d.Protocol = *m.Protocol
+		}
+		if m.Internal != nil {
+			d.Internal = *m.Internal
+		}
+		if m.WorkloadIdentity != nil {
+			d.WorkloadIdentity = *m.WorkloadIdentity
+		}
+		if m.PullSecret != nil {
+			d.PullSecret = *m.PullSecret
+		}
+		if m.Disk != nil {
+			d.Disk = clone(m.Disk)
+		}
+		if m.Schedule != nil {
+			d.Schedule = *m.Schedule
+		}
+		if m.Resources != nil {
+			d.Resources = *m.Resources
+		}
+		if m.Access != nil {
+			d.Access = clone(m.Access)
+		}
+		if m.TTL != nil {
+			d.TTL = *m.TTL
+		}
+		d.ExpiresAt = time.Time{}
+		if d.TTL > 0 {
+			d.ExpiresAt = now().Add(time.Duration(d.TTL) * time.Second)
+		}
+		d.URL = ""
+		if d.Type == api.DeploymentTypeWebService || d.Type == api.DeploymentTypeStatic {
+			d.URL = fmt.Sprintf("https://%s-%s%s", d.Name, d.Project, loc.DomainSuffix)
+		}
+		st.release(d, prev)
+		return nil
+	}))
+}
+
+func (s deploymentService) List(_ context.Context, m *api.DeploymentList) (*api.DeploymentListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.DeploymentListResult{Items: []*api.DeploymentListItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, d := range st.latestDeployments(m.Project, m.Location) {
+			res.Items = append(res.Items, listItem(d))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// listItem is the deployment.list projection of d.
+func listItem(d *api.DeploymentItem) *api.DeploymentListItem {
+	return &api.DeploymentListItem{
+		Project:            d.Project,
+		Location:           d.Location,
+		Name:               d.Name,
+		Type:               d.Type,
+		Revision:           d.Revision,
+		Image:              d.Image,
+		Site:               d.Site,
+		SiteManifestDigest: d.SiteManifestDigest,
+		MinReplicas:        d.MinReplicas,
+		MaxReplicas:        d.MaxReplicas,
+		Schedule:           d.Schedule,
+		Port:               d.Port,
+		Protocol:           d.Protocol,
+		Internal:           d.Internal,
+		Resources:          d.Resources,
+		URL:                d.URL,
+		Status:             d.Status,
+		Action:             d.Action,
+		AllocatedPrice:     d.AllocatedPrice,
+		CreatedAt:          d.CreatedAt,
+		CreatedBy:          d.CreatedBy,
+		SuccessAt:          d.SuccessAt,
+		TTL:                d.TTL,
+		ExpiresAt:          d.ExpiresAt,
+		ReleaseURL:         d.ReleaseURL,
+	}
+}
+
+func (s deploymentService) Get(_ context.Context, m *api.DeploymentGet) (*api.DeploymentItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.DeploymentItem
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		xs := st.revisions(m.Project, m.Location, m.Name)
+		if m.Revision > 0 {
+			xs = slices.DeleteFunc(xs, func(d *api.DeploymentItem) bool { return d.Revision != int64(m.Revision) })
+		}
+		if len(xs) == 0 {
+			return api.ErrDeploymentNotFound
+		}
+		res = clone(xs[len(xs)-1])
+		return nil
+	})
+	return res, err
+}
+
+// Revisions lists a deployment's revisions, newest first.
+func (s deploymentService) Revisions(_ context.Context, m *api.DeploymentRevisions) (*api.DeploymentRevisionsResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res api.DeploymentRevisionsResult
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		xs := st.revisions(m.Project, m.Location, m.Name)
+		if len(xs) == 0 {
+			return api.ErrDeploymentNotFound
+		}
+		for _, d := range slices.Backward(xs) {
+			res.Items = append(res.Items, clone(d))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// latest runs fn on a deployment's latest revision.
+func (s deploymentService) latest(project, location, name string, fn func(st *State, d *api.DeploymentItem) error) error {
+	return s.a.update(func(st *State) error {
+		if err := st.checkProject(project); err != nil {
+			return err
+		}
+		d := st.deployment(project, location, name)
+		if d == nil {
+			return api.ErrDeploymentNotFound
+		}
+		return fn(st, d)
+	})
+}
+
+func (s deploymentService) Resume(_ context.Context, m *api.DeploymentResume) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(_ *State, d *api.DeploymentItem) error {
+		if d.Action != api.DeploymentActionPause {
+			return api.ErrCanNotResume
+		}
+		d.Action = api.DeploymentActionDeploy
+		return nil
+	}))
+}
+
+func (s deploymentService) Pause(_ context.Context, m *api.DeploymentPause) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(_ *State, d *api.DeploymentItem) error {
+		if d.Action == api.DeploymentActionPause {
+			return api.ErrCanNotPause
+		}
+		d.Action = api.DeploymentActionPause
+		return nil
+	}))
+}
+
+func (s deploymentService) Restart(_ context.Context, m *api.DeploymentRestart) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(_ *State, d *api.DeploymentItem) error {
+		if d.Action == api.DeploymentActionPause || d.Type == api.DeploymentTypeStatic {
+			return api.ErrCanNotRestart
+		}
+		return nil
+	}))
+}
+
+// Rollback rolls a new revision with the given revision's spec.
+func (s deploymentService) Rollback(_ context.Context, m *api.DeploymentRollback) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(st *State, prev *api.DeploymentItem) error {
+		revs := st.revisions(m.Project, m.Location, m.Name)
+		i := slices.IndexFunc(revs, func(d *api.DeploymentItem) bool { return d.Revision == int64(m.Revision) })
+		if i < 0 {
+			return api.ErrDeploymentNotFound
+		}
+		st.release(clone(revs[i]), prev)
+		return nil
+	}))
+}
+
+func (s deploymentService) Delete(_ context.Context, m *api.DeploymentDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(st *State, _ *api.DeploymentItem) error {
+		st.Deployments = slices.DeleteFunc(st.Deployments, func(d *api.DeploymentItem) bool {
+			return d.Project == m.Project && d.Location == m.Location && d.Name == m.Name
+		})
+		return nil
+	}))
+}
+
+func (s deploymentService) ExtendTTL(_ context.Context, m *api.DeploymentExtendTTL) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.latest(m.Project, m.Location, m.Name, func(_ *State, d *api.DeploymentItem) error {
+		d.TTL = m.TTL
+		d.ExpiresAt = now().Add(time.Duration(m.TTL) * time.Second)
+		return nil
+	}))
+}
+
+// Status reports every replica of a running deployment ready; a paused one
+// (or a CronJob between runs) has no pods.
+func (s deploymentService) Status(_ context.Context, m *api.DeploymentStatus) (*api.DeploymentStatusResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.DeploymentStatusResult{Pods: []api.DeploymentPodStatus{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		d := st.deployment(m.Project, m.Location, m.Name)
+		switch {
+		case d == nil:
+			return api.ErrDeploymentNotFound
+		case d.Type == api.DeploymentTypeStatic:
+			return api.ErrDeploymentNoStatusForStatic
+		case d.Action == api.DeploymentActionPause, d.Type == api.DeploymentTypeCronJob:
+			return nil
+		}
+		for i := range max(d.MinReplicas, 1) {
+			res.Pods = append(res.Pods, api.DeploymentPodStatus{
+				Name:      fmt.Sprintf("%s-%d-%d", d.Name, d.Revision, i),
+				Phase:     "Running",
+				Ready:     true,
+				Container: d.Name,
+			})
+		}
+		res.Count = len(res.Pods)
+		res.Ready = len(res.Pods)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (deploymentService) Metrics(context.Context, *api.DeploymentMetrics) (*api.DeploymentMetricsResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (deploymentService) Logs(context.Context, *api.DeploymentLogs) (*api.DeploymentLogsResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (deploymentService) LogsHistory(context.Context, *api.DeploymentLogsHistory) (*api.DeploymentLogsHistoryResult, error) {
+	return nil, ErrNotImplemented
+}
@@ -0,0 +1,366 @@
+// Package devserver implements api.Interface in memory, for running scripts
+// and tests against a local, offline stand-in for the deploys api.
+//
+// It keeps projects, locations, deployments (with their revisions), routes,
+// env groups, domains, scheduler jobs, and notification channels, and applies
+// the api's validation and not-found/in-use errors to them. Nothing runs:
+// a deploy succeeds at once, a scheduler trigger or notification test records
+// a successful attempt without calling out. Services and methods outside that
+// set return ErrNotImplemented (or, for whole services, are left nil).
+//
+// The state is one JSON document (State). An API opened on a file writes it
+// back after every change, and the same format seeds a fresh server.
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+// ErrNotImplemented is returned by the methods the dev server does not model.
+var ErrNotImplemented = errors.New("devserver: not implemented")
+
+// DefaultEmail is the user the dev server acts as when the state names none.
+const DefaultEmail = "dev@localhost"
+
+// State is everything the dev server holds. Deployments lists every revision,
+// oldest first.
+type State struct {
+	Email         string                 `json:"email"`
+	Locations     []*api.LocationItem    `json:"locations"`
+	Projects      []*api.ProjectItem     `json:"projects"`
+	Deployments   []*api.DeploymentItem  `json:"deployments"`
+	Routes        []*Route               `json:"routes"`
+	EnvGroups     []*api.EnvGroupItem    `json:"envGroups"`
+	Domains       []*api.DomainItem      `json:"domains"`
+	Schedulers    []*SchedulerJob        `json:"schedulers"`
+	Notifications []*NotificationChannel `json:"notifications"`
+}
+
+// Route is a route with the project it belongs to, which api.RouteItem does
+// not carry.
+type Route struct {
+	Project string `json:"project"`
+	api.RouteItem
+}
+
+// SchedulerJob is a scheduler job with its invocations, newest first. The
+// job's auth secret is kept, and left out of what the api returns.
+type SchedulerJob struct {
+	api.SchedulerItem
+	Invocations []*api.SchedulerInvocation `json:"invocations,omitempty"`
+}
+
+// NotificationChannel is a notification channel with its deliveries, newest
+// first. The config's secret is kept, and left out of what the api returns.
+type NotificationChannel struct {
+	api.NotificationItem
+	Deliveries []*api.NotificationDelivery `json:"deliveries,omitempty"`
+}
+
+// DefaultState is a fresh server's state: the default location and an empty
+// "dev-project" project.
+func DefaultState() *State {
+	now := time.Now().UTC()
+	return &State{
+		Email: DefaultEmail,
+		Locations: []*api.LocationItem{{
+			ID:                "gke.cluster-rcf2",
+			DomainSuffix:      ".localhost",
+			Endpoint:          "localhost",
+			CName:             "localhost",
+			FreeTier:          true,
+			CPUAllocatable:    []string{"100m", "250m", "500m", "1", "2"},
+			MemoryAllocatable: []string{"128Mi", "256Mi", "512Mi", "1Gi", "2Gi"},
+			CreatedAt:         now,
+		}},
+		Projects: []*api.ProjectItem{{
+			ID:             1,
+			Project:        "dev-project",
+			Name:           "Dev Project",
+			BillingAccount: 1,
+			Quota:          api.ProjectQuota{Deployments: 100, DeploymentMaxReplicas: 20},
+			CreatedAt:      now,
+		}},
+	}
+}
+
+// LoadState reads a state file.
+func LoadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("devserver: %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// API is the in-memory api. It is safe for concurrent use.
+type API struct {
+	api.Interface // nil: the services not modeled
+
+	mu    sync.Mutex
+	state *State
+	path  string
+}
+
+// New returns an API holding st, or DefaultState when st is nil. It keeps
+// st, so a caller may read it back after the calls it makes.
+func New(st *State) *API {
+	if st == nil {
+		st = DefaultState()
+	}
+	st.Email = cmp.Or(st.Email, DefaultEmail)
+	return &API{state: st}
+}
+
+// Open returns an API persisted to path: the state is read from it when the
+// file exists, else it starts as seed (DefaultState when nil) and is written
+// there.
+func Open(path string, seed *State) (*API, error) {
+	st, err := LoadState(path)
+	if errors.Is(err, os.ErrNotExist) {
+		a := New(seed)
+		a.path = path
+		return a, a.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	a := New(st)
+	a.path = path
+	return a, nil
+}
+
+// State returns a copy of the current state.
+func (a *API) State() *State {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return clone(a.state)
+}
+
+// view runs fn on the state.
+func (a *API) view(fn func(st *State) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return fn(a.state)
+}
+
+// update runs fn on the state and, when it succeeds, persists the state.
+func (a *API) update(fn func(st *State) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := fn(a.state); err != nil {
+		return err
+	}
+	return a.save()
+}
+
+func (a *API) save() error {
+	if a.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(a.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (a *API) Me() api.Me                     { return meService{a} }
+func (a *API) Location() api.Location         { return locationService{a} }
+func (a *API) Project() api.Project           { return projectService{a} }
+func (a *API) Deployment() api.Deployment     { return deploymentService{a} }
+func (a *API) Route() api.Route               { return routeService{a} }
+func (a *API) EnvGroup() api.EnvGroup         { return envGroupService{a} }
+func (a *API) Domain() api.Domain             { return domainService{a} }
+func (a *API) Scheduler() api.Scheduler       { return schedulerService{a} }
+func (a *API) Notification() api.Notification { return notificationService{a} }
+
+// valid runs the request's own validation, as the api client does before
+// sending it.
+func valid(m any) error {
+	if v, ok := m.(interface{ Valid() error }); ok {
+		return v.Valid()
+	}
+	return nil
+}
+
+// clone deep-copies v through its json encoding, so what a caller gets
+// shares nothing with the state.
+func clone[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var c T
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return &c
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func find[T any](xs []*T, match func(*T) bool) *T {
+	if i := slices.IndexFunc(xs, match); i >= 0 {
+		return xs[i]
+	}
+	return nil
+}
+
+func (st *State) project(id string) *api.ProjectItem {
+	return find(st.Projects, func(p *api.ProjectItem) bool { return p.Project == id })
+}
+
+func (st *State) location(id string) *api.LocationItem {
+	return find(st.Locations, func(l *api.LocationItem) bool { return l.ID == id })
+}
+
+// checkProject returns ErrProjectNotFound for an unknown project.
+func (st *State) checkProject(id string) error {
+	if st.project(id) == nil {
+		return api.ErrProjectNotFound
+	}
+	return nil
+}
+
+// checkLocation returns ErrProjectNotFound or ErrLocationNotAvailable for an
+// unknown project or location.
+func (st *State) checkLocation(project, location string) (*api.LocationItem, error) {
+	if err := st.checkProject(project); err != nil {
+		return nil, err
+	}
+	loc := st.location(location)
+	if loc == nil {
+		return nil, api.ErrLocationNotAvailable
+	}
+	return loc, nil
+}
+
+type meService struct{ a *API }
+
+func (s meService) Get(context.Context, *api.Empty) (*api.MeItem, error) {
+	var res api.MeItem
+	s.a.view(func(st *State) error {
+		res = api.MeItem{Email: st.Email, KYC: true}
+		return nil
+	})
+	return &res, nil
+}
+
+// Authorized grants every permission on a known project: the dev server has
+// one user, who owns everything.
+func (s meService) Authorized(_ context.Context, m *api.MeAuthorized) (*api.MeAuthorizedResult, error) {
+	var res api.MeAuthorizedResult
+	s.a.view(func(st *State) error {
+		p := find(st.Projects, func(p *api.ProjectItem) bool {
+			return p.Project == m.Project || (m.ProjectID != 0 && p.ID == m.ProjectID)
+		})
+		if p != nil {
+			res.Authorized = true
+			res.Project.ID = p.ID
+			res.Project.Project = p.Project
+			res.Project.BillingAccount.Active = true
+		}
+		return nil
+	})
+	return &res, nil
+}
+
+func (s meService) Permissions(_ context.Context, m *api.MePermissions) (*api.MePermissionsResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	err := s.a.view(func(st *State) error { return st.checkProject(m.Project) })
+	if err != nil {
+		return nil, err
+	}
+	return &api.MePermissionsResult{Permissions: []string{"*"}}, nil
+}
+
+func (meService) GenerateToken(context.Context, *api.MeGenerateToken) (*api.MeGenerateTokenResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (meService) ListTokens(context.Context, *api.MeListTokens) (*api.MeListTokensResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (meService) RevokeToken(context.Context, *api.MeRevokeToken) (*api.Empty, error) {
+	return nil, ErrNotImplemented
+}
+
+func (meService) UploadKYCDocument(context.Context, *api.MeUploadKYCDocument) (*api.MeUploadKYCDocumentResult, error) {
+	return nil, ErrNotImplemented
+}
+
+type locationService struct{ a *API }
+
+func (s locationService) List(_ context.Context, m *api.LocationList) (*api.LocationListResult, error) {
+	var res api.LocationListResult
+	err := s.a.view(func(st *State) error {
+		if m.Project != "" {
+			if err := st.checkProject(m.Project); err != nil {
+				return err
+			}
+		}
+		for _, l := range st.Locations {
+			res.Items = append(res.Items, clone(l))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s locationService) Get(_ context.Context, m *api.LocationGet) (*api.LocationItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.LocationItem
+	err := s.a.view(func(st *State) error {
+		l := st.location(m.ID)
+		if l == nil {
+			return api.ErrLocationNotAvailable
+		}
+		res = clone(l)
+		return nil
+	})
+	return res, err
+}
+
+// empty is the result of a call that returns nothing but err.
+func empty(err error) (*api.Empty, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &api.Empty{}, nil
+}
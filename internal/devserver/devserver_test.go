@@ -0,0 +1,185 @@
+package devserver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+const (
+	project  = "dev-project"
+	location = "gke.cluster-rcf2"
+)
+
+func deploy(t *testing.T, a *API, m *api.DeploymentDeploy) {
+	t.Helper()
+	m.Project = project
+	m.Location = location
+	if _, err := a.Deployment().Deploy(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeploymentRevisions(t *testing.T) {
+	ctx := context.Background()
+	a := New(nil)
+
+	deploy(t, a, &api.DeploymentDeploy{Name: "web", Image: "nginx:1", Env: map[string]string{"A": "1"}})
+	deploy(t, a, &api.DeploymentDeploy{Name: "web", Image: "nginx:2", AddEnv: map[string]string{"B": "2"}})
+
+	d, err := a.Deployment().Get(ctx, &api.DeploymentGet{Project: project, Location: location, Name: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Revision != 2 || d.Image != "nginx:2" || d.Env["A"] != "1" || d.Env["B"] != "2" {
+		t.Errorf("latest = rev %d %s %v", d.Revision, d.Image, d.Env)
+	}
+	if d.Port != defaultPort || d.URL != "https://web-dev-project.localhost" {
+		t.Errorf("defaults: port %d, url %q", d.Port, d.URL)
+	}
+
+	if _, err := a.Deployment().Rollback(ctx, &api.DeploymentRollback{Project: project, Location: location, Name: "web", Revision: 1}); err != nil {
+		t.Fatal(err)
+	}
+	revs, err := a.Deployment().Revisions(ctx, &api.DeploymentRevisions{Project: project, Location: location, Name: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs.Items) != 3 || revs.Items[0].Revision != 3 || revs.Items[0].Image != "nginx:1" {
+		t.Errorf("revisions after rollback: %+v", revs.Items)
+	}
+
+	// a returned item is a copy
+	d.Env["A"] = "changed"
+	d, _ = a.Deployment().Get(ctx, &api.DeploymentGet{Project: project, Location: location, Name: "web", Revision: 2})
+	if d.Env["A"] != "1" {
+		t.Error("the stored deployment changed")
+	}
+
+	_, err = a.Deployment().Deploy(ctx, &api.DeploymentDeploy{Project: project, Location: location, Name: "web", Type: api.DeploymentTypeWorker, Image: "x"})
+	if !errors.Is(err, api.ErrTypeNotAllowChange) {
+		t.Errorf("type change: %v", err)
+	}
+	_, err = a.Deployment().Get(ctx, &api.DeploymentGet{Project: project, Location: location, Name: "missing"})
+	if !errors.Is(err, api.ErrDeploymentNotFound) {
+		t.Errorf("missing deployment: %v", err)
+	}
+	_, err = a.Deployment().Logs(ctx, &api.DeploymentLogs{Project: project, Location: location, Name: "web"})
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("logs: %v", err)
+	}
+}
+
+func TestInUse(t *testing.T) {
+	ctx := context.Background()
+	a := New(nil)
+
+	if _, err := a.EnvGroup().Create(ctx, &api.EnvGroupCreate{Project: project, Name: "shared", Env: map[string]string{"A": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	deploy(t, a, &api.DeploymentDeploy{Name: "web", Image: "nginx", EnvGroups: []string{"shared"}})
+	if _, err := a.EnvGroup().Delete(ctx, &api.EnvGroupDelete{Project: project, Name: "shared"}); !errors.Is(err, api.ErrEnvGroupInUse) {
+		t.Errorf("envgroup delete: %v", err)
+	}
+
+	if _, err := a.Domain().Create(ctx, &api.DomainCreate{Project: project, Location: location, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Route().CreateV2(ctx, &api.RouteCreateV2{Project: project, Location: location, Domain: "example.com", Target: "deployment://web"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := a.Domain().Delete(ctx, &api.DomainDelete{Project: project, Domain: "example.com"})
+	var inUse *api.DomainInUsedError
+	if !errors.As(err, &inUse) || len(inUse.Routes) != 1 {
+		t.Errorf("domain delete: %v", err)
+	}
+
+	_, err = a.Route().CreateV2(ctx, &api.RouteCreateV2{Project: project, Location: location, Domain: "example.com", Path: "/api", Target: "deployment://missing"})
+	if !errors.Is(err, api.ErrDeploymentNotFound) {
+		t.Errorf("route to a missing deployment: %v", err)
+	}
+
+	// deleting the project takes everything in it along
+	if _, err := a.Project().Delete(ctx, &api.ProjectDelete{Project: project}); err != nil {
+		t.Fatal(err)
+	}
+	st := a.State()
+	if len(st.Deployments)+len(st.Routes)+len(st.EnvGroups)+len(st.Domains) != 0 {
+		t.Errorf("left behind: %+v", st)
+	}
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	ctx := context.Background()
+	a := New(nil)
+
+	_, err := a.Scheduler().Create(ctx, &api.SchedulerCreate{
+		Project:  project,
+		Name:     "nightly",
+		Schedule: "0 0 * * *",
+		Method:   "POST",
+		URL:      "https://example.com/hook",
+		Auth:     api.SchedulerAuth{Type: api.SchedulerAuthBearer, Secret: "s3cret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := a.Scheduler().Get(ctx, &api.SchedulerGet{Project: project, Name: "nightly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Auth.Secret != "" || j.Timezone != "UTC" {
+		t.Errorf("get: %+v", j.Auth)
+	}
+	if a.State().Schedulers[0].Auth.Secret != "s3cret" {
+		t.Error("stored secret lost")
+	}
+
+	inv, err := a.Scheduler().Trigger(ctx, &api.SchedulerTrigger{Project: project, Name: "nightly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, err := a.Scheduler().Logs(ctx, &api.SchedulerLogs{Project: project, Name: "nightly", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.Items) != 1 || logs.Items[0].ID != inv.ID || inv.Result != "success" {
+		t.Errorf("logs: %+v", logs.Items)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	fn := filepath.Join(t.TempDir(), "state.json")
+
+	a, err := Open(fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.EnvGroup().Create(ctx, &api.EnvGroupCreate{Project: project, Name: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the change is on disk; a seed is only used when the file is missing
+	seed := DefaultState()
+	seed.Email = "other@localhost"
+	a, err = Open(fn, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := a.State()
+	if len(st.EnvGroups) != 1 || st.Email != DefaultEmail {
+		t.Errorf("reopened: %d env groups, email %q", len(st.EnvGroups), st.Email)
+	}
+
+	st, err = LoadState(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Projects) != 1 || st.Projects[0].Project != project {
+		t.Errorf("loaded projects: %+v", st.Projects)
+	}
+}
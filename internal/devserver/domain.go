@@ -0,0 +1,129 @@
+package devserver
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+type domainService struct{ a *API }
+
+func (st *State) domain(project, domain string) *api.DomainItem {
+	return find(st.Domains, func(d *api.DomainItem) bool { return d.Project == project && d.Domain == domain })
+}
+
+// Create adds the domain verified, with its certificate issued.
+func (s domainService) Create(_ context.Context, m *api.DomainCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		loc, err := st.checkLocation(m.Project, m.Location)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(st.Domains, func(d *api.DomainItem) bool { return d.Domain == m.Domain }) {
+			return api.ErrDomainNotAvailable
+		}
+		st.Domains = append(st.Domains, &api.DomainItem{
+			Project:    m.Project,
+			Location:   m.Location,
+			Domain:     m.Domain,
+			Wildcard:   m.Wildcard,
+			DNSConfig:  api.DomainDNSConfig{CName: []string{loc.CName}},
+			Status:     api.DomainStatusSuccess,
+			CertStatus: api.DomainCertStatusCreated,
+			CreatedAt:  now(),
+			CreatedBy:  st.Email,
+		})
+		return nil
+	}))
+}
+
+func (s domainService) Get(_ context.Context, m *api.DomainGet) (*api.DomainItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.DomainItem
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		d := st.domain(m.Project, m.Domain)
+		if d == nil {
+			return api.ErrDomainNotFound
+		}
+		res = clone(d)
+		return nil
+	})
+	return res, err
+}
+
+func (s domainService) List(_ context.Context, m *api.DomainList) (*api.DomainListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.DomainListResult{Items: []*api.DomainItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, d := range st.Domains {
+			if d.Project == m.Project && (m.Location == "" || d.Location == m.Location) {
+				res.Items = append(res.Items, clone(d))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *api.DomainItem) int { return strings.Compare(a.Domain, b.Domain) })
+	return &res, nil
+}
+
+// Delete refuses while a route is on the domain.
+func (s domainService) Delete(_ context.Context, m *api.DomainDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.domain(m.Project, m.Domain) == nil {
+			return api.ErrDomainNotFound
+		}
+		var routes []string
+		for _, r := range st.Routes {
+			if r.Project == m.Project && r.Domain == m.Domain {
+				routes = append(routes, r.Domain+r.Path)
+			}
+		}
+		if len(routes) > 0 {
+			return &api.DomainInUsedError{Routes: routes}
+		}
+		st.Domains = slices.DeleteFunc(st.Domains, func(d *api.DomainItem) bool {
+			return d.Project == m.Project && d.Domain == m.Domain
+		})
+		return nil
+	}))
+}
+
+// PurgeCache has no cache to purge; it checks the domain exists.
+func (s domainService) PurgeCache(_ context.Context, m *api.DomainPurgeCache) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.domain(m.Project, m.Domain) == nil {
+			return api.ErrDomainNotFound
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,151 @@
+package devserver
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+type envGroupService struct{ a *API }
+
+func (st *State) envGroup(project, name string) *api.EnvGroupItem {
+	return find(st.EnvGroups, func(g *api.EnvGroupItem) bool { return g.Project == project && g.Name == name })
+}
+
+// envGroupUsers returns the latest revisions of the project's deployments
+// using the group.
+func (st *State) envGroupUsers(project, name string) []*api.DeploymentItem {
+	return slices.DeleteFunc(st.latestDeployments(project, ""), func(d *api.DeploymentItem) bool {
+		return !slices.Contains(d.EnvGroups, name)
+	})
+}
+
+func (s envGroupService) Create(_ context.Context, m *api.EnvGroupCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.envGroup(m.Project, m.Name) != nil {
+			return api.ErrEnvGroupAlreadyExists
+		}
+		st.EnvGroups = append(st.EnvGroups, &api.EnvGroupItem{
+			Project:   m.Project,
+			Name:      m.Name,
+			Env:       maps.Clone(m.Env),
+			CreatedAt: now(),
+			CreatedBy: st.Email,
+		})
+		return nil
+	}))
+}
+
+func (s envGroupService) Get(_ context.Context, m *api.EnvGroupGet) (*api.EnvGroupItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.EnvGroupItem
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		g := st.envGroup(m.Project, m.Name)
+		if g == nil {
+			return api.ErrEnvGroupNotFound
+		}
+		res = clone(g)
+		return nil
+	})
+	return res, err
+}
+
+func (s envGroupService) List(_ context.Context, m *api.EnvGroupList) (*api.EnvGroupListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.EnvGroupListResult{Project: m.Project, Items: []*api.EnvGroupListItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, g := range st.EnvGroups {
+			if g.Project != m.Project {
+				continue
+			}
+			res.Items = append(res.Items, &api.EnvGroupListItem{
+				Project:   g.Project,
+				Name:      g.Name,
+				EnvCount:  len(g.Env),
+				CreatedAt: g.CreatedAt,
+				CreatedBy: g.CreatedBy,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *api.EnvGroupListItem) int { return strings.Compare(a.Name, b.Name) })
+	return &res, nil
+}
+
+// Update changes the group's env and, with Redeploy, rolls a new revision of
+// each running deployment that uses it.
+func (s envGroupService) Update(_ context.Context, m *api.EnvGroupUpdate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		g := st.envGroup(m.Project, m.Name)
+		if g == nil {
+			return api.ErrEnvGroupNotFound
+		}
+		if m.Env != nil {
+			g.Env = maps.Clone(m.Env)
+		}
+		if g.Env == nil {
+			g.Env = map[string]string{}
+		}
+		maps.Copy(g.Env, m.AddEnv)
+		for _, k := range m.RemoveEnv {
+			delete(g.Env, k)
+		}
+		if m.Redeploy {
+			for _, d := range st.envGroupUsers(m.Project, m.Name) {
+				if d.Action != api.DeploymentActionPause {
+					st.release(clone(d), d)
+				}
+			}
+		}
+		return nil
+	}))
+}
+
+func (s envGroupService) Delete(_ context.Context, m *api.EnvGroupDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.envGroup(m.Project, m.Name) == nil {
+			return api.ErrEnvGroupNotFound
+		}
+		if len(st.envGroupUsers(m.Project, m.Name)) > 0 {
+			return api.ErrEnvGroupInUse
+		}
+		st.EnvGroups = slices.DeleteFunc(st.EnvGroups, func(g *api.EnvGroupItem) bool {
+			return g.Project == m.Project && g.Name == m.Name
+		})
+		return nil
+	}))
+}
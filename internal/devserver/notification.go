@@ -0,0 +1,183 @@
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+type notificationService struct{ a *API }
+
+func (st *State) notificationChannel(project, name string) *NotificationChannel {
+	return find(st.Notifications, func(n *NotificationChannel) bool { return n.Project == project && n.Name == name })
+}
+
+// channel runs fn on a notification channel.
+func (s notificationService) channel(project, name string, update bool, fn func(st *State, n *NotificationChannel) error) error {
+	run := s.a.view
+	if update {
+		run = s.a.update
+	}
+	return run(func(st *State) error {
+		if err := st.checkProject(project); err != nil {
+			return err
+		}
+		n := st.notificationChannel(project, name)
+		if n == nil {
+			return api.ErrNotificationChannelNotFound
+		}
+		return fn(st, n)
+	})
+}
+
+// item is the channel as the api returns it, without its secret.
+func (n *NotificationChannel) item() *api.NotificationItem {
+	x := clone(&n.NotificationItem)
+	x.Config.Secret = ""
+	return x
+}
+
+func (s notificationService) Create(_ context.Context, m *api.NotificationCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.notificationChannel(m.Project, m.Name) != nil {
+			return api.ErrNotificationChannelAlreadyExists
+		}
+		t := now()
+		st.Notifications = append(st.Notifications, &NotificationChannel{NotificationItem: api.NotificationItem{
+			Project:      m.Project,
+			Name:         m.Name,
+			Config:       m.Config,
+			Subscription: *clone(&m.Subscription),
+			Disabled:     m.Disabled,
+			CreatedAt:    t,
+			CreatedBy:    st.Email,
+			UpdatedAt:    t,
+			UpdatedBy:    st.Email,
+		}})
+		return nil
+	}))
+}
+
+// Update replaces the channel's configuration; an empty url or secret keeps
+// the stored one.
+func (s notificationService) Update(_ context.Context, m *api.NotificationUpdate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.channel(m.Project, m.Name, true, func(st *State, n *NotificationChannel) error {
+		cfg := m.Config
+		cfg.URL = cmp.Or(cfg.URL, n.Config.URL)
+		cfg.Secret = cmp.Or(cfg.Secret, n.Config.Secret)
+		n.Config = cfg
+		n.Subscription = *clone(&m.Subscription)
+		n.Disabled = m.Disabled
+		n.UpdatedAt = now()
+		n.UpdatedBy = st.Email
+		return nil
+	}))
+}
+
+func (s notificationService) Get(_ context.Context, m *api.NotificationGet) (*api.NotificationItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.NotificationItem
+	err := s.channel(m.Project, m.Name, false, func(_ *State, n *NotificationChannel) error {
+		res = n.item()
+		return nil
+	})
+	return res, err
+}
+
+func (s notificationService) List(_ context.Context, m *api.NotificationList) (*api.NotificationListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.NotificationListResult{Project: m.Project, Items: []*api.NotificationItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, n := range st.Notifications {
+			if n.Project == m.Project {
+				res.Items = append(res.Items, n.item())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *api.NotificationItem) int { return strings.Compare(a.Name, b.Name) })
+	return &res, nil
+}
+
+func (s notificationService) Delete(_ context.Context, m *api.NotificationDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.channel(m.Project, m.Name, true, func(st *State, n *NotificationChannel) error {
+		st.Notifications = slices.DeleteFunc(st.Notifications, func(x *NotificationChannel) bool { return x == n })
+		return nil
+	}))
+}
+
+// Test records a successful delivery without sending it.
+func (s notificationService) Test(_ context.Context, m *api.NotificationTest) (*api.NotificationDelivery, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.NotificationDelivery
+	err := s.channel(m.Project, m.Name, true, func(_ *State, n *NotificationChannel) error {
+		d := &api.NotificationDelivery{
+			ID:         strconv.Itoa(len(n.Deliveries) + 1),
+			StartedAt:  now(),
+			Result:     "success",
+			HTTPStatus: 200,
+		}
+		n.Deliveries = slices.Insert(n.Deliveries, 0, d)
+		res = clone(d)
+		return nil
+	})
+	return res, err
+}
+
+func (s notificationService) Deliveries(_ context.Context, m *api.NotificationDeliveries) (*api.NotificationDeliveriesResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.NotificationDeliveriesResult{Project: m.Project, Name: m.Name, Items: []*api.NotificationDelivery{}}
+	err := s.channel(m.Project, m.Name, false, func(_ *State, n *NotificationChannel) error {
+		for _, x := range recent(n.Deliveries, func(x *api.NotificationDelivery) time.Time { return x.StartedAt }, m.After, m.Before, m.Limit) {
+			res.Items = append(res.Items, clone(x))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Pull has no change events to return; the cursor stays where it was acked.
+func (s notificationService) Pull(_ context.Context, m *api.NotificationPull) (*api.NotificationPullResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	err := s.channel(m.Project, m.Name, false, func(*State, *NotificationChannel) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return &api.NotificationPullResult{Project: m.Project, Name: m.Name, Events: []api.ChangeEventPayload{}, Cursor: m.Ack}, nil
+}
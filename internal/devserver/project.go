@@ -0,0 +1,108 @@
+package devserver
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+type projectService struct{ a *API }
+
+func (s projectService) Create(_ context.Context, m *api.ProjectCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if st.project(m.SID) != nil {
+			return api.ErrSIDNotAvailable
+		}
+		var id int64
+		for _, p := range st.Projects {
+			id = max(id, p.ID)
+		}
+		st.Projects = append(st.Projects, &api.ProjectItem{
+			ID:             id + 1,
+			Project:        m.SID,
+			Name:           m.Name,
+			BillingAccount: m.BillingAccount,
+			Quota:          api.ProjectQuota{Deployments: 100, DeploymentMaxReplicas: 20},
+			CreatedAt:      now(),
+		})
+		return nil
+	}))
+}
+
+func (s projectService) Get(_ context.Context, m *api.ProjectGet) (*api.ProjectItem, error) {
+	var res *api.ProjectItem
+	err := s.a.view(func(st *State) error {
+		p := st.project(m.Project)
+		if p == nil {
+			return api.ErrProjectNotFound
+		}
+		res = clone(p)
+		return nil
+	})
+	return res, err
+}
+
+func (s projectService) List(context.Context, *api.Empty) (*api.ProjectListResult, error) {
+	res := api.ProjectListResult{Items: []*api.ProjectItem{}}
+	s.a.view(func(st *State) error {
+		for _, p := range st.Projects {
+			res.Items = append(res.Items, clone(p))
+		}
+		return nil
+	})
+	slices.SortFunc(res.Items, func(a, b *api.ProjectItem) int { return strings.Compare(a.Project, b.Project) })
+	return &res, nil
+}
+
+func (s projectService) Update(_ context.Context, m *api.ProjectUpdate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		p := st.project(m.Project)
+		if p == nil {
+			return api.ErrProjectNotFound
+		}
+		if m.Name != nil {
+			p.Name = *m.Name
+		}
+		if m.BillingAccount != nil {
+			p.BillingAccount = *m.BillingAccount
+		}
+		return nil
+	}))
+}
+
+// Delete removes the project and everything in it.
+func (s projectService) Delete(_ context.Context, m *api.ProjectDelete) (*api.Empty, error) {
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		st.Projects = slices.DeleteFunc(st.Projects, func(p *api.ProjectItem) bool { return p.Project == m.Project })
+		st.Deployments = slices.DeleteFunc(st.Deployments, func(d *api.DeploymentItem) bool { return d.Project == m.Project })
+		st.Routes = slices.DeleteFunc(st.Routes, func(r *Route) bool { return r.Project == m.Project })
+		st.EnvGroups = slices.DeleteFunc(st.EnvGroups, func(g *api.EnvGroupItem) bool { return g.Project == m.Project })
+		st.Domains = slices.DeleteFunc(st.Domains, func(d *api.DomainItem) bool { return d.Project == m.Project })
+		st.Schedulers = slices.DeleteFunc(st.Schedulers, func(j *SchedulerJob) bool { return j.Project == m.Project })
+		st.Notifications = slices.DeleteFunc(st.Notifications, func(n *NotificationChannel) bool { return n.Project == m.Project })
+		return nil
+	}))
+}
+
+func (projectService) Usage(context.Context, *api.ProjectUsage) (*api.ProjectUsageResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (projectService) StorageMetrics(context.Context, *api.ProjectStorageMetrics) (*api.ProjectStorageMetricsResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (projectService) Metrics(context.Context, *api.ProjectMetrics) (*api.ProjectMetricsResult, error) {
+	return nil, ErrNotImplemented
+}
@@ -0,0 +1,141 @@
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+type routeService struct{ a *API }
+
+const deploymentTarget = "deployment://"
+
+func (st *State) route(project, location, domain, path string) *Route {
+	return find(st.Routes, func(r *Route) bool {
+		return r.Project == project && r.Location == location && r.Domain == domain && r.Path == path
+	})
+}
+
+// setRoute points domain+path at target, replacing the route there. A
+// deployment target must be a WebService without a TTL.
+func (st *State) setRoute(project, location, domain, path, target string, config api.RouteConfig) error {
+	if _, err := st.checkLocation(project, location); err != nil {
+		return err
+	}
+	var deployment string
+	if name, ok := strings.CutPrefix(target, deploymentTarget); ok {
+		d := st.deployment(project, location, name)
+		switch {
+		case d == nil:
+			return api.ErrDeploymentNotFound
+		case d.Type != api.DeploymentTypeWebService:
+			return api.ErrCanMapOnlyWebService
+		case d.TTL > 0:
+			return api.ErrTTLDeploymentNotAllowRoute
+		}
+		deployment = name
+	}
+	path = cmp.Or(path, "/")
+	st.Routes = slices.DeleteFunc(st.Routes, func(r *Route) bool {
+		return r.Project == project && r.Location == location && r.Domain == domain && r.Path == path
+	})
+	st.Routes = append(st.Routes, &Route{
+		Project: project,
+		RouteItem: api.RouteItem{
+			Location:   location,
+			Domain:     domain,
+			Path:       path,
+			Target:     target,
+			Deployment: deployment,
+			Config:     config,
+			CreatedAt:  now(),
+			CreatedBy:  st.Email,
+		},
+	})
+	return nil
+}
+
+func (s routeService) Create(_ context.Context, m *api.RouteCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		return st.setRoute(m.Project, m.Location, m.Domain, m.Path, deploymentTarget+m.Deployment, api.RouteConfig{})
+	}))
+}
+
+func (s routeService) CreateV2(_ context.Context, m *api.RouteCreateV2) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	m.Config.Host = strings.TrimSpace(m.Config.Host)
+	return empty(s.a.update(func(st *State) error {
+		return st.setRoute(m.Project, m.Location, m.Domain, m.Path, m.Target, *clone(&m.Config))
+	}))
+}
+
+func (s routeService) Get(_ context.Context, m *api.RouteGet) (*api.RouteItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.RouteItem
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		r := st.route(m.Project, m.Location, m.Domain, cmp.Or(m.Path, "/"))
+		if r == nil {
+			return api.ErrRouteNotFound
+		}
+		res = clone(&r.RouteItem)
+		return nil
+	})
+	return res, err
+}
+
+func (s routeService) List(_ context.Context, m *api.RouteList) (*api.RouteListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.RouteListResult{Items: []*api.RouteItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, r := range st.Routes {
+			if r.Project == m.Project && (m.Location == "" || r.Location == m.Location) {
+				res.Items = append(res.Items, clone(&r.RouteItem))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *api.RouteItem) int {
+		return cmp.Or(strings.Compare(a.Domain, b.Domain), strings.Compare(a.Path, b.Path), strings.Compare(a.Location, b.Location))
+	})
+	return &res, nil
+}
+
+func (s routeService) Delete(_ context.Context, m *api.RouteDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	path := cmp.Or(m.Path, "/")
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.route(m.Project, m.Location, m.Domain, path) == nil {
+			return api.ErrRouteNotFound
+		}
+		st.Routes = slices.DeleteFunc(st.Routes, func(r *Route) bool {
+			return r.Project == m.Project && r.Location == m.Location && r.Domain == m.Domain && r.Path == path
+		})
+		return nil
+	}))
+}
@@ -0,0 +1,230 @@
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+type schedulerService struct{ a *API }
+
+func (st *State) schedulerJob(project, name string) *SchedulerJob {
+	return find(st.Schedulers, func(j *SchedulerJob) bool { return j.Project == project && j.Name == name })
+}
+
+// job runs fn on a scheduler job.
+func (s schedulerService) job(project, name string, update bool, fn func(st *State, j *SchedulerJob) error) error {
+	run := s.a.view
+	if update {
+		run = s.a.update
+	}
+	return run(func(st *State) error {
+		if err := st.checkProject(project); err != nil {
+			return err
+		}
+		j := st.schedulerJob(project, name)
+		if j == nil {
+			return api.ErrSchedulerJobNotFound
+		}
+		return fn(st, j)
+	})
+}
+
+// item is the job as the api returns it, without its secret.
+func (j *SchedulerJob) item() *api.SchedulerItem {
+	x := clone(&j.SchedulerItem)
+	x.Auth.Secret = ""
+	return x
+}
+
+func (s schedulerService) Create(_ context.Context, m *api.SchedulerCreate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.a.update(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		if st.schedulerJob(m.Project, m.Name) != nil {
+			return api.ErrSchedulerJobAlreadyExists
+		}
+		t := now()
+		st.Schedulers = append(st.Schedulers, &SchedulerJob{SchedulerItem: api.SchedulerItem{
+			Project:            m.Project,
+			Name:               m.Name,
+			Schedule:           m.Schedule,
+			Timezone:           cmp.Or(m.Timezone, "UTC"),
+			Method:             m.Method,
+			URL:                m.URL,
+			Headers:            maps.Clone(m.Headers),
+			Body:               m.Body,
+			Auth:               m.Auth,
+			InsecureSkipVerify: m.InsecureSkipVerify,
+			Paused:             m.Paused,
+			CreatedAt:          t,
+			CreatedBy:          st.Email,
+			UpdatedAt:          t,
+			UpdatedBy:          st.Email,
+		}})
+		return nil
+	}))
+}
+
+// Update replaces the job's configuration; an empty auth secret keeps the
+// stored one.
+func (s schedulerService) Update(_ context.Context, m *api.SchedulerUpdate) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.job(m.Project, m.Name, true, func(st *State, j *SchedulerJob) error {
+		auth := m.Auth
+		if auth.Secret == "" && auth.Type == j.Auth.Type {
+			auth.Secret = j.Auth.Secret
+		}
+		if (auth.Type == api.SchedulerAuthBasic || auth.Type == api.SchedulerAuthBearer) && auth.Secret == "" {
+			return api.ErrSchedulerAuthSecretRequired
+		}
+		j.Schedule = m.Schedule
+		j.Timezone = cmp.Or(m.Timezone, "UTC")
+		j.Method = m.Method
+		j.URL = m.URL
+		j.Headers = maps.Clone(m.Headers)
+		j.Body = m.Body
+		j.Auth = auth
+		j.InsecureSkipVerify = m.InsecureSkipVerify
+		j.UpdatedAt = now()
+		j.UpdatedBy = st.Email
+		return nil
+	}))
+}
+
+func (s schedulerService) Get(_ context.Context, m *api.SchedulerGet) (*api.SchedulerItem, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.SchedulerItem
+	err := s.job(m.Project, m.Name, false, func(_ *State, j *SchedulerJob) error {
+		res = j.item()
+		return nil
+	})
+	return res, err
+}
+
+func (s schedulerService) List(_ context.Context, m *api.SchedulerList) (*api.SchedulerListResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.SchedulerListResult{Project: m.Project, Items: []*api.SchedulerItem{}}
+	err := s.a.view(func(st *State) error {
+		if err := st.checkProject(m.Project); err != nil {
+			return err
+		}
+		for _, j := range st.Schedulers {
+			if j.Project == m.Project {
+				res.Items = append(res.Items, j.item())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *api.SchedulerItem) int { return strings.Compare(a.Name, b.Name) })
+	return &res, nil
+}
+
+func (s schedulerService) Delete(_ context.Context, m *api.SchedulerDelete) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.job(m.Project, m.Name, true, func(st *State, j *SchedulerJob) error {
+		st.Schedulers = slices.DeleteFunc(st.Schedulers, func(x *SchedulerJob) bool { return x == j })
+		return nil
+	}))
+}
+
+func (s schedulerService) Pause(_ context.Context, m *api.SchedulerPause) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.job(m.Project, m.Name, true, func(_ *State, j *SchedulerJob) error {
+		j.Paused = true
+		return nil
+	}))
+}
+
+func (s schedulerService) Resume(_ context.Context, m *api.SchedulerResume) (*api.Empty, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	return empty(s.job(m.Project, m.Name, true, func(_ *State, j *SchedulerJob) error {
+		j.Paused = false
+		return nil
+	}))
+}
+
+// Trigger records a successful run without calling the job's url.
+func (s schedulerService) Trigger(_ context.Context, m *api.SchedulerTrigger) (*api.SchedulerInvocation, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	var res *api.SchedulerInvocation
+	err := s.job(m.Project, m.Name, true, func(_ *State, j *SchedulerJob) error {
+		t := now()
+		inv := &api.SchedulerInvocation{
+			ID:         strconv.Itoa(len(j.Invocations) + 1),
+			StartedAt:  t,
+			Result:     "success",
+			HTTPStatus: 200,
+		}
+		j.Invocations = slices.Insert(j.Invocations, 0, inv)
+		j.LastResult = inv.Result
+		j.LastRunAt = &t
+		j.LastHTTPStatus = inv.HTTPStatus
+		j.LastLatencyMs = 0
+		j.LastError = ""
+		res = clone(inv)
+		return nil
+	})
+	return res, err
+}
+
+func (s schedulerService) Logs(_ context.Context, m *api.SchedulerLogs) (*api.SchedulerLogsResult, error) {
+	if err := valid(m); err != nil {
+		return nil, err
+	}
+	res := api.SchedulerLogsResult{Project: m.Project, Name: m.Name, Items: []*api.SchedulerInvocation{}}
+	err := s.job(m.Project, m.Name, false, func(_ *State, j *SchedulerJob) error {
+		for _, x := range recent(j.Invocations, func(x *api.SchedulerInvocation) time.Time { return x.StartedAt }, m.After, m.Before, m.Limit) {
+			res.Items = append(res.Items, clone(x))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// recent returns up to limit of xs (newest first) started after after and
+// before before, each bound applying when set.
+func recent[T any](xs []*T, at func(*T) time.Time, after, before time.Time, limit int) []*T {
+	var res []*T
+	for _, x := range xs {
+		t := at(x)
+		if (!after.IsZero() && !t.After(after)) || (!before.IsZero() && !t.Before(before)) {
+			continue
+		}
+		if len(res) == limit {
+			break
+		}
+		res = append(res, x)
+	}
+	return res
+}
@@ -0,0 +1,309 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+)
+
+// apiMethod is one RPC of api.Interface: Service() returns the service whose
+// Method takes a *Request and returns a Result.
+type apiMethod struct {
+	Name    string // the rpc, e.g. "deployment.get"
+	Service string // the api.Interface method, e.g. "Deployment"
+	Method  string // the service's method, e.g. "Get"
+	Request reflect.Type
+	Result  reflect.Type
+}
+
+// rpcNames are the rpcs not named by lowering the first letter of their Go
+// method.
+var rpcNames = map[string]string{
+	"billing.SKUs":          "billing.skus",
+	"registry.GC":           "registry.gc",
+	"collector.SetWAFUsage": "collector.setWafUsage",
+}
+
+// multipartRPCs take a multipart upload, which the json wire cannot carry.
+var multipartRPCs = map[string]bool{
+	"billing.uploadTransferSlip":   true,
+	"billing.uploadWHTCertificate": true,
+	"me.uploadKYCDocument":         true,
+}
+
+// apiMethods lists the rpcs of api.Interface, sorted by name.
+func apiMethods() []apiMethod {
+	var list []apiMethod
+	it := reflect.TypeFor[api.Interface]()
+	for i := range it.NumMethod() {
+		svc := it.Method(i)
+		resource := strings.ToLower(svc.Name)
+		if resource == "errors" {
+			resource = "error"
+		}
+		st := svc.Type.Out(0)
+		for j := range st.NumMethod() {
+			m := st.Method(j)
+			if m.Type.NumIn() != 2 || m.Type.NumOut() != 2 {
+				continue
+			}
+			name, ok := rpcNames[resource+"."+m.Name]
+			if !ok {
+				r := []rune(m.Name)
+				r[0] = unicode.ToLower(r[0])
+				name = resource + "." + string(r)
+			}
+			if multipartRPCs[name] {
+				continue
+			}
+			list = append(list, apiMethod{
+				Name:    name,
+				Service: svc.Name,
+				Method:  m.Name,
+				Request: m.Type.In(1),
+				Result:  m.Type.Out(0),
+			})
+		}
+	}
+	slices.SortFunc(list, func(a, b apiMethod) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+func lookupAPIMethod(name string) *apiMethod {
+	for _, m := range apiMethods() {
+		if m.Name == name {
+			return &m
+		}
+	}
+	return nil
+}
+
+// apiMethodList is the result of `api -list`.
+type apiMethodList struct {
+	Items []apiMethodItem `json:"items" yaml:"items"`
+}
+
+type apiMethodItem struct {
+	Method  string `json:"method" yaml:"method"`
+	Request string `json:"request" yaml:"request"`
+	Result  string `json:"result" yaml:"result"`
+}
+
+func (l *apiMethodList) Table() [][]string {
+	table := [][]string{{"METHOD", "REQUEST", "RESULT"}}
+	for _, x := range l.Items {
+		table = append(table, []string{x.Method, x.Request, x.Result})
+	}
+	return table
+}
+
+// api calls an rpc by name with a json request body. A method of
+// api.Interface is called through rn.API and its result printed like the
+// command that wraps it; against the api server the body is sent as given, so
+// fields and rpcs newer than this cli go through too (an unknown rpc's result
+// prints as plain yaml or json).
+func (rn Runner) api(args ...string) error {
+	_, run := rn.apiLeaf()
+	return run(args)
+}
+
+func (rn *Runner) apiLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("api", writeAPIUsage)
+	var (
+		data string
+		list bool
+	)
+	f.StringVar(&data, "data", "", "json request body, @file to read it from a file, or - for stdin (default {})")
+	f.BoolVar(&list, "list", false, "list the known methods (optionally those starting with the argument)")
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeAPIUsage(rn.output())
+			return nil
+		}
+		var name string
+		if len(args) > 0 && !isFlag(args[0]) {
+			name, args = args[0], args[1:]
+		}
+		f.Parse(args)
+		if name == "" {
+			name = f.Arg(0)
+		}
+
+		if list {
+			res := apiMethodList{Items: []apiMethodItem{}}
+			for _, m := range apiMethods() {
+				if strings.HasPrefix(m.Name, name) {
+					res.Items = append(res.Items, apiMethodItem{Method: m.Name, Request: m.Request.Elem().Name(), Result: typeName(m.Result)})
+				}
+			}
+			return rn.print(&res)
+		}
+		if name == "" {
+			writeAPIUsage(rn.output())
+			return fmt.Errorf("api: method required (deploys api <resource.method>, or -list)")
+		}
+
+		body, err := readAPIData(data)
+		if err != nil {
+			return err
+		}
+		m := lookupAPIMethod(name)
+		c, isClient := rn.API.(*client.Client)
+		switch {
+		case isClient:
+			res, err := invokeRaw(rn.ctx(), c, name, body, m)
+			if err != nil {
+				return err
+			}
+			return rn.print(res)
+		case m == nil:
+			return fmt.Errorf("api: unknown method %q (run \"deploys api -list\")", name)
+		}
+		res, err := m.call(rn.ctx(), rn.API, body)
+		if err != nil {
+			return err
+		}
+		return rn.print(res)
+	}
+}
+
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem().Name()
+	}
+	return t.String()
+}
+
+// readAPIData returns the request body -data names: inline json, @file, or -
+// (stdin). It must be a json object; empty is {}.
+func readAPIData(data string) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case data == "-" || data == "@-":
+		b, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(data, "@"):
+		b, err = os.ReadFile(data[1:])
+	default:
+		b = []byte(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return []byte("{}"), nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, fmt.Errorf("api: -data is not a json object: %w", err)
+	}
+	return b, nil
+}
+
+// call decodes body into the method's request and calls it on a.
+func (m *apiMethod) call(ctx context.Context, a api.Interface, body []byte) (any, error) {
+	req := reflect.New(m.Request.Elem())
+	if err := json.Unmarshal(body, req.Interface()); err != nil {
+		return nil, fmt.Errorf("api: %s request: %w", m.Name, err)
+	}
+	svc := reflect.ValueOf(a).MethodByName(m.Service).Call(nil)[0]
+	out := svc.MethodByName(m.Method).Call([]reflect.Value{reflect.ValueOf(ctx), req})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	return out[0].Interface(), nil
+}
+
+// IdempotentRequest reports whether an api request can be sent again after it
+// may have taken effect: an idempotent http method, or an rpc that only reads.
+// It decides which failed calls main's retrying transport repeats.
+func IdempotentRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return readOnlyRPC(path.Base(r.URL.Path))
+	}
+	return false
+}
+
+// invokeRaw posts body to the rpc the way c does, with its endpoint, auth,
+// and audit channel. The result decodes into the method's result type when m
+// is known, else into plain values.
+func invokeRaw(ctx context.Context, c *client.Client, name string, body []byte, m *apiMethod) (any, error) {
+	endpoint := strings.TrimSuffix(c.Endpoint, "/") + "/"
+	if c.Endpoint == "" {
+		endpoint = "https://api.deploys.app/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+name, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	if c.Channel != "" {
+		req.Header.Set(api.HeaderChannel, string(c.Channel))
+	}
+	if c.Auth != nil {
+		c.Auth(req)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api return not ok; statusCode=%d", resp.StatusCode)
+	}
+
+	var res any
+	if m != nil {
+		res = reflect.New(m.Result).Interface()
+	} else {
+		res = new(any)
+	}
+	var errMsg client.Error
+	envelope := struct {
+		OK     bool          `json:"ok"`
+		Result any           `json:"result"`
+		Error  *client.Error `json:"error"`
+	}{Result: res, Error: &errMsg}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if !envelope.OK {
+		// the api's typed errors, as the client maps them
+		for _, e := range api.AllErrors {
+			if e.Error() == errMsg.Message {
+				return nil, e
+			}
+		}
+		return nil, &errMsg
+	}
+	return reflect.ValueOf(res).Elem().Interface(), nil
+}
+
+func writeAPIUsage(w io.Writer) {
+	fmt.Fprint(w, "api — call an api method by name with a json request body\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys api <resource.method> [-data json|@file|-]\n  deploys api -list [prefix]\n")
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+)
+
+// The catalog names every rpc the way the api client sends it.
+func TestAPIMethods(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, strings.TrimPrefix(r.URL.Path, "/"))
+		w.Write([]byte(`{"ok":true,"result":null}`))
+	}))
+	defer srv.Close()
+	c := &client.Client{Endpoint: srv.URL}
+
+	seen := map[string]bool{}
+	sent := 0
+	for _, m := range apiMethods() {
+		if seen[m.Name] {
+			t.Errorf("%s listed twice", m.Name)
+		}
+		seen[m.Name] = true
+
+		paths = nil
+		// a request the client rejects as invalid never reaches the server
+		m.call(context.Background(), c, []byte("{}"))
+		if len(paths) == 0 {
+			continue
+		}
+		sent++
+		if paths[0] != m.Name {
+			t.Errorf("%s: the client calls %s", m.Name, paths[0])
+		}
+	}
+	if sent < 20 {
+		t.Errorf("only %d methods reached the server", sent)
+	}
+	for _, name := range []string{"deployment.get", "billing.skus", "registry.gc", "collector.setWafUsage", "error.list"} {
+		if !seen[name] {
+			t.Errorf("%s not listed", name)
+		}
+	}
+	if seen["me.uploadKYCDocument"] {
+		t.Error("multipart upload listed")
+	}
+}
+
+func TestAPIRaw(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	var gotPath, gotBody, gotAuth, gotChannel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotPath, gotBody = r.URL.Path, string(b)
+		gotAuth, gotChannel = r.Header.Get("Authorization"), r.Header.Get(api.HeaderChannel)
+		switch r.URL.Path {
+		case "/deployment.get":
+			w.Write([]byte(`{"ok":true,"result":{"name":"web","revision":3}}`))
+		case "/future.rpc":
+			w.Write([]byte(`{"ok":true,"result":{"answer":42}}`))
+		default:
+			w.Write([]byte(`{"ok":false,"error":{"message":"` + api.ErrDeploymentNotFound.Error() + `"}}`))
+		}
+	}))
+	defer srv.Close()
+	c := &client.Client{
+		Endpoint: srv.URL,
+		Channel:  api.AuditChannelCLI,
+		Auth:     func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") },
+	}
+	run := func(args ...string) (string, error) {
+		out := tempOut(t)
+		err := Runner{API: c, Output: out}.Run(append([]string{"api"}, args...)...)
+		return readOut(t, out), err
+	}
+
+	// fields the request struct lacks are sent as given
+	out, err := run("deployment.get", "-data", `{"project":"acme","name":"web","newField":true}`, "-ojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/deployment.get" || !strings.Contains(gotBody, `"newField":true`) {
+		t.Errorf("sent %s %s", gotPath, gotBody)
+	}
+	if gotAuth != "Bearer tok" || gotChannel != string(api.AuditChannelCLI) {
+		t.Errorf("auth %q, channel %q", gotAuth, gotChannel)
+	}
+	if !strings.Contains(out, `"revision": 3`) {
+		t.Errorf("output:\n%s", out)
+	}
+
+	// an rpc newer than the cli prints as plain values
+	out, err = run("future.rpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotBody != "{}" || strings.TrimSpace(out) != "answer: 42" {
+		t.Errorf("sent %s; output:\n%s", gotBody, out)
+	}
+
+	if _, err := run("deployment.delete"); !errors.Is(err, api.ErrDeploymentNotFound) {
+		t.Errorf("api error: %v", err)
+	}
+	if _, err := run("deployment.get", "-data", "[1]"); err == nil {
+		t.Error("non-object -data: want an error")
+	}
+}
+
+// Against any other api.Interface the call goes through its typed method.
+func TestAPIInterface(t *testing.T) {
+	rn, _ := newJournalRunner(t)
+	out := tempOut(t)
+	rn.Output = out
+	if err := rn.Run("api", "envgroup.get", "-data", `{"project":"acme","name":"shared"}`, "-oyaml"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readOut(t, out); !strings.Contains(got, "name: shared") || !strings.Contains(got, "A: \"1\"") {
+		t.Errorf("output:\n%s", got)
+	}
+	if err := rn.Run("api", "future.rpc"); err == nil || !strings.Contains(err.Error(), "unknown method") {
+		t.Errorf("unknown method: %v", err)
+	}
+}
+
+func TestAPIList(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	out := tempOut(t)
+	if err := (Runner{Output: out}).Run("api", "envgroup.", "-list", "-columns", "method"); err != nil {
+		t.Fatal(err)
+	}
+	want := "METHOD            \nenvgroup.create   \nenvgroup.delete   \nenvgroup.get      \nenvgroup.list     \nenvgroup.update   \n"
+	if got := readOut(t, out); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestIdempotentRequest(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodPost, "/deployment.get", true},
+		{http.MethodPost, "/api/project.list", true},
+		{http.MethodPost, "/deployment.deploy", false},
+		{http.MethodPost, "/notification.pull", false},
+		{http.MethodPut, "/sites/x/index.html", true},
+		{http.MethodGet, "/anything", true},
+		{http.MethodPatch, "/deployment.get", false},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if got := IdempotentRequest(r); got != tc.want {
+			t.Errorf("%s %s = %v; want %v", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -1,28 +1,18 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) auditLog(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("auditlog")
-	}
+	return rn.runGroup("auditlog", args, rn.auditLogLeaf)
+}
 
+func (rn *Runner) auditLogLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.AuditLog()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("auditlog", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("auditlog", args[0])
+	switch sub {
 	case "list":
 		var (
 			req     api.AuditLogList
@@ -37,29 +27,29 @@ func (rn Runner) auditLog(args ...string) error {
 		f.Var(timeFlag{&req.After}, "after", "only entries after this time (RFC 3339 or YYYY-MM-DD)")
 		f.Var(timeFlag{&req.Before}, "before", "only entries before this time (RFC 3339 or YYYY-MM-DD)")
 		f.IntVar(&req.Limit, "limit", 0, "max entries")
-		f.Parse(args[1:])
-
-		switch api.AuditChannel(channel) {
-		case "":
-		case api.AuditChannelAPI, api.AuditChannelConsole, api.AuditChannelCLI, api.AuditChannelMCP:
-			req.Channel = api.AuditChannel(channel)
-		default:
-			return fmt.Errorf("invalid channel: '%s'", channel)
-		}
-
-		switch outcome {
-		case "":
-		case api.AuditOutcomeSuccess.String():
-			req.Outcome = api.AuditOutcomeSuccess
-		case api.AuditOutcomeFailure.String():
-			req.Outcome = api.AuditOutcomeFailure
-		default:
-			return fmt.Errorf("invalid outcome: '%s'", outcome)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+
+			switch api.AuditChannel(channel) {
+			case "":
+			case api.AuditChannelAPI, api.AuditChannelConsole, api.AuditChannelCLI, api.AuditChannelMCP:
+				req.Channel = api.AuditChannel(channel)
+			default:
+				return nil, fmt.Errorf("invalid channel: '%s'", channel)
+			}
+
+			switch outcome {
+			case "":
+			case api.AuditOutcomeSuccess.String():
+				req.Outcome = api.AuditOutcomeSuccess
+			case api.AuditOutcomeFailure.String():
+				req.Outcome = api.AuditOutcomeFailure
+			default:
+				return nil, fmt.Errorf("invalid outcome: '%s'", outcome)
+			}
+			return s.List(rn.ctx(), &req)
 		}
-		resp, err = s.List(context.Background(), &req)
-	}
-	if err != nil {
-		return err
 	}
-	return rn.print(resp)
+	return nil
 }
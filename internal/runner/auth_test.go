@@ -64,7 +64,6 @@ func TestIsAuthCommand(t *testing.T) {
 func TestLoginGlue(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	me := meGetServer(t, "alice@example.com", true)
-	t.Setenv("DEPLOYS_ENDPOINT", me.URL)
 	t.Setenv("DEPLOYS_AUTH_ENDPOINT", "https://auth.deploys.app")
 
 	old := doLogin
@@ -73,7 +72,7 @@ func TestLoginGlue(t *testing.T) {
 	}
 	defer func() { doLogin = old }()
 
-	rn := Runner{Output: tempOut(t)}
+	rn := Runner{Output: tempOut(t), Endpoint: me.URL}
 	if err := rn.login(); err != nil {
 		t.Fatalf("login: %v", err)
 	}
@@ -105,7 +104,6 @@ func TestLoginGlue(t *testing.T) {
 func TestLoginMeGetFailurePersists(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	me := meGetServer(t, "", false) // 500 -> me.get fails
-	t.Setenv("DEPLOYS_ENDPOINT", me.URL)
 
 	old := doLogin
 	doLogin = func(ctx context.Context, authBase, apiEndpoint string, opts auth.LoginOptions) (auth.Result, error) {
@@ -113,7 +111,7 @@ func TestLoginMeGetFailurePersists(t *testing.T) {
 	}
 	defer func() { doLogin = old }()
 
-	rn := Runner{Output: tempOut(t)}
+	rn := Runner{Output: tempOut(t), Endpoint: me.URL}
 	if err := rn.login(); err != nil {
 		t.Fatalf("login should still succeed when me.get fails: %v", err)
 	}
@@ -136,7 +134,6 @@ func TestAuthListAndStatus(t *testing.T) {
 	t.Setenv("DEPLOYS_AUTH_USER", "")
 	t.Setenv("DEPLOYS_AUTH_PASS", "")
 	ep := "https://api.deploys.app/"
-	t.Setenv("DEPLOYS_ENDPOINT", ep)
 
 	if err := auth.Mutate(func(cc *auth.Credentials) error {
 		cc.Upsert(auth.Account{Key: auth.AccountKey(ep, "alice@x"), Endpoint: ep, Email: "alice@x", Token: "t1", ExpiresAt: time.Now().Add(48 * time.Hour)})
@@ -148,8 +145,8 @@ func TestAuthListAndStatus(t *testing.T) {
 	}
 
 	// list
-	rn := Runner{Output: tempOut(t)}
-	if err := rn.authList(); err != nil {
+	rn := Runner{Output: tempOut(t), Endpoint: ep}
+	if err := rn.authGroup("list"); err != nil {
 		t.Fatalf("authList: %v", err)
 	}
 	out := readOut(t, rn.Output)
@@ -160,8 +157,8 @@ func TestAuthListAndStatus(t *testing.T) {
 	}
 
 	// status -> active account from the file
-	rn2 := Runner{Output: tempOut(t)}
-	if err := rn2.authStatus(); err != nil {
+	rn2 := Runner{Output: tempOut(t), Endpoint: ep}
+	if err := rn2.authGroup("status"); err != nil {
 		t.Fatalf("authStatus: %v", err)
 	}
 	st := readOut(t, rn2.Output)
@@ -174,7 +171,7 @@ func TestAuthStatusEnvToken(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	t.Setenv("DEPLOYS_TOKEN", "deploys-api.env")
 	rn := Runner{Output: tempOut(t)}
-	if err := rn.authStatus(); err != nil {
+	if err := rn.authGroup("status"); err != nil {
 		t.Fatalf("authStatus: %v", err)
 	}
 	out := readOut(t, rn.Output)
@@ -186,7 +183,6 @@ func TestAuthStatusEnvToken(t *testing.T) {
 func TestAuthSwitch(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	ep := "https://api.deploys.app/"
-	t.Setenv("DEPLOYS_ENDPOINT", ep)
 	if err := auth.Mutate(func(cc *auth.Credentials) error {
 		cc.Upsert(auth.Account{Key: auth.AccountKey(ep, "alice@x"), Endpoint: ep, Email: "alice@x", Token: "t1", ExpiresAt: time.Now().Add(time.Hour)})
 		cc.Upsert(auth.Account{Key: auth.AccountKey(ep, "bob@x"), Endpoint: ep, Email: "bob@x", Token: "t2", ExpiresAt: time.Now().Add(time.Hour)})
@@ -196,8 +192,8 @@ func TestAuthSwitch(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rn := Runner{Output: tempOut(t), Account: "bob@x"}
-	if err := rn.authSwitch(); err != nil {
+	rn := Runner{Output: tempOut(t), Endpoint: ep, Account: "bob@x"}
+	if err := rn.authGroup("switch"); err != nil {
 		t.Fatalf("authSwitch: %v", err)
 	}
 	c, _ := auth.Load()
@@ -210,7 +206,6 @@ func TestAuthTokenPipeNoNewline(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	t.Setenv("DEPLOYS_TOKEN", "")
 	ep := "https://api.deploys.app/"
-	t.Setenv("DEPLOYS_ENDPOINT", ep)
 	if err := auth.Mutate(func(cc *auth.Credentials) error {
 		cc.Upsert(auth.Account{Key: auth.AccountKey(ep, "alice@x"), Endpoint: ep, Email: "alice@x", Token: "deploys-api.tok", ExpiresAt: time.Now().Add(time.Hour)})
 		cc.SetActive(ep, auth.AccountKey(ep, "alice@x"))
@@ -219,8 +214,8 @@ func TestAuthTokenPipeNoNewline(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Output is a regular file (not a TTY) -> prints without a trailing newline.
-	rn := Runner{Output: tempOut(t)}
-	if err := rn.authToken(); err != nil {
+	rn := Runner{Output: tempOut(t), Endpoint: ep}
+	if err := rn.authGroup("token"); err != nil {
 		t.Fatalf("authToken: %v", err)
 	}
 	if got := readOut(t, rn.Output); got != "deploys-api.tok" {
@@ -231,7 +226,6 @@ func TestAuthTokenPipeNoNewline(t *testing.T) {
 func TestLogoutRevokesAndRemoves(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	ep := "https://api.deploys.app/"
-	t.Setenv("DEPLOYS_ENDPOINT", ep)
 
 	var revoked bool
 	revokeSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -250,7 +244,7 @@ func TestLogoutRevokesAndRemoves(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rn := Runner{Output: tempOut(t)}
+	rn := Runner{Output: tempOut(t), Endpoint: ep}
 	if err := rn.logout(); err != nil {
 		t.Fatalf("logout: %v", err)
 	}
@@ -266,7 +260,6 @@ func TestLogoutRevokesAndRemoves(t *testing.T) {
 func TestLogoutKeepsEntryOnRevokeFailure(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
 	ep := "https://api.deploys.app/"
-	t.Setenv("DEPLOYS_ENDPOINT", ep)
 
 	// An unreachable auth base makes revoke fail; the entry must be kept.
 	if err := auth.Mutate(func(cc *auth.Credentials) error {
@@ -277,7 +270,7 @@ func TestLogoutKeepsEntryOnRevokeFailure(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rn := Runner{Output: tempOut(t)}
+	rn := Runner{Output: tempOut(t), Endpoint: ep}
 	if err := rn.logout(); err == nil {
 		t.Fatal("logout should fail loudly when revoke fails")
 	}
@@ -289,8 +282,7 @@ func TestLogoutKeepsEntryOnRevokeFailure(t *testing.T) {
 
 func TestLogoutNothingToDo(t *testing.T) {
 	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
-	t.Setenv("DEPLOYS_ENDPOINT", "https://api.deploys.app/")
-	rn := Runner{Output: tempOut(t)}
+	rn := Runner{Output: tempOut(t), Endpoint: "https://api.deploys.app/"}
 	if err := rn.logout(); err != nil {
 		t.Fatalf("logout with no accounts should be a no-op success: %v", err)
 	}
@@ -1,27 +1,18 @@
 package runner
 
 import (
-	"context"
+	"strconv"
 
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) billing(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("billing")
-	}
+	return rn.runGroup("billing", args, rn.billingLeaf)
+}
 
+func (rn *Runner) billingLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Billing()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("billing", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("billing", args[0])
+	switch sub {
 	case "create":
 		var req api.BillingCreate
 		f.StringVar(&req.Name, "name", "", "billing account name")
@@ -29,16 +20,27 @@ func (rn Runner) billing(args ...string) error {
 		f.StringVar(&req.TaxID, "tax-id", "", "tax id")
 		f.StringVar(&req.TaxName, "tax-name", "", "tax name")
 		f.StringVar(&req.TaxAddress, "tax-address", "", "tax address")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("billing create", journalTarget{Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list":
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &api.Empty{})
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &api.Empty{})
+		}
 	case "get":
 		var req api.BillingGet
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "update":
 		var req api.BillingUpdate
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
@@ -47,13 +49,25 @@ func (rn Runner) billing(args ...string) error {
 		f.StringVar(&req.TaxID, "tax-id", "", "tax id")
 		f.StringVar(&req.TaxName, "tax-name", "", "tax name")
 		f.StringVar(&req.TaxAddress, "tax-address", "", "tax address")
-		f.Parse(args[1:])
-		resp, err = s.Update(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("billing update", journalTarget{Name: strconv.FormatInt(req.ID, 10)}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "delete":
 		var req api.BillingDelete
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("billing delete", journalTarget{Name: strconv.FormatInt(req.ID, 10)}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "report":
 		var (
 			req      api.BillingReport
@@ -62,58 +76,92 @@ func (rn Runner) billing(args ...string) error {
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
 		f.StringVar(&req.Range, "range", "", "report range")
 		f.StringVar(&projects, "projects", "", "project ids (comma separated values, empty = all)")
-		f.Parse(args[1:])
-		req.ProjectSIDs = splitComma(projects)
-		resp, err = s.Report(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			if projects != "" {
+				req.ProjectSIDs = splitComma(projects)
+			}
+			return s.Report(rn.ctx(), &req)
+		}
 	case "skus":
-		f.Parse(args[1:])
-		resp, err = s.SKUs(context.Background(), &api.Empty{})
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.SKUs(rn.ctx(), &api.Empty{})
+		}
 	case "project":
 		var req api.BillingProject
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Project(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Project(rn.ctx(), &req)
+		}
 	case "invoices":
 		var req api.InvoiceList
 		f.Int64Var(&req.BillingAccountID, "id", 0, "billing account id")
-		f.Parse(args[1:])
-		resp, err = s.ListInvoices(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.ListInvoices(rn.ctx(), &req)
+		}
 	case "invoice":
 		var req api.InvoiceGet
 		f.Int64Var(&req.InvoiceID, "id", 0, "invoice id")
-		f.Parse(args[1:])
-		resp, err = s.GetInvoice(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.GetInvoice(rn.ctx(), &req)
+		}
 	case "downloadinvoice":
 		var req api.InvoiceGet
 		f.Int64Var(&req.InvoiceID, "id", 0, "invoice id")
-		f.Parse(args[1:])
-		resp, err = s.DownloadInvoice(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.DownloadInvoice(rn.ctx(), &req)
+		}
 	case "downloadreceipt":
 		var req api.InvoiceGet
 		f.Int64Var(&req.InvoiceID, "id", 0, "invoice id")
-		f.Parse(args[1:])
-		resp, err = s.DownloadReceipt(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.DownloadReceipt(rn.ctx(), &req)
+		}
 	case "list-members", "listMembers":
 		var req api.BillingMemberList
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
-		f.Parse(args[1:])
-		resp, err = s.ListMembers(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.ListMembers(rn.ctx(), &req)
+		}
 	case "add-member", "addMember":
 		var req api.BillingMemberAdd
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
 		f.StringVar(&req.Email, "email", "", "member email")
 		f.StringVar(&req.Role, "role", "", "member role: admin|accountant")
-		f.Parse(args[1:])
-		resp, err = s.AddMember(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("billing add-member", journalTarget{Name: strconv.FormatInt(req.ID, 10), Email: req.Email}, &req)
+			resp, err = s.AddMember(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "remove-member", "removeMember":
 		var req api.BillingMemberRemove
 		f.Int64Var(&req.ID, "id", 0, "billing account id")
 		f.StringVar(&req.Email, "email", "", "member email")
-		f.Parse(args[1:])
-		resp, err = s.RemoveMember(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("billing remove-member", journalTarget{Name: strconv.FormatInt(req.ID, 10), Email: req.Email}, &req)
+			resp, err = s.RemoveMember(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
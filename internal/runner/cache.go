@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -10,32 +9,30 @@ import (
 )
 
 func (rn Runner) cache(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("cache")
-	}
+	return rn.runGroup("cache", args, rn.cacheLeaf)
+}
 
+func (rn *Runner) cacheLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Cache()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("cache", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("cache", args[0])
+	zone := rn.cacheZone()
+	switch sub {
 	case "get":
 		var req api.CacheGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.CacheList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "set":
 		// Set replaces the whole zone (all overrides) all-or-nothing, so it takes
 		// a spec file rather than per-override flags. The file is the yaml form of
@@ -46,57 +43,95 @@ func (rn Runner) cache(args ...string) error {
 			project     string
 			location    string
 			description string
+			keep        int
 		)
 		f.StringVar(&fn, "f", "", "spec file (yaml: description, overrides)")
 		f.StringVar(&project, "project", "", "project id")
 		f.StringVar(&location, "location", "", "location")
 		f.StringVar(&description, "description", "", "zone description")
-		f.Parse(args[1:])
+		f.IntVar(&keep, "keep", defaultSnapshotKeep, snapshotKeepUsage)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		if fn == "" {
-			return fmt.Errorf("spec file required (-f)")
+			if fn == "" {
+				return nil, fmt.Errorf("spec file required (-f)")
+			}
+			b, ferr := os.ReadFile(fn)
+			if ferr != nil {
+				return nil, ferr
+			}
+			// non-strict so the yaml output of `cache get` (which carries extra
+			// read-only fields) can be edited and fed back in
+			var req api.CacheSet
+			ferr = yaml.Unmarshal(b, &req)
+			if ferr != nil {
+				return nil, fmt.Errorf("parse %s: %w", fn, ferr)
+			}
+			f.override("project", &req.Project, project)
+			f.override("location", &req.Location, location)
+			if description != "" {
+				req.Description = description
+			}
+			resp, err = zone.apply(rn, "set", req.Project, req.Location, keep, &req, func() (any, error) {
+				return s.Set(rn.ctx(), &req)
+			})
+			return resp, err
 		}
-		b, ferr := os.ReadFile(fn)
-		if ferr != nil {
-			return ferr
-		}
-		// non-strict so the yaml output of `cache get` (which carries extra
-		// read-only fields) can be edited and fed back in
+	case "init":
+		// init writes a spec file for `set`; it makes no change to the zone.
+		return printed(rn.cacheInit(s, f))
+	case "history":
+		return zone.history(f)
+	case "restore":
 		var req api.CacheSet
-		ferr = yaml.Unmarshal(b, &req)
-		if ferr != nil {
-			return fmt.Errorf("parse %s: %w", fn, ferr)
-		}
-		if project != "" {
-			req.Project = project
-		}
-		if location != "" {
-			req.Location = location
-		}
-		if description != "" {
-			req.Description = description
-		}
-		resp, err = s.Set(context.Background(), &req)
+		return zone.restore(rn, f, &req, &req.Project, &req.Location, func() (any, error) {
+			return s.Set(rn.ctx(), &req)
+		})
 	case "delete":
 		var req api.CacheDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("cache delete", journalTarget{Project: req.Project, Location: req.Location}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "metrics":
 		var (
 			req       api.CacheMetrics
 			timeRange string
+			report    bool
 		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&timeRange, "time-range", "1h", "time range (1h, 6h, 12h, 1d, 7d, 30d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.WAFMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.BoolVar(&report, "report", false, "summarize hit ratio, bandwidth saved, and uncached overrides instead of raw series")
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.TimeRange = api.WAFMetricsTimeRange(timeRange)
+			res, err := s.Metrics(rn.ctx(), &req)
+			if err != nil || !report {
+				return res, err
+			}
+			// hit ratio and bytes come from the project-wide result metrics (summed
+			// across locations); the api has no per-location or per-path breakdown
+			results, err := s.ResultMetrics(rn.ctx(), &api.CacheResultMetrics{Project: req.Project, TimeRange: req.TimeRange})
+			if err != nil {
+				return nil, err
+			}
+			return newCacheReport(req.TimeRange, results, res), nil
+		}
 	}
-	return rn.print(resp)
+	return nil
+}
+
+// cacheZone snapshots cache zones through their get.
+func (rn Runner) cacheZone() zoneSnapshots {
+	return zoneSnapshots{kind: "cache", notFound: api.ErrCacheZoneNotFound, get: func(project, location string) (any, error) {
+		return rn.API.Cache().Get(rn.ctx(), &api.CacheGet{Project: project, Location: location})
+	}}
 }
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/deploys-app/api"
+	"gopkg.in/yaml.v2"
+)
+
+// collectorPush reads a usage file and sends it to one usage endpoint.
+type collectorPush func(ctx context.Context, s api.Collector, b []byte, location string) (any, error)
+
+// collectorKinds maps each `collector push -kind` to the usage endpoint it
+// feeds. The request is read from the -f file (yaml or json, the api's wire
+// shape: location, list/resources) and sent as-is.
+var collectorKinds = map[string]collectorPush{
+	"project":       collect(api.Collector.SetProjectUsage, func(r *api.CollectorSetProjectUsage) *string { return &r.Location }),
+	"deployment":    collect(api.Collector.SetDeploymentUsage, func(r *api.CollectorSetDeploymentUsage) *string { return &r.Location }),
+	"disk":          collect(api.Collector.SetDiskUsage, func(r *api.CollectorSetDiskUsage) *string { return &r.Location }),
+	"waf":           collect(api.Collector.SetWAFUsage, func(r *api.CollectorSetWAFUsage) *string { return &r.Location }),
+	"ratelimit":     collect(api.Collector.SetRateLimitUsage, func(r *api.CollectorSetRateLimitUsage) *string { return &r.Location }),
+	"cacheoverride": collect(api.Collector.SetCacheOverrideUsage, func(r *api.CollectorSetCacheOverrideUsage) *string { return &r.Location }),
+	"cacheresult":   collect(api.Collector.SetCacheResultUsage, func(r *api.CollectorSetCacheResultUsage) *string { return &r.Location }),
+}
+
+// collect is the push of one usage kind: decode the file into a T, point
+// location at its Location field, and send it with set (the endpoint's method
+// expression on api.Collector).
+func collect[T any](set func(api.Collector, context.Context, *T) (*api.Empty, error), location func(*T) *string) collectorPush {
+	return func(ctx context.Context, s api.Collector, b []byte, loc string) (any, error) {
+		var req T
+		if err := unmarshalCollectorUsage(b, &req, location(&req), loc); err != nil {
+			return nil, err
+		}
+		return set(s, ctx, &req)
+	}
+}
+
+func collectorKindNames() string {
+	names := make([]string, 0, len(collectorKinds))
+	for k := range collectorKinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// unmarshalCollectorUsage decodes a usage file into req and applies the
+// -location override. A json body is decoded as json, not as yaml, because the
+// api's wire shape carries project ids as strings (`json:",string"`), which
+// only encoding/json understands.
+func unmarshalCollectorUsage(b []byte, req any, reqLocation *string, location string) error {
+	var err error
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		err = json.Unmarshal(b, req)
+	} else {
+		err = yaml.Unmarshal(b, req)
+	}
+	if err != nil {
+		return fmt.Errorf("parse usage file: %w", err)
+	}
+	if location != "" {
+		*reqLocation = location
+	}
+	return nil
+}
+
+// collector wraps the usage-collector endpoints. They are internal: the api
+// authenticates them with a location's collector token (pass it as
+// DEPLOYS_TOKEN), not a user permission, and there is nothing to create,
+// update, or delete — a collector only reads which projects a location serves
+// and pushes usage buckets, which the api upserts idempotently.
+func (rn Runner) collector(args ...string) error {
+	return rn.runGroup("collector", args, rn.collectorLeaf)
+}
+
+func (rn *Runner) collectorLeaf(f *leafFlagSet, sub string) leafRun {
+	s := rn.API.Collector()
+	switch sub {
+	case "location":
+		var req api.CollectorLocation
+		f.StringVar(&req.Location, "location", "", "location")
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Location(rn.ctx(), &req)
+		}
+	case "push":
+		var (
+			kind     string
+			fn       string
+			location string
+		)
+		f.StringVar(&kind, "kind", "", "usage kind ("+collectorKindNames()+")")
+		f.StringVar(&fn, "f", "", "usage file (json request body, or yaml)")
+		f.StringVar(&location, "location", "", "location (overrides the file)")
+		return func(args []string) (any, error) {
+			f.Parse(args)
+
+			push := collectorKinds[kind]
+			if push == nil {
+				return nil, fmt.Errorf("invalid -kind %q (want one of: %s)", kind, collectorKindNames())
+			}
+			if fn == "" {
+				return nil, fmt.Errorf("usage file required (-f)")
+			}
+			b, ferr := os.ReadFile(fn)
+			if ferr != nil {
+				return nil, ferr
+			}
+			return push(rn.ctx(), s, b, location)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+type fakeCollector struct {
+	api.Collector
+	waf *api.CollectorSetWAFUsage
+}
+
+func (c *fakeCollector) SetWAFUsage(_ context.Context, m *api.CollectorSetWAFUsage) (*api.Empty, error) {
+	c.waf = m
+	return &api.Empty{}, nil
+}
+
+// push decodes the file in the api's wire shape (json ids are strings) and
+// -location overrides the file's location.
+func TestCollectorPushWAF(t *testing.T) {
+	b := []byte(`{"location": "file-loc", "list": [{"projectId": "42", "ruleId": "42-abc", "action": "block", "value": 3, "at": 1700000040}]}`)
+	var c fakeCollector
+	if _, err := collectorKinds["waf"](context.Background(), &c, b, "gke.cluster-rcf2"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if c.waf == nil || c.waf.Location != "gke.cluster-rcf2" || len(c.waf.List) != 1 || c.waf.List[0].RuleID != "42-abc" || c.waf.List[0].Value != 3 {
+		t.Errorf("request = %+v", c.waf)
+	}
+
+	if _, err := collectorKinds["waf"](context.Background(), &c, []byte("list: ["), ""); err == nil {
+		t.Error("malformed usage file should fail")
+	}
+}
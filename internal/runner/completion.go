@@ -0,0 +1,511 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+// completeCommand is the hidden command the completion scripts call with the
+// words of the command line; it prints the candidates for the last one.
+const completeCommand = "__complete"
+
+// IsCompleteCommand reports whether name is the hidden completion helper.
+// main builds the api client for it when it can, but completion never fails
+// the shell for want of a login: it just offers no api-backed values.
+func IsCompleteCommand(name string) bool {
+	return name == completeCommand
+}
+
+const (
+	// completionCacheFile holds recently fetched dynamic values, under the
+	// config dir, so repeated tabs do not wait on the api.
+	completionCacheFile = "completion-cache.json"
+	completionCacheTTL  = time.Minute
+	// completionTimeout bounds an api fetch; a slow api offers nothing rather
+	// than freezing the prompt.
+	completionTimeout = 3 * time.Second
+)
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// completion handles `deploys completion <shell>`: print the shell's script.
+// The scripts are thin: they hand the command line to `deploys __complete`,
+// which answers from the registry and the real flag sets, so completion
+// always matches the binary it runs.
+func (rn Runner) completion(args ...string) error {
+	if len(args) == 0 || IsHelpArg(args[0]) {
+		writeCompletionUsage(rn.output())
+		return nil
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return fmt.Errorf("completion: unknown shell %q (want bash, zsh, fish, or powershell)", args[0])
+	}
+	_, err := io.WriteString(rn.output(), script)
+	return err
+}
+
+func writeCompletionUsage(w io.Writer) {
+	fmt.Fprint(w, "completion — print a shell completion script\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys completion bash|zsh|fish|powershell\n\n")
+	fmt.Fprint(w, "Load it in the current shell, e.g.:\n")
+	fmt.Fprint(w, "  source <(deploys completion bash)\n")
+	fmt.Fprint(w, "  source <(deploys completion zsh)\n")
+	fmt.Fprint(w, "  deploys completion fish | source\n")
+	fmt.Fprint(w, "  deploys completion powershell | Out-String | Invoke-Expression\n")
+}
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for deploys
+_deploys() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    [[ $cur == "=" ]] && cur=""
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(deploys __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _deploys deploys
+`,
+	"zsh": `#compdef deploys
+# zsh completion for deploys
+_deploys() {
+  local -a candidates
+  candidates=(${(f)"$(deploys __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+  if (( ${#candidates} )); then
+    compadd -- "${candidates[@]}"
+  else
+    _files
+  fi
+}
+if [ "$funcstack[1]" = "_deploys" ]; then
+  _deploys "$@"
+else
+  compdef _deploys deploys
+fi
+`,
+	"fish": `# fish completion for deploys
+function __deploys_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    deploys __complete $words (commandline -ct) 2>/dev/null
+end
+complete -c deploys -f -a '(__deploys_complete)'
+`,
+	"powershell": `# powershell completion for deploys
+Register-ArgumentCompleter -Native -CommandName deploys -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '""' }
+    & deploys __complete @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+// complete handles the hidden `deploys __complete <words...>`: the candidates
+// for the last word (which may be empty), one per line.
+func (rn Runner) complete(words ...string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	// best effort: a broken context or .deploys.yaml only loses the defaults
+	rn.scope, _ = loadScopeDefaults()
+	for _, c := range rn.completions(normalizeCompletionWords(words)) {
+		fmt.Fprintln(rn.output(), c)
+	}
+	return nil
+}
+
+// normalizeCompletionWords undoes the shells' quirks: bash splits -flag=value
+// at the "=", and powershell passes an empty word as "".
+func normalizeCompletionWords(words []string) []string {
+	if last := len(words) - 1; words[last] == `""` || words[last] == `''` {
+		words[last] = ""
+	}
+	res := make([]string, 0, len(words))
+	for i, w := range words {
+		if w == "=" && i > 0 && isFlag(words[i-1]) {
+			if i == len(words)-1 {
+				res = append(res, "")
+			}
+			continue
+		}
+		res = append(res, w)
+	}
+	return res
+}
+
+// completions returns the candidates for the last of words, filtered by it.
+func (rn Runner) completions(words []string) []string {
+	cur := words[len(words)-1]
+	prev := words[:len(words)-1]
+	if len(prev) == 0 {
+		return matching(topLevelNames(), cur)
+	}
+
+	var group, sub string
+	switch name := prev[0]; {
+	case name == "completion":
+		if len(prev) == 1 {
+			return matching(completionShells, cur)
+		}
+		return nil
+	case name == "refs":
+		if len(prev) == 1 {
+			return matching(refTypeNames(), cur)
+		}
+		group = name
+	case name == "api":
+		if len(prev) == 1 && !strings.HasPrefix(cur, "-") {
+			var names []string
+			for _, m := range apiMethods() {
+				names = append(names, m.Name)
+			}
+			return matching(names, cur)
+		}
+		group = name
+	case name == "login" || name == "logout" || lookupStandalone(name) != nil:
+		group = name
+	default:
+		c := lookupCommand(name)
+		if c == nil {
+			return nil
+		}
+		group = c.name
+		if len(prev) == 1 {
+			return matching(c.subNames(), cur)
+		}
+		e := c.lookupSub(prev[1])
+		if e == nil {
+			return nil
+		}
+		sub = e.name
+		if group == "deployment" && sub == "set" {
+			if len(prev) == 2 {
+				return matching([]string{"image"}, cur)
+			}
+			sub = "set image"
+		}
+	}
+
+	f := leafFlags(group, sub)
+	if f == nil {
+		return nil
+	}
+	if name, value, ok := strings.Cut(cur, "="); ok && isFlag(name) {
+		var res []string
+		n, _ := flagName(name)
+		for _, v := range matching(rn.flagValues(group, n, words), value) {
+			res = append(res, name+"="+v)
+		}
+		return res
+	}
+	if isFlag(cur) || cur == "-" {
+		return matching(flagNames(f), cur)
+	}
+	if last := prev[len(prev)-1]; isFlag(last) && !strings.Contains(last, "=") {
+		n, _ := flagName(last)
+		if fl := f.Lookup(n); fl != nil && !isBoolFlag(fl) {
+			return matching(rn.flagValues(group, fl.Name, words), cur)
+		}
+	}
+	return matching(rn.positionalValues(group, sub, words), cur)
+}
+
+// flagValues returns the values worth offering for a flag. The api-backed
+// ones are scoped by the -project/-location already on the command line, else
+// by the scope defaults.
+func (rn Runner) flagValues(group, name string, words []string) []string {
+	project := completionScope(words, "project", rn.scope, group)
+	location := completionScope(words, "location", rn.scope, group)
+	switch name {
+	case "output":
+		return []string{"table", "yaml", "json", "toon", "csv", "tsv", "markdown"}
+	case "format":
+		if group == "docs" {
+			return []string{"man", "markdown"}
+		}
+	case "project":
+		return rn.completionValues("project", "", "")
+	case "location":
+		return rn.completionValues("location", project, "")
+	case "deployment":
+		return rn.completionValues("deployment", project, location)
+	case "domain":
+		return rn.completionValues("domain", project, location)
+	case "name":
+		switch group {
+		case "deployment":
+			return rn.completionValues("deployment", project, location)
+		case "envgroup":
+			return rn.completionValues("envgroup", project, "")
+		}
+	}
+	return nil
+}
+
+// positionalValues returns the candidates for a leaf's positional argument.
+func (rn Runner) positionalValues(group, sub string, words []string) []string {
+	project := completionScope(words, "project", rn.scope, group)
+	location := completionScope(words, "location", rn.scope, group)
+	switch group + " " + sub {
+	case "deployment set image":
+		return rn.completionValues("deployment", project, location)
+	case "refs ":
+		switch refTypes[words[1]] {
+		case "deployment":
+			return rn.completionValues("deployment", project, location)
+		case "envgroup":
+			return rn.completionValues("envgroup", project, "")
+		}
+	case "config set", "config get", "config unset":
+		return auth.ContextKeys
+	case "context use", "context update", "context delete":
+		c, err := auth.LoadContexts()
+		if err != nil {
+			return nil
+		}
+		return c.Names()
+	}
+	return nil
+}
+
+// completionScope is the value of -key on the command line, else its scope
+// default.
+func completionScope(words []string, key string, scope *scopeDefaults, group string) string {
+	for i, w := range words {
+		if n, ok := flagName(w); !ok || n != key {
+			continue
+		}
+		_, value, hasValue := strings.Cut(w, "=")
+		if hasValue {
+			return value
+		}
+		if i+1 < len(words)-1 {
+			return words[i+1]
+		}
+	}
+	v, _ := scope.lookup(group, key)
+	return v
+}
+
+// completionValues returns the api-backed values of kind, from the cache when
+// it is fresh enough.
+func (rn Runner) completionValues(kind, project, location string) []string {
+	endpoint := rn.journalEndpoint()
+	key := strings.Join([]string{endpoint, rn.journalActor(endpoint), kind, project, location}, "|")
+	cache := loadCompletionCache()
+	if e, ok := cache[key]; ok && time.Since(e.At) < completionCacheTTL {
+		return e.Values
+	}
+	if rn.API == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	values, err := fetchCompletionValues(ctx, rn.API, kind, project, location)
+	if err != nil {
+		return nil
+	}
+	for k, e := range cache {
+		if time.Since(e.At) >= completionCacheTTL {
+			delete(cache, k)
+		}
+	}
+	cache[key] = completionCacheEntry{At: time.Now(), Values: values}
+	saveCompletionCache(cache)
+	return values
+}
+
+// fetchCompletionValues lists the names of kind from the api.
+func fetchCompletionValues(ctx context.Context, a api.Interface, kind, project, location string) ([]string, error) {
+	values := []string{}
+	switch kind {
+	case "project":
+		res, err := a.Project().List(ctx, &api.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range res.Items {
+			values = append(values, x.Project)
+		}
+	case "location":
+		res, err := a.Location().List(ctx, &api.LocationList{Project: project})
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range res.Items {
+			values = append(values, x.ID)
+		}
+	case "deployment":
+		if project == "" {
+			return nil, errors.New("project required")
+		}
+		res, err := a.Deployment().List(ctx, &api.DeploymentList{Project: project, Location: location})
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range res.Items {
+			values = append(values, x.Name)
+		}
+	case "envgroup":
+		if project == "" {
+			return nil, errors.New("project required")
+		}
+		res, err := a.EnvGroup().List(ctx, &api.EnvGroupList{Project: project})
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range res.Items {
+			values = append(values, x.Name)
+		}
+	case "domain":
+		if project == "" {
+			return nil, errors.New("project required")
+		}
+		res, err := a.Domain().List(ctx, &api.DomainList{Project: project, Location: location})
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range res.Items {
+			values = append(values, x.Domain)
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+type completionCacheEntry struct {
+	At     time.Time `json:"at"`
+	Values []string  `json:"values"`
+}
+
+func completionCachePath() (string, error) {
+	d, err := auth.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, completionCacheFile), nil
+}
+
+// loadCompletionCache reads the cache; a missing or unreadable one is empty.
+func loadCompletionCache() map[string]completionCacheEntry {
+	cache := map[string]completionCacheEntry{}
+	fn, err := completionCachePath()
+	if err != nil {
+		return cache
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return cache
+	}
+	if json.Unmarshal(b, &cache) != nil {
+		return map[string]completionCacheEntry{}
+	}
+	return cache
+}
+
+// saveCompletionCache writes the cache, best effort: a failed write only costs
+// the next tab an api call.
+func saveCompletionCache(cache map[string]completionCacheEntry) {
+	fn, err := completionCachePath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return
+	}
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, fn); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// topLevelNames returns every command that can start a command line.
+func topLevelNames() []string {
+	names := []string{"login", "logout"}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	for _, c := range standaloneCommands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// lookupStandalone finds a standalone command by name.
+func lookupStandalone(name string) *subcommand {
+	for i := range standaloneCommands {
+		if standaloneCommands[i].name == name {
+			return &standaloneCommands[i]
+		}
+	}
+	return nil
+}
+
+// subNames returns the group's listed subcommand names.
+func (c *command) subNames() []string {
+	var names []string
+	for _, s := range c.subs {
+		if !s.hidden {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+func refTypeNames() []string {
+	names := make([]string, 0, len(refTypes))
+	for n := range refTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flagNames returns f's flags as -name, sorted.
+func flagNames(f *flag.FlagSet) []string {
+	var names []string
+	f.VisitAll(func(fl *flag.Flag) {
+		names = append(names, "-"+fl.Name)
+	})
+	return names
+}
+
+func isBoolFlag(fl *flag.Flag) bool {
+	b, ok := fl.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// matching returns the values that start with prefix, in order.
+func matching(values []string, prefix string) []string {
+	var res []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+// completionAPI serves project and deployment lists and counts the calls, to
+// observe the cache.
+type completionAPI struct {
+	api.Interface
+	calls int
+}
+
+func (a *completionAPI) Project() api.Project       { return completionProject{a: a} }
+func (a *completionAPI) Deployment() api.Deployment { return completionDeployment{a: a} }
+
+type completionProject struct {
+	api.Project
+	a *completionAPI
+}
+
+func (s completionProject) List(context.Context, *api.Empty) (*api.ProjectListResult, error) {
+	s.a.calls++
+	return &api.ProjectListResult{Items: []*api.ProjectItem{{Project: "beta"}, {Project: "acme"}}}, nil
+}
+
+type completionDeployment struct {
+	api.Deployment
+	a *completionAPI
+}
+
+func (s completionDeployment) List(_ context.Context, m *api.DeploymentList) (*api.DeploymentListResult, error) {
+	s.a.calls++
+	if m.Project != "acme" {
+		return &api.DeploymentListResult{}, nil
+	}
+	return &api.DeploymentListResult{Items: []*api.DeploymentListItem{{Name: "web"}, {Name: "worker"}}}, nil
+}
+
+func newCompletionRunner(t *testing.T) (Runner, *completionAPI) {
+	t.Helper()
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Setenv("DEPLOYS_TOKEN", "tok")
+	t.Chdir(t.TempDir())
+	a := &completionAPI{}
+	return Runner{API: a}, a
+}
+
+func TestCompletions(t *testing.T) {
+	rn, _ := newCompletionRunner(t)
+	cases := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"dep"}, []string{"deployment"}},
+		{[]string{"com"}, []string{"completion"}},
+		{[]string{"eg", "up"}, []string{"update"}},
+		{[]string{"deployment", "set", ""}, []string{"image"}},
+		{[]string{"d", "deploy", "-min"}, []string{"-minReplicas"}},
+		{[]string{"d", "set", "image", "web", "-i"}, []string{"-image"}},
+		{[]string{"envgroup", "delete", "-f"}, []string{"-f", "-force"}},
+		{[]string{"login", "-no"}, []string{"-no-browser"}},
+		{[]string{"history", "-l"}, []string{"-limit"}},
+		{[]string{"refs", "e"}, []string{"eg", "envgroup"}},
+		{[]string{"completion", "f"}, []string{"fish"}},
+		{[]string{"config", "set", "pro"}, []string{"project"}},
+		{[]string{"d", "list", "-output", "j"}, []string{"json"}},
+		{[]string{"d", "list", "-output=t"}, []string{"-output=table", "-output=toon", "-output=tsv"}},
+		{[]string{"d", "list", "-output", "=", "y"}, []string{"yaml"}},
+		{[]string{"d", "list", "-project", ""}, []string{"acme", "beta"}},
+		{[]string{"d", "get", "-project", "acme", "-name", "w"}, []string{"web", "worker"}},
+		{[]string{"d", "set", "image", ""}, nil}, // no project to list in
+		{[]string{"d", "list", "-yes", ""}, nil},
+		{[]string{"nope", ""}, nil},
+	}
+	for _, c := range cases {
+		got := rn.completions(normalizeCompletionWords(slices.Clone(c.words)))
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%q: got %q, want %q", c.words, got, c.want)
+		}
+	}
+}
+
+func TestCompletionScopeDefaults(t *testing.T) {
+	rn, _ := newCompletionRunner(t)
+	t.Setenv("DEPLOYS_PROJECT", "acme")
+	rn.scope, _ = loadScopeDefaults()
+	got := rn.completions([]string{"d", "set", "image", ""})
+	if want := []string{"web", "worker"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompletionValuesCached(t *testing.T) {
+	rn, a := newCompletionRunner(t)
+	for range 2 {
+		if got := rn.completionValues("project", "", ""); !slices.Equal(got, []string{"acme", "beta"}) {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if a.calls != 1 {
+		t.Errorf("api called %d times, want 1 (second from cache)", a.calls)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("DEPLOYS_CONFIG_DIR"), completionCacheFile)); err != nil {
+		t.Errorf("cache file: %v", err)
+	}
+
+	// a different identity does not share the cached values
+	t.Setenv("DEPLOYS_TOKEN", "")
+	t.Setenv("DEPLOYS_AUTH_USER", "ci@acme.iam")
+	t.Setenv("DEPLOYS_AUTH_PASS", "secret")
+	rn.completionValues("project", "", "")
+	if a.calls != 2 {
+		t.Errorf("api called %d times, want 2 (cache is per identity)", a.calls)
+	}
+}
+
+func TestCompletionWithoutAPI(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	var rn Runner
+	if got := rn.completions([]string{"d", "list", "-project", ""}); got != nil {
+		t.Errorf("got %q, want nothing", got)
+	}
+	if got := rn.completions([]string{"d", "li"}); !slices.Equal(got, []string{"list"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+// TestLeafFlagsCoverRegistry probes every registry entry: each must reach its
+// flag parsing offline, so completion (and generated docs) see its flags.
+func TestLeafFlagsCoverRegistry(t *testing.T) {
+	for _, c := range commands {
+		for _, s := range c.subs {
+			if c.name == "deployment" && s.name == "set" {
+				continue // the listing of the hidden "set image" leaf
+			}
+			if leafFlags(c.name, s.name) == nil {
+				t.Errorf("deploys %s %s: no flag set", c.name, s.name)
+			}
+		}
+	}
+	for _, name := range []string{"login", "logout", "refs", "history", "undo", "version", "check-update"} {
+		if leafFlags(name, "") == nil {
+			t.Errorf("deploys %s: no flag set", name)
+		}
+	}
+	f := leafFlags("deployment", "deploy")
+	if f == nil || f.Lookup("image") == nil {
+		t.Fatal("deployment deploy: missing -image")
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, sh := range completionShells {
+		out := tempOut(t)
+		if err := (Runner{Output: out}).Run("completion", sh); err != nil {
+			t.Fatal(err)
+		}
+		if s := readOut(t, out); !strings.Contains(s, "deploys __complete") {
+			t.Errorf("%s script does not call __complete:\n%s", sh, s)
+		}
+	}
+	if err := (Runner{Output: tempOut(t)}).Run("completion", "tcsh"); err == nil {
+		t.Error("unknown shell: want error")
+	}
+}
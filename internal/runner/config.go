@@ -0,0 +1,529 @@
+package runner
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+// scopeKeys are the flags an unset value of which falls back to the scope
+// defaults, in the order they are reported. name is only defaulted for
+// deployment commands (from .deploys.yaml's deployment).
+var scopeKeys = []string{"project", "location", "name"}
+
+// noScopeFill lists leaves whose -project/-location must never be defaulted:
+// project create names a new project, so filling in the current one would only
+// collide with it, and a collector push file carries its own location.
+var noScopeFill = map[string]bool{
+	"project create": true,
+	"collector push": true,
+}
+
+// scopeDefaults is where an unset -project/-location comes from, in order: the
+// DEPLOYS_PROJECT/DEPLOYS_LOCATION environment, the nearest .deploys.yaml, and
+// the active context. The .deploys.yaml also supplies a deployment's -name and
+// per-command default flags. A nil *scopeDefaults fills nothing.
+type scopeDefaults struct {
+	contextName string
+	context     *auth.Context
+	dir         *dirConfig
+	quiet       bool // fill without the stderr notes (a -watch refresh)
+}
+
+// loadScopeDefaults resolves the active context and the directory's
+// .deploys.yaml once per command, so a broken file or an unknown
+// DEPLOYS_CONTEXT fails up front rather than silently leaving flags empty.
+func loadScopeDefaults() (*scopeDefaults, error) {
+	name, ctx, err := auth.ActiveContext()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := loadDirConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &scopeDefaults{contextName: name, context: ctx, dir: dir}, nil
+}
+
+// lookup returns the default for key in group and where it came from.
+func (d *scopeDefaults) lookup(group, key string) (value, source string) {
+	if d == nil {
+		return "", ""
+	}
+	if key == "name" {
+		if group == "deployment" && d.dir != nil && d.dir.Deployment != "" {
+			return d.dir.Deployment, d.dir.Path
+		}
+		return "", ""
+	}
+	env := "DEPLOYS_" + strings.ToUpper(key)
+	if v := os.Getenv(env); v != "" {
+		return v, env
+	}
+	if d.dir != nil {
+		v := d.dir.Project
+		if key == "location" {
+			v = d.dir.Location
+		}
+		if v != "" {
+			return v, d.dir.Path
+		}
+	}
+	if d.context != nil {
+		if v, _ := d.context.Get(key); v != "" {
+			return v, fmt.Sprintf("context %q", d.contextName)
+		}
+	}
+	return "", ""
+}
+
+// defaultFlags returns the .deploys.yaml default flags of a canonical
+// "<group> <sub>" command.
+func (d *scopeDefaults) defaultFlags(command string) []string {
+	if d == nil || d.dir == nil {
+		return nil
+	}
+	return d.dir.Flags[command]
+}
+
+// fill sets each scope key that unset reports as unset to its default, and
+// notes on stderr which values were filled in and from where. It returns the
+// keys it filled.
+func (d *scopeDefaults) fill(group string, unset func(key string) bool, set func(key, value string)) map[string]bool {
+	filled := map[string]bool{}
+	var (
+		sources []string
+		notes   = map[string][]string{}
+	)
+	for _, k := range scopeKeys {
+		if !unset(k) {
+			continue
+		}
+		v, src := d.lookup(group, k)
+		if v == "" {
+			continue
+		}
+		set(k, v)
+		filled[k] = true
+		if !slices.Contains(sources, src) {
+			sources = append(sources, src)
+		}
+		notes[src] = append(notes[src], k+" "+v)
+	}
+	for _, src := range sources {
+		if !d.quiet {
+			fmt.Fprintf(os.Stderr, "using %s (%s)\n", strings.Join(notes[src], ", "), src)
+		}
+	}
+	return filled
+}
+
+// fillFlags fills the scope flags f defines but the command line left unset.
+func (d *scopeDefaults) fillFlags(group string, f *flag.FlagSet) map[string]bool {
+	set := visitedFlags(f)
+	return d.fill(group,
+		func(k string) bool { return f.Lookup(k) != nil && !set[k] },
+		func(k, v string) { f.Set(k, v) },
+	)
+}
+
+// fillFields fills empty project/location/name request fields, for commands
+// that parse their flags outside a leafFlagSet.
+func (d *scopeDefaults) fillFields(group string, project, location, name *string) {
+	fields := map[string]*string{"project": project, "location": location, "name": name}
+	d.fill(group,
+		func(k string) bool { return fields[k] != nil && *fields[k] == "" },
+		func(k, v string) { *fields[k] = v },
+	)
+}
+
+// leafFlagSet is a leaf command's flag set. Its Parse applies the command's
+// .deploys.yaml default flags and fills in the -project, -location (and, for
+// deployments, -name) the user left unset.
+type leafFlagSet struct {
+	*flag.FlagSet
+	group   string
+	command string // canonical "<group> <sub>"
+	scope   *scopeDefaults
+	filled  map[string]bool
+	// body is the api request the flags fill, when the leaf passed it to
+	// Request; -f then reads it from requestFile.
+	body        any
+	requestFile *string
+}
+
+// Request binds the leaf to req, the api request its flags fill, and adds the
+// -f flag that reads it from a file.
+func (f *leafFlagSet) Request(req any) {
+	f.body = req
+	if f.Lookup("f") == nil { // a leaf's own -f file wins
+		f.requestFile = f.String("f", "", requestFileUsage)
+	}
+}
+
+func (f *leafFlagSet) Parse(args []string) error {
+	args = withDefaultFlags(f.scope.defaultFlags(f.command), args)
+	if f.requestFile != nil {
+		fileArgs, err := applyRequestFile(f.FlagSet, args, f.body)
+		if err != nil {
+			// as a bad flag value would under ExitOnError
+			fmt.Fprintln(f.Output(), err)
+			if f.ErrorHandling() == flag.ExitOnError {
+				os.Exit(2)
+			}
+			return err
+		}
+		args = append(fileArgs, args...)
+	}
+	if err := f.FlagSet.Parse(args); err != nil {
+		return err
+	}
+	f.filled = f.scope.fillFlags(f.group, f.FlagSet)
+	return nil
+}
+
+// ParseRequest parses args into req, the api request the leaf's flags are
+// bound to, after the -f request file if one is given.
+func (f *leafFlagSet) ParseRequest(req any, args []string) error {
+	f.Request(req)
+	return f.Parse(args)
+}
+
+// override applies a -project/-location value over one read from a spec file,
+// unless the value was only a scope default: the file is more specific.
+func (f *leafFlagSet) override(key string, dst *string, v string) {
+	if v == "" || (f.filled[key] && *dst != "") {
+		return
+	}
+	*dst = v
+}
+
+// config handles `deploys config`: the settings of the active context.
+func (rn Runner) config(args ...string) error {
+	return rn.runGroup("config", args, rn.configLeaf)
+}
+
+func (rn *Runner) configLeaf(f *leafFlagSet, sub string) leafRun {
+	switch sub {
+	case "set", "unset":
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			key, value := f.Arg(0), f.Arg(1)
+			if key == "" || (sub == "set" && f.NArg() != 2) {
+				f.Usage()
+				return nil, fmt.Errorf("deploys config %s: key required", sub)
+			}
+			var name string
+			err := auth.MutateContexts(func(c *auth.Contexts) error {
+				name = os.Getenv("DEPLOYS_CONTEXT")
+				if name == "" {
+					name = c.Current
+				}
+				if name == "" {
+					// the first setting creates the default context
+					name = auth.DefaultContext
+					c.Current = name
+				}
+				ctx := c.Contexts[name]
+				if ctx == nil {
+					if os.Getenv("DEPLOYS_CONTEXT") != "" {
+						return fmt.Errorf("context %q not found (see 'deploys context list')", name)
+					}
+					ctx = &auth.Context{}
+					c.Contexts[name] = ctx
+				}
+				return ctx.Set(key, value)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if sub == "set" {
+				fmt.Fprintf(rn.output(), "context %q: %s = %s\n", name, key, value)
+			} else {
+				fmt.Fprintf(rn.output(), "context %q: %s unset\n", name, key)
+			}
+			return nil, nil
+		}
+	case "show":
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			res, err := rn.configShow()
+			if err != nil {
+				return nil, err
+			}
+			return nil, rn.print(res)
+		}
+	case "get":
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			if f.NArg() != 1 {
+				f.Usage()
+				return nil, fmt.Errorf("deploys config get: key required")
+			}
+			_, ctx, err := auth.ActiveContext()
+			if err != nil {
+				return nil, err
+			}
+			if ctx == nil {
+				ctx = &auth.Context{}
+			}
+			v, err := ctx.Get(f.Arg(0))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintln(rn.output(), v)
+			return nil, nil
+		}
+	}
+	return nil
+}
+
+// configSetting is one effective setting and where it came from.
+type configSetting struct {
+	Setting string `json:"setting" yaml:"setting"`
+	Value   string `json:"value" yaml:"value"`
+	Source  string `json:"source" yaml:"source"`
+}
+
+type configSettings struct {
+	Items []configSetting `json:"items" yaml:"items"`
+}
+
+func (l *configSettings) Table() [][]string {
+	table := [][]string{
+		{"SETTING", "VALUE", "SOURCE"},
+	}
+	for _, x := range l.Items {
+		table = append(table, []string{x.Setting, x.Value, x.Source})
+	}
+	return table
+}
+
+// configShow merges every source a command in this directory would use: the
+// environment, the nearest .deploys.yaml, the active context, and the stored
+// login.
+func (rn Runner) configShow() (*configSettings, error) {
+	d, err := loadScopeDefaults()
+	if err != nil {
+		return nil, err
+	}
+	res := &configSettings{Items: []configSetting{}}
+	add := func(setting, value, source string) {
+		res.Items = append(res.Items, configSetting{Setting: setting, Value: value, Source: source})
+	}
+
+	switch {
+	case d.contextName == "":
+		add("context", "", "")
+	case os.Getenv("DEPLOYS_CONTEXT") != "":
+		add("context", d.contextName, "DEPLOYS_CONTEXT")
+	default:
+		add("context", d.contextName, "current context")
+	}
+	fromContext := func(key string) (string, string) {
+		if d.context == nil {
+			return "", ""
+		}
+		v, _ := d.context.Get(key)
+		if v == "" {
+			return "", ""
+		}
+		return v, fmt.Sprintf("context %q", d.contextName)
+	}
+
+	endpoint, src := os.Getenv("DEPLOYS_ENDPOINT"), "DEPLOYS_ENDPOINT"
+	if endpoint == "" {
+		endpoint, src = fromContext("endpoint")
+	}
+	if endpoint == "" {
+		endpoint, src = auth.NormalizeEndpoint(""), "default"
+	}
+	add("endpoint", endpoint, src)
+
+	account, src := rn.Account, "-account"
+	if account != "" && account == os.Getenv("DEPLOYS_ACCOUNT") {
+		src = "DEPLOYS_ACCOUNT"
+	}
+	if account == "" {
+		account, src = fromContext("account")
+	}
+	if account == "" {
+		if acct, err := auth.Lookup("", endpoint); err == nil && acct != nil {
+			account, src = acct.Email, "active login for the endpoint"
+		}
+	}
+	add("account", account, src)
+
+	for _, k := range []string{"project", "location", "name"} {
+		v, src := d.lookup("deployment", k)
+		if k == "name" {
+			k = "deployment"
+		}
+		add(k, v, src)
+	}
+	for _, k := range d.dir.flagKeys() {
+		add("flags: "+k, strings.Join(d.dir.Flags[k], " "), d.dir.Path)
+	}
+	return res, nil
+}
+
+// contextItem is one row of `context list`.
+type contextItem struct {
+	Name    string `json:"name" yaml:"name"`
+	Current bool   `json:"current" yaml:"current"`
+	auth.Context
+}
+
+type contextList struct {
+	Items []contextItem `json:"items" yaml:"items"`
+}
+
+func (l *contextList) Table() [][]string {
+	table := [][]string{
+		{"CURRENT", "NAME", "ENDPOINT", "ACCOUNT", "PROJECT", "LOCATION"},
+	}
+	for _, x := range l.Items {
+		cur := ""
+		if x.Current {
+			cur = "*"
+		}
+		table = append(table, []string{cur, x.Name, x.Endpoint, x.Account, x.Project, x.Location})
+	}
+	return table
+}
+
+// context handles `deploys context`: named bundles of endpoint, account,
+// project, and location.
+func (rn Runner) context(args ...string) error {
+	return rn.runGroup("context", args, rn.contextLeaf)
+}
+
+func (rn *Runner) contextLeaf(f *leafFlagSet, sub string) leafRun {
+	switch sub {
+	case "list":
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			c, err := auth.LoadContexts()
+			if err != nil {
+				return nil, err
+			}
+			active, _, _ := auth.ActiveContext()
+			res := contextList{Items: []contextItem{}}
+			for _, n := range c.Names() {
+				res.Items = append(res.Items, contextItem{Name: n, Current: n == active, Context: *c.Contexts[n]})
+			}
+			return nil, rn.print(&res)
+		}
+	case "current":
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			name, _, err := auth.ActiveContext()
+			if err != nil {
+				return nil, err
+			}
+			if name == "" {
+				return nil, fmt.Errorf("no active context (create one with 'deploys context create' or 'deploys config set')")
+			}
+			fmt.Fprintln(rn.output(), name)
+			return nil, nil
+		}
+	case "create", "update":
+		var next auth.Context
+		f.StringVar(&next.Endpoint, "endpoint", "", "api endpoint")
+		// -account is the global account selector (taken before dispatch)
+		f.StringVar(&next.Account, "email", "", "stored account (email) the context logs in as")
+		f.StringVar(&next.Project, "project", "", "default project id")
+		f.StringVar(&next.Location, "location", "", "default location")
+		return func(args []string) (any, error) {
+			name, rest := leadingName(args)
+			f.Parse(rest)
+			if name == "" {
+				name = f.Arg(0)
+			}
+			if name == "" {
+				return nil, fmt.Errorf("deploys context %s: name required", sub)
+			}
+			set := visitedFlags(f.FlagSet)
+			return nil, auth.MutateContexts(func(c *auth.Contexts) error {
+				cur, exists := c.Contexts[name]
+				switch {
+				case sub == "create" && exists:
+					return fmt.Errorf("context %q already exists (use 'deploys context update')", name)
+				case sub == "update" && !exists:
+					return fmt.Errorf("context %q not found", name)
+				case !exists:
+					cur = &auth.Context{}
+					c.Contexts[name] = cur
+				}
+				set["account"] = set["email"]
+				for _, k := range auth.ContextKeys {
+					if set[k] {
+						v, _ := next.Get(k)
+						cur.Set(k, v)
+					}
+				}
+				return nil
+			})
+		}
+	case "use":
+		return func(args []string) (any, error) {
+			name, rest := leadingName(args)
+			f.Parse(rest)
+			if name == "" {
+				name = f.Arg(0)
+			}
+			if name == "" {
+				return nil, fmt.Errorf("deploys context use: name required")
+			}
+			err := auth.MutateContexts(func(c *auth.Contexts) error {
+				if _, ok := c.Contexts[name]; !ok {
+					return fmt.Errorf("context %q not found (create it with 'deploys context create %s')", name, name)
+				}
+				c.Current = name
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(rn.output(), "switched to context %q\n", name)
+			if env := os.Getenv("DEPLOYS_CONTEXT"); env != "" && env != name {
+				fmt.Fprintf(os.Stderr, "note: DEPLOYS_CONTEXT=%s still overrides it in this shell\n", env)
+			}
+			return nil, nil
+		}
+	case "delete":
+		return func(args []string) (any, error) {
+			name, rest := leadingName(args)
+			f.Parse(rest)
+			if name == "" {
+				name = f.Arg(0)
+			}
+			if name == "" {
+				return nil, fmt.Errorf("deploys context delete: name required")
+			}
+			return nil, auth.MutateContexts(func(c *auth.Contexts) error {
+				if _, ok := c.Contexts[name]; !ok {
+					return fmt.Errorf("context %q not found", name)
+				}
+				delete(c.Contexts, name)
+				if c.Current == name {
+					c.Current = ""
+				}
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+// leadingName splits a positional name given before the flags off args.
+func leadingName(args []string) (string, []string) {
+	if len(args) > 0 && !isFlag(args[0]) {
+		return args[0], args[1:]
+	}
+	return "", args
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+func TestConfigSetCreatesDefaultContext(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Setenv("DEPLOYS_CONTEXT", "")
+	rn := Runner{Output: tempOut(t)}
+
+	if err := rn.Run("config", "set", "project", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("config", "set", "location", "loc"); err != nil {
+		t.Fatal(err)
+	}
+	name, c, err := auth.ActiveContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != auth.DefaultContext || c.Project != "acme" || c.Location != "loc" {
+		t.Errorf("active = %q %+v", name, c)
+	}
+
+	if err := rn.Run("context", "create", "staging", "-project", "acme-stg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("context", "use", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("config", "unset", "project"); err != nil {
+		t.Fatal(err)
+	}
+	name, c, _ = auth.ActiveContext()
+	if name != "staging" || c.Project != "" {
+		t.Errorf("after unset: %q %+v", name, c)
+	}
+}
+
+func TestLeafFlagSetFillsScope(t *testing.T) {
+	t.Setenv("DEPLOYS_LOCATION", "")
+	t.Setenv("DEPLOYS_PROJECT", "")
+	rn := Runner{Output: tempOut(t)}
+	rn.scope = &scopeDefaults{contextName: "prod", context: &auth.Context{Project: "acme", Location: "loc"}}
+
+	f := rn.subFlagSet("deployment", "list")
+	var project, location string
+	f.StringVar(&project, "project", "", "")
+	f.StringVar(&location, "location", "", "")
+	f.Parse([]string{"-location", "other"})
+	if project != "acme" || location != "other" {
+		t.Errorf("project, location = %q, %q; want acme (context), other (flag)", project, location)
+	}
+
+	// the environment beats the context
+	t.Setenv("DEPLOYS_PROJECT", "env-project")
+	f = rn.subFlagSet("deployment", "list")
+	f.StringVar(&project, "project", "", "")
+	f.Parse(nil)
+	if project != "env-project" {
+		t.Errorf("project = %q; want env-project", project)
+	}
+
+	// project create names a new project; it is never defaulted
+	t.Setenv("DEPLOYS_PROJECT", "")
+	project = ""
+	f = rn.subFlagSet("project", "create")
+	f.StringVar(&project, "project", "", "")
+	f.Parse(nil)
+	if project != "" {
+		t.Errorf("project create filled -project with %q", project)
+	}
+}
+
+func TestLeafFlagSetOverride(t *testing.T) {
+	t.Setenv("DEPLOYS_PROJECT", "")
+	rn := Runner{Output: tempOut(t)}
+	rn.scope = &scopeDefaults{contextName: "prod", context: &auth.Context{Project: "acme"}}
+
+	// a context default only fills what the spec file leaves out
+	f := rn.subFlagSet("waf", "set")
+	var project string
+	f.StringVar(&project, "project", "", "")
+	f.Parse(nil)
+	fromFile := "file-project"
+	f.override("project", &fromFile, project)
+	if fromFile != "file-project" {
+		t.Errorf("context default overrode the file: %q", fromFile)
+	}
+	empty := ""
+	f.override("project", &empty, project)
+	if empty != "acme" {
+		t.Errorf("empty file value = %q; want acme", empty)
+	}
+
+	// an explicit flag always wins
+	f = rn.subFlagSet("waf", "set")
+	f.StringVar(&project, "project", "", "")
+	f.Parse([]string{"-project", "flag-project"})
+	f.override("project", &fromFile, project)
+	if fromFile != "flag-project" {
+		t.Errorf("explicit flag lost to the file: %q", fromFile)
+	}
+}
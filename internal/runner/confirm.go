@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+// protectedFile lists, per project, resource names that destructive commands
+// never touch without -force (on top of the usual confirmation):
+//
+//	acme:
+//	  - acme       # the project itself
+//	  - web        # a deployment, disk, or registry repository
+const protectedFile = "protected.yaml"
+
+func protectedPath() (string, error) {
+	d, err := auth.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, protectedFile), nil
+}
+
+// loadProtected reads the protected-names file; a missing file protects
+// nothing.
+func loadProtected() (map[string][]string, error) {
+	fn, err := protectedPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m map[string][]string
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fn, err)
+	}
+	return m, nil
+}
+
+// confirmDestructive gates a leaf the registry marks destructive. A protected
+// name needs -force, which only lifts the protection: the name is still
+// confirmed. Then -yes proceeds; otherwise a terminal must type the name back,
+// and without a terminal the command refuses.
+func (rn Runner) confirmDestructive(group, sub, project, name string, yes, force bool) error {
+	return confirmDestructive(os.Stdin, os.Stderr, isTTY(os.Stdin), group, sub, project, name, yes, force)
+}
+
+func confirmDestructive(in io.Reader, out io.Writer, tty bool, group, sub, project, name string, yes, force bool) error {
+	entry := lookupCommand(group).lookupSub(sub)
+	if entry == nil || !entry.destructive {
+		return nil
+	}
+	action := "deploys " + group + " " + sub
+
+	protected, err := loadProtected()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(protected[project], name) && !force {
+		fn, _ := protectedPath()
+		return fmt.Errorf("%s: %q is protected in project %q (listed in %s); use -force to proceed", action, name, project, fn)
+	}
+
+	if yes {
+		return nil
+	}
+	if !tty {
+		return fmt.Errorf("%s: refusing to run without -yes (no interactive terminal)", action)
+	}
+	fmt.Fprintf(out, "%s is destructive: %s.\nType %q to confirm: ", action, entry.short, name)
+	s := bufio.NewScanner(in)
+	if !s.Scan() || strings.TrimSpace(s.Text()) != name {
+		return fmt.Errorf("%s: aborted (typed name did not match %q)", action, name)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfirmDestructive(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DEPLOYS_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, protectedFile), []byte("acme:\n  - web\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		group, sub string
+		resource   string
+		input      string
+		tty        bool
+		yes, force bool
+		ok         bool
+	}{
+		{name: "typed name", group: "disk", sub: "delete", resource: "data", input: "data\n", tty: true, ok: true},
+		{name: "wrong name", group: "disk", sub: "delete", resource: "data", input: "y\n", tty: true},
+		{name: "no tty", group: "disk", sub: "delete", resource: "data"},
+		{name: "no tty -yes", group: "disk", sub: "delete", resource: "data", yes: true, ok: true},
+		{name: "protected -yes", group: "deployment", sub: "delete", resource: "web", yes: true},
+		{name: "protected typed", group: "deployment", sub: "delete", resource: "web", input: "web\n", tty: true},
+		{name: "protected -force -yes", group: "deployment", sub: "delete", resource: "web", yes: true, force: true, ok: true},
+		{name: "protected -force typed", group: "deployment", sub: "delete", resource: "web", input: "web\n", tty: true, force: true, ok: true},
+		{name: "protected -force no tty", group: "deployment", sub: "delete", resource: "web", force: true},
+		{name: "not destructive", group: "route", sub: "delete", resource: "web", ok: true},
+	}
+	for _, c := range cases {
+		err := confirmDestructive(strings.NewReader(c.input), io.Discard, c.tty, c.group, c.sub, "acme", c.resource, c.yes, c.force)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v; want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
+
+// A destructive leaf rejects an incomplete request before it asks for the name,
+// so an empty name is never "confirmed" by pressing enter.
+func TestConfirmAfterValid(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	rn := Runner{API: offlineAPI(), Output: tempOut(t)}
+	for _, args := range [][]string{
+		{"project", "delete"},
+		{"registry", "delete", "-project", "acme"},
+		{"registry", "gc"},
+	} {
+		err := rn.Run(args...)
+		if err == nil || !strings.Contains(err.Error(), "required") {
+			t.Errorf("%q: err = %v; want a required-field error", args, err)
+		}
+	}
+}
+
+// Every leaf the registry marks destructive names itself in its -h banner.
+func TestDestructiveBanner(t *testing.T) {
+	for _, c := range commands {
+		for _, s := range c.subs {
+			if !s.destructive {
+				continue
+			}
+			var b strings.Builder
+			rn := Runner{}
+			writeSubUsage(&b, rn.subFlagSet(c.name, s.name), c.name, s.name)
+			if !strings.Contains(b.String(), "Destructive:") {
+				t.Errorf("%s %s banner does not mention confirmation:\n%s", c.name, s.name, b.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/deploys-app/api"
+
+	"github.com/deploys-app/deploys/internal/devserver"
+)
+
+func (rn Runner) devServer(args ...string) error {
+	_, run := rn.devServerLeaf()
+	return run(args)
+}
+
+func (rn *Runner) devServerLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("dev-server", writeDevServerUsage)
+	var listen, statePath, seed string
+	f.StringVar(&listen, "listen", "127.0.0.1:8080", "address to serve the api on")
+	f.StringVar(&statePath, "state", "", "json file to keep the state in: read at start when it exists, written after every change")
+	f.StringVar(&seed, "seed", "", "json state file to start from (when -state does not exist yet)")
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeDevServerUsage(rn.output())
+			return nil
+		}
+		f.Parse(args)
+
+		var st *devserver.State
+		if seed != "" {
+			var err error
+			st, err = devserver.LoadState(seed)
+			if err != nil {
+				return err
+			}
+		}
+		var a *devserver.API
+		if statePath != "" {
+			var err error
+			a, err = devserver.Open(statePath, st)
+			if err != nil {
+				return err
+			}
+		} else {
+			a = devserver.New(st)
+		}
+
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "deploys dev-server: serving the api on http://%s/\n", ln.Addr())
+		fmt.Fprintf(os.Stderr, "point the cli at it with: export DEPLOYS_ENDPOINT=http://%s/ DEPLOYS_TOKEN=dev\n", ln.Addr())
+		return http.Serve(ln, devHandler(a, os.Stderr))
+	}
+}
+
+// devHandler serves a over the api's wire format: a POST to /<rpc> with the
+// json request, answered {"ok":true,"result":...} or {"ok":false,"error":
+// {"message":...}}. Each call is logged to log.
+func devHandler(a api.Interface, log io.Writer) http.Handler {
+	methods := map[string]apiMethod{}
+	for _, m := range apiMethods() {
+		methods[m.Name] = m
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		m, ok := methods[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			body = []byte("{}")
+		}
+
+		var res any
+		if devServes(a, m.Service) {
+			res, err = m.call(r.Context(), a, body)
+		} else {
+			err = devserver.ErrNotImplemented
+		}
+		if errors.Is(err, devserver.ErrNotImplemented) {
+			err = fmt.Errorf("dev-server: %s is not implemented", name)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err != nil {
+			fmt.Fprintf(log, "%s: %v\n", name, err)
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": map[string]string{"message": err.Error()}})
+			return
+		}
+		fmt.Fprintln(log, name)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": res})
+	})
+}
+
+// devServes reports whether a has the named service; the dev server leaves
+// the ones it does not model nil.
+func devServes(a api.Interface, service string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return !reflect.ValueOf(a).MethodByName(service).Call(nil)[0].IsNil()
+}
+
+func writeDevServerUsage(w io.Writer) {
+	fmt.Fprint(w, "dev-server — serve an in-memory api for testing scripts offline\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys dev-server [-listen 127.0.0.1:8080] [-state file.json] [-seed file.json]\n")
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+
+	"github.com/deploys-app/deploys/internal/devserver"
+)
+
+func TestDevServer(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Setenv("DEPLOYS_TOKEN", "dev")
+	var log bytes.Buffer
+	srv := httptest.NewServer(devHandler(devserver.New(nil), &log))
+	defer srv.Close()
+	rn := Runner{Output: tempOut(t), API: &client.Client{Endpoint: srv.URL + "/"}}
+
+	for _, args := range [][]string{
+		{"envgroup", "create", "-project", "dev-project", "-name", "shared", "-env", "A=1"},
+		{"deployment", "deploy", "-project", "dev-project", "-location", "gke.cluster-rcf2", "-name", "web", "-image", "nginx:1", "-envGroups", "shared"},
+		{"deployment", "deploy", "-project", "dev-project", "-location", "gke.cluster-rcf2", "-name", "web", "-image", "nginx:2"},
+	} {
+		if err := rn.Run(args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	// the journal's undo goes through the same server
+	if err := rn.Run("undo"); err != nil {
+		t.Fatal(err)
+	}
+	out := tempOut(t)
+	rn.Output = out
+	if err := rn.Run("deployment", "get", "-project", "dev-project", "-location", "gke.cluster-rcf2", "-name", "web", "-ojson"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readOut(t, out); !strings.Contains(got, `"revision": 3`) || !strings.Contains(got, `"image": "nginx:1"`) {
+		t.Errorf("after undo:\n%s", got)
+	}
+
+	// errors come back as the api's own
+	err := rn.Run("envgroup", "delete", "-project", "dev-project", "-name", "shared", "-force")
+	if !errors.Is(err, api.ErrEnvGroupInUse) {
+		t.Errorf("envgroup delete: %v", err)
+	}
+	err = rn.Run("waf", "get", "-project", "dev-project", "-location", "gke.cluster-rcf2")
+	if err == nil || err.Error() != "dev-server: waf.get is not implemented" {
+		t.Errorf("waf get: %v", err)
+	}
+	if !strings.Contains(log.String(), "deployment.rollback\n") || !strings.Contains(log.String(), "envgroup.delete: api: env group in use\n") {
+		t.Errorf("log:\n%s", log.String())
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/deployment.nope", http.StatusNotFound},
+		{http.MethodGet, "/deployment.list", http.StatusMethodNotAllowed},
+	} {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s %s: %d; want %d", tc.method, tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// dirConfigFile is the per-directory settings file, found by walking up from
+// the working directory:
+//
+//	project: acme
+//	location: gke.cluster-rcf2
+//	deployment: web          # -name of deployment commands
+//	flags:
+//	  site publish: -dir dist -spa
+//	  deployment logs: [-tail, "100"]
+const dirConfigFile = ".deploys.yaml"
+
+// dirConfig is a parsed .deploys.yaml. Flags is keyed by canonical
+// "<group> <sub>" command names.
+type dirConfig struct {
+	Path       string              `yaml:"-"`
+	Project    string              `yaml:"project"`
+	Location   string              `yaml:"location"`
+	Deployment string              `yaml:"deployment"`
+	Flags      map[string]flagList `yaml:"flags"`
+}
+
+// flagList is a command's default flags, written either as one string split
+// on spaces or as a list (for values that contain spaces).
+type flagList []string
+
+func (l *flagList) UnmarshalYAML(unmarshal func(any) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*l = list
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return fmt.Errorf("flags must be a string or a list of strings")
+	}
+	*l = strings.Fields(s)
+	return nil
+}
+
+// findDirConfig walks up from dir to the filesystem root and returns the
+// first .deploys.yaml, or "" when there is none.
+func findDirConfig(dir string) (string, error) {
+	for {
+		fn := filepath.Join(dir, dirConfigFile)
+		fi, err := os.Stat(fn)
+		if err == nil && !fi.IsDir() {
+			return fn, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadDirConfig reads the .deploys.yaml that governs the working directory;
+// none is (nil, nil). Unknown keys and commands are errors, so a typo never
+// silently does nothing.
+func loadDirConfig() (*dirConfig, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	fn, err := findDirConfig(wd)
+	if err != nil || fn == "" {
+		return nil, err
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var c dirConfig
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fn, err)
+	}
+	c.Path = fn
+	flags := make(map[string]flagList, len(c.Flags))
+	for k, v := range c.Flags {
+		key, err := canonicalCommand(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s: flags: %w", fn, err)
+		}
+		flags[key] = v
+	}
+	c.Flags = flags
+	return &c, nil
+}
+
+// canonicalCommand resolves "<group> <sub>" (aliases allowed) to the
+// registry's names.
+func canonicalCommand(s string) (string, error) {
+	group, sub, _ := strings.Cut(strings.Join(strings.Fields(s), " "), " ")
+	c := lookupCommand(group)
+	if c == nil {
+		return "", fmt.Errorf("unknown command %q", s)
+	}
+	e := c.lookupSub(sub)
+	if e == nil {
+		return "", fmt.Errorf("unknown command %q", s)
+	}
+	return c.name + " " + e.name, nil
+}
+
+// flagKeys returns the commands with default flags, sorted.
+func (c *dirConfig) flagKeys() []string {
+	if c == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(c.Flags))
+	for k := range c.Flags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withDefaultFlags prepends the default flags that args does not set itself,
+// so an explicit flag always wins (a repeatable flag is replaced by, not merged
+// with, what the command line gives). A default flag's values are the tokens
+// up to its next flag.
+func withDefaultFlags(defaults, args []string) []string {
+	if len(defaults) == 0 {
+		return args
+	}
+	given := map[string]bool{}
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if n, ok := flagName(a); ok {
+			given[n] = true
+		}
+	}
+	var (
+		res  []string
+		skip bool
+	)
+	for _, d := range defaults {
+		if n, ok := flagName(d); ok {
+			skip = given[n]
+		}
+		if !skip {
+			res = append(res, d)
+		}
+	}
+	return append(res, args...)
+}
+
+// flagName returns the name of a -flag, --flag, or -flag=value token.
+func flagName(s string) (string, bool) {
+	if !isFlag(s) {
+		return "", false
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "-")
+	name, _, _ := strings.Cut(s, "=")
+	return name, name != ""
+}
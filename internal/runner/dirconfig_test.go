@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithDefaultFlags(t *testing.T) {
+	defaults := []string{"-dir", "dist", "-spa", "-env", "A=1", "-env", "B=2"}
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{nil, defaults},
+		// an explicit flag replaces its default, whatever its form
+		{[]string{"--dir=build"}, []string{"-spa", "-env", "A=1", "-env", "B=2", "--dir=build"}},
+		// a repeatable flag is replaced, not merged
+		{[]string{"-env", "C=3"}, []string{"-dir", "dist", "-spa", "-env", "C=3"}},
+		// flags after -- are positional
+		{[]string{"--", "-spa"}, append(append([]string{}, defaults...), "--", "-spa")},
+	}
+	for _, tc := range cases {
+		if got := withDefaultFlags(defaults, tc.args); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("withDefaultFlags(%q) = %q; want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestLoadDirConfig(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	if c, err := loadDirConfig(); err != nil || c != nil {
+		t.Fatalf("no file: %v, %v", c, err)
+	}
+
+	fn := filepath.Join(root, dirConfigFile)
+	os.WriteFile(fn, []byte("project: acme\ndeployment: web\nflags:\n  site publish: -dir dist -spa\n  deploy logs: [-tail, \"100\"]\n"), 0o644)
+	c, err := loadDirConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != fn || c.Project != "acme" || c.Deployment != "web" {
+		t.Errorf("config = %+v", c)
+	}
+	// command keys are canonical, aliases included
+	want := map[string]flagList{
+		"site publish":    {"-dir", "dist", "-spa"},
+		"deployment logs": {"-tail", "100"},
+	}
+	if !reflect.DeepEqual(c.Flags, want) {
+		t.Errorf("flags = %q; want %q", c.Flags, want)
+	}
+
+	os.WriteFile(fn, []byte("flags:\n  site pubish: -spa\n"), 0o644)
+	if _, err := loadDirConfig(); err == nil || !strings.Contains(err.Error(), `unknown command "site pubish"`) {
+		t.Errorf("unknown command err = %v", err)
+	}
+	os.WriteFile(fn, []byte("projcet: acme\n"), 0o644)
+	if _, err := loadDirConfig(); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
+
+func TestLeafFlagSetDirDefaults(t *testing.T) {
+	t.Setenv("DEPLOYS_PROJECT", "")
+	t.Setenv("DEPLOYS_LOCATION", "")
+	rn := Runner{Output: tempOut(t)}
+	rn.scope = &scopeDefaults{
+		dir: &dirConfig{
+			Path:       "/repo/.deploys.yaml",
+			Project:    "acme",
+			Deployment: "web",
+			Flags:      map[string]flagList{"deployment logs": {"-tail", "50"}},
+		},
+	}
+
+	f := rn.subFlagSet("deployment", "logs")
+	var project, name string
+	var tail int
+	f.StringVar(&project, "project", "", "")
+	f.StringVar(&name, "name", "", "")
+	f.IntVar(&tail, "tail", 0, "")
+	f.Parse(nil)
+	if project != "acme" || name != "web" || tail != 50 {
+		t.Errorf("project, name, tail = %q, %q, %d", project, name, tail)
+	}
+
+	// explicit flags win over the file
+	f = rn.subFlagSet("deployment", "logs")
+	f.StringVar(&name, "name", "", "")
+	f.IntVar(&tail, "tail", 0, "")
+	f.Parse([]string{"-name", "api", "-tail", "5"})
+	if name != "api" || tail != 5 {
+		t.Errorf("name, tail = %q, %d; want api, 5", name, tail)
+	}
+
+	// the deployment name only defaults deployment commands
+	name = ""
+	f = rn.subFlagSet("site", "publish")
+	f.StringVar(&name, "name", "", "")
+	f.Parse(nil)
+	if name != "" {
+		t.Errorf("site publish -name = %q; want empty", name)
+	}
+}
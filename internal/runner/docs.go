@@ -0,0 +1,299 @@
+package runner
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// docPage is one page of the generated reference: the top-level command, a
+// group, or a leaf (a subcommand or a standalone command).
+type docPage struct {
+	words       []string // after "deploys"; none for the top-level page
+	short       string
+	args        string
+	aliases     []string
+	destructive bool
+	flags       *flag.FlagSet
+	children    []*docPage
+	parent      *docPage
+}
+
+// title is the page's command line, e.g. "deploys deployment deploy".
+func (p *docPage) title() string {
+	return strings.Join(append([]string{"deploys"}, p.words...), " ")
+}
+
+// file is the page's file name without its extension.
+func (p *docPage) file() string {
+	return strings.ReplaceAll(p.title(), " ", "-")
+}
+
+func (p *docPage) usage() string {
+	u := p.title()
+	switch {
+	case p.parent == nil:
+		u += " <command> <subcommand> [flags]"
+	case len(p.children) > 0:
+		u += " <subcommand> [flags]"
+	default:
+		if p.args != "" {
+			u += " " + p.args
+		}
+		u += " [flags]"
+	}
+	return u
+}
+
+// docPages builds the reference from the registry and the commands' real flag
+// sets (see leafFlags): the top-level page first, then each group followed by
+// its leaves, then the standalone commands. A group's listing of a nested
+// leaf (deployment's "set", for "set image") gets no page of its own.
+func docPages() []*docPage {
+	top := &docPage{short: "the deploys.app cli"}
+	pages := []*docPage{top}
+	add := func(parent, p *docPage) {
+		p.parent = parent
+		parent.children = append(parent.children, p)
+		pages = append(pages, p)
+	}
+
+	auth := lookupCommand("auth")
+	for _, name := range []string{"login", "logout"} {
+		e := auth.lookupSub(name)
+		add(top, &docPage{
+			words: []string{name},
+			short: e.short + " (alias of auth " + name + ")",
+			args:  e.args,
+			flags: leafFlags(name, ""),
+		})
+	}
+	for _, c := range commands {
+		g := &docPage{words: []string{c.name}, short: c.short, aliases: c.aliases}
+		add(top, g)
+		for _, s := range c.subs {
+			if isListing(&c, s.name) {
+				continue
+			}
+			add(g, &docPage{
+				words:       append([]string{c.name}, strings.Fields(s.name)...),
+				short:       s.short,
+				args:        s.args,
+				aliases:     s.aliases,
+				destructive: s.destructive,
+				flags:       leafFlags(c.name, s.name),
+			})
+		}
+	}
+	for _, s := range standaloneCommands {
+		add(top, &docPage{words: []string{s.name}, short: s.short, args: s.args, flags: leafFlags(s.name, "")})
+	}
+	return pages
+}
+
+// isListing reports whether sub only lists nested leaves ("set" for "set
+// image").
+func isListing(c *command, sub string) bool {
+	for _, s := range c.subs {
+		if strings.HasPrefix(s.name, sub+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// docFlag is a flag as the pages show it.
+type docFlag struct {
+	name, typ, def, usage string
+}
+
+func docFlags(f *flag.FlagSet) []docFlag {
+	if f == nil {
+		return nil
+	}
+	var res []docFlag
+	f.VisitAll(func(fl *flag.Flag) {
+		typ, usage := flag.UnquoteUsage(fl)
+		def := fl.DefValue
+		switch def {
+		case "", "0", "false", "0s", "[]":
+			def = ""
+		}
+		res = append(res, docFlag{name: "-" + fl.Name, typ: typ, def: def, usage: usage})
+	})
+	return res
+}
+
+const destructiveNote = "Destructive: on a terminal, type the resource name to confirm; otherwise pass -yes. Names protected in the config dir's protected.yaml also need -force."
+
+// writeMarkdownPage renders p as GitHub-flavored Markdown; pages link to each
+// other by relative file name.
+func writeMarkdownPage(w io.Writer, p *docPage) {
+	fmt.Fprintf(w, "# %s\n\n%s\n\n", p.title(), mdEscape(p.short))
+	fmt.Fprintf(w, "```\n%s\n```\n\n", p.usage())
+	if len(p.aliases) > 0 {
+		fmt.Fprintf(w, "Aliases: %s\n\n", strings.Join(p.aliases, ", "))
+	}
+	if p.destructive {
+		fmt.Fprintf(w, "> %s\n\n", destructiveNote)
+	}
+	if len(p.children) > 0 {
+		fmt.Fprint(w, "## Commands\n\n| Command | Description |\n| --- | --- |\n")
+		for _, c := range p.children {
+			fmt.Fprintf(w, "| [%s](%s.md) | %s |\n", strings.Join(c.words[len(p.words):], " "), c.file(), mdCell(c.short))
+		}
+		fmt.Fprintln(w)
+	}
+	if flags := docFlags(p.flags); len(flags) > 0 {
+		fmt.Fprint(w, "## Flags\n\n| Flag | Default | Description |\n| --- | --- | --- |\n")
+		for _, f := range flags {
+			name := "`" + f.name
+			if f.typ != "" {
+				name += " " + f.typ
+			}
+			name += "`"
+			def := ""
+			if f.def != "" {
+				def = "`" + mdCell(f.def) + "`"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s |\n", name, def, mdCell(f.usage))
+		}
+		fmt.Fprintln(w)
+	}
+	if p.parent != nil {
+		fmt.Fprintf(w, "See also: [%s](%s.md)\n", p.parent.title(), p.parent.file())
+	}
+}
+
+// mdEscape keeps text from being read as Markdown markup.
+func mdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "<", `\<`, ">", `\>`, "[", `\[`, "]", `\]`, "`", "\\`").Replace(s)
+}
+
+// mdCell escapes text for a table cell, where a pipe ends the cell.
+func mdCell(s string) string {
+	return strings.ReplaceAll(mdEscape(strings.ReplaceAll(s, "\n", " ")), "|", `\|`)
+}
+
+// writeManPage renders p as a man(7) page in section 1.
+func writeManPage(w io.Writer, p *docPage, version string) {
+	fmt.Fprintf(w, ".TH %q 1 \"\" %q \"deploys manual\"\n", strings.ToUpper(p.file()), "deploys "+version)
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", roff(p.file()), roff(p.short))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n", roff(p.usage()))
+	if len(p.aliases) > 0 || p.destructive {
+		fmt.Fprint(w, ".SH DESCRIPTION\n")
+		if len(p.aliases) > 0 {
+			fmt.Fprintf(w, "Aliases: %s\n", roff(strings.Join(p.aliases, ", ")))
+		}
+		if p.destructive {
+			fmt.Fprintf(w, ".PP\n%s\n", roff(destructiveNote))
+		}
+	}
+	if len(p.children) > 0 {
+		fmt.Fprint(w, ".SH COMMANDS\n")
+		for _, c := range p.children {
+			fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roff(strings.Join(c.words[len(p.words):], " ")), roff(c.short))
+		}
+	}
+	if flags := docFlags(p.flags); len(flags) > 0 {
+		fmt.Fprint(w, ".SH OPTIONS\n")
+		for _, f := range flags {
+			fmt.Fprintf(w, ".TP\n\\fB%s\\fR", roff(f.name))
+			if f.typ != "" {
+				fmt.Fprintf(w, " \\fI%s\\fR", roff(f.typ))
+			}
+			fmt.Fprintf(w, "\n%s", roff(f.usage))
+			if f.def != "" {
+				fmt.Fprintf(w, " (default %s)", roff(f.def))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	var see []string
+	if p.parent != nil {
+		see = append(see, p.parent.file())
+	}
+	for _, c := range p.children {
+		see = append(see, c.file())
+	}
+	if len(see) > 0 {
+		fmt.Fprint(w, ".SH SEE ALSO\n")
+		for i, s := range see {
+			sep := ","
+			if i == len(see)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(w, ".BR %s (1)%s\n", roff(s), sep)
+		}
+	}
+}
+
+// roff escapes text for a man page: backslashes and hyphens, and a leading
+// control character that would start a request.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`, "\n", " ").Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// docs handles `deploys docs`: write the command reference, one page per
+// group and command, as man pages or Markdown.
+func (rn Runner) docs(args ...string) error {
+	_, run := rn.docsLeaf()
+	return run(args)
+}
+
+func (rn *Runner) docsLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("docs", writeDocsUsage)
+	var format, out string
+	f.StringVar(&format, "format", "markdown", "page format: man or markdown")
+	f.StringVar(&out, "out", "", "directory to write the pages to (created if missing)")
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeDocsUsage(rn.output())
+			return nil
+		}
+		f.Parse(args)
+
+		if out == "" {
+			return fmt.Errorf("docs: output directory required (-out)")
+		}
+		var (
+			ext   string
+			write func(io.Writer, *docPage)
+		)
+		switch format {
+		case "markdown", "md":
+			ext, write = ".md", writeMarkdownPage
+		case "man":
+			v := displayVersion(rn.Version)
+			ext, write = ".1", func(w io.Writer, p *docPage) { writeManPage(w, p, v) }
+		default:
+			return fmt.Errorf("docs: unknown format %q (want man or markdown)", format)
+		}
+
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return err
+		}
+		pages := docPages()
+		for _, p := range pages {
+			var b strings.Builder
+			write(&b, p)
+			if err := os.WriteFile(filepath.Join(out, p.file()+ext), []byte(b.String()), 0o644); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(rn.output(), "wrote %d pages to %s\n", len(pages), out)
+		return nil
+	}
+}
+
+func writeDocsUsage(w io.Writer) {
+	fmt.Fprint(w, "docs — write the command reference as man pages or Markdown\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys docs -out <dir> [-format man|markdown]\n")
+}
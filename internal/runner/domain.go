@@ -1,64 +1,109 @@
 package runner
 
 import (
-	"context"
+	"os"
+	"time"
 
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) domain(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("domain")
-	}
+	return rn.runGroup("domain", args, rn.domainLeaf)
+}
 
+func (rn *Runner) domainLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Domain()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("domain", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("domain", args[0])
+	switch sub {
 	case "create":
-		var req api.DomainCreate
+		var (
+			req      api.DomainCreate
+			wait     bool
+			resolver string
+			interval time.Duration
+			timeout  time.Duration
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Domain, "domain", "", "domain")
 		f.BoolVar(&req.Wildcard, "wildcard", false, "wildcard domain")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.BoolVar(&wait, "wait", false, "print the required DNS records and wait until the domain is active")
+		f.StringVar(&resolver, "resolver", "", "with -wait, DNS server (host[:port]) to check records against (default: system resolver)")
+		f.DurationVar(&interval, "interval", 10*time.Second, "with -wait, poll interval")
+		f.DurationVar(&timeout, "wait-timeout", 30*time.Minute, "with -wait, give up after this long (0 waits forever)")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("domain create", journalTarget{Project: req.Project, Location: req.Location, Name: req.Domain}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			if err != nil || !wait {
+				return resp, err
+			}
+			// progress goes to stderr so stdout carries only the final domain
+			return waitDomain(rn.ctx(), s, newDNSResolver(resolver),
+				&api.DomainGet{Project: req.Project, Domain: req.Domain}, interval, timeout, os.Stderr)
+		}
+	case "check":
+		var (
+			req      api.DomainGet
+			resolver string
+		)
+		f.StringVar(&req.Project, "project", "", "project id")
+		f.StringVar(&req.Domain, "domain", "", "domain")
+		f.StringVar(&resolver, "resolver", "", "DNS server (host[:port]) to check records against (default: system resolver)")
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			item, err := s.Get(rn.ctx(), &req)
+			if err != nil {
+				return nil, err
+			}
+			return diagnoseDomain(rn.ctx(), newDNSResolver(resolver), item), nil
+		}
 	case "get":
 		var req api.DomainGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Domain, "domain", "", "domain")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.DomainList
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "delete":
 		var req api.DomainDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Domain, "domain", "", "domain")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("domain delete", journalTarget{Project: req.Project, Name: req.Domain}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "purgecache":
 		var req api.DomainPurgeCache
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Domain, "domain", "", "domain")
 		f.StringVar(&req.File, "file", "", "purge a single file path")
 		f.StringVar(&req.Prefix, "prefix", "", "purge all files under a path prefix")
-		f.Parse(args[1:])
-		resp, err = s.PurgeCache(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("domain purgecache", journalTarget{Project: req.Project, Name: req.Domain}, &req)
+			resp, err = s.PurgeCache(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
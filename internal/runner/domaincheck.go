@@ -0,0 +1,296 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+// dnsResolver is the subset of *net.Resolver the domain diagnosis uses, so
+// tests can substitute a fake.
+type dnsResolver interface {
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// newDNSResolver returns the system resolver, or one that sends every query to
+// addr (host or host:port, port 53 by default) — e.g. a public resolver to
+// bypass a stale local cache, or a local DNS stub.
+func newDNSResolver(addr string) dnsResolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// dnsRecord is one record the domain needs. Purpose groups alternatives: a
+// purpose is satisfied when every record of any one of its types matches
+// (routing by CNAME or by A records; ssl by the DCV CNAME or all TXT records).
+type dnsRecord struct {
+	Purpose string `json:"purpose" yaml:"purpose"` // routing|ownership|ssl
+	Type    string `json:"type" yaml:"type"`
+	Name    string `json:"name" yaml:"name"`
+	Value   string `json:"value" yaml:"value"`
+}
+
+// domainRecords lists the records the api asks for, from a domain's dnsConfig
+// and verification. A wildcard domain is routed as "*.<domain>".
+func domainRecords(item *api.DomainItem) []dnsRecord {
+	var rs []dnsRecord
+	name := item.Domain
+	if item.Wildcard {
+		name = "*." + name
+	}
+	for _, v := range item.DNSConfig.CName {
+		rs = append(rs, dnsRecord{Purpose: "routing", Type: "CNAME", Name: name, Value: v})
+	}
+	for _, v := range item.DNSConfig.IPv4 {
+		rs = append(rs, dnsRecord{Purpose: "routing", Type: "A", Name: name, Value: v})
+	}
+	for _, v := range item.DNSConfig.IPv6 {
+		rs = append(rs, dnsRecord{Purpose: "routing", Type: "AAAA", Name: name, Value: v})
+	}
+	if o := item.Verification.Ownership; o.Name != "" {
+		typ := strings.ToUpper(o.Type)
+		if typ == "" {
+			typ = "TXT"
+		}
+		rs = append(rs, dnsRecord{Purpose: "ownership", Type: typ, Name: o.Name, Value: o.Value})
+	}
+	if dcv := item.Verification.SSL.DCV; dcv.Name != "" {
+		rs = append(rs, dnsRecord{Purpose: "ssl", Type: "CNAME", Name: dcv.Name, Value: dcv.Value})
+	}
+	for _, r := range item.Verification.SSL.Records {
+		rs = append(rs, dnsRecord{Purpose: "ssl", Type: "TXT", Name: r.TxtName, Value: r.TxtValue})
+	}
+	return rs
+}
+
+type dnsCheck struct {
+	dnsRecord `yaml:",inline"`
+	Found     []string `json:"found" yaml:"found"`
+	Error     string   `json:"error,omitempty" yaml:"error,omitempty"`
+	OK        bool     `json:"ok" yaml:"ok"`
+}
+
+// domainDiagnosis is the result of `domain check`: the records the domain
+// needs, what DNS currently answers for each, and the api's own view.
+type domainDiagnosis struct {
+	Domain     string     `json:"domain" yaml:"domain"`
+	Status     string     `json:"status" yaml:"status"`
+	CertStatus string     `json:"certStatus" yaml:"certStatus"`
+	Records    []dnsCheck `json:"records" yaml:"records"`
+	Missing    []string   `json:"missing" yaml:"missing"` // purposes no record set satisfies
+	Errors     []string   `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+func (d *domainDiagnosis) Table() [][]string {
+	table := [][]string{
+		{"PURPOSE", "TYPE", "NAME", "VALUE", "DNS"},
+	}
+	for _, c := range d.Records {
+		st := "ok"
+		switch {
+		case c.OK:
+		case c.Error != "":
+			st = c.Error
+		case len(c.Found) > 0:
+			st = "found " + strings.Join(c.Found, ", ")
+		default:
+			st = "missing"
+		}
+		table = append(table, []string{c.Purpose, c.Type, c.Name, c.Value, st})
+	}
+	return table
+}
+
+// lookupName is the name to query for a record; a wildcard is probed through
+// an arbitrary label it should cover.
+func lookupName(name string) string {
+	if rest, ok := strings.CutPrefix(name, "*."); ok {
+		return "deploys-dns-check." + rest
+	}
+	return name
+}
+
+func normalizeDNSName(s string) string {
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
+
+func checkDNSRecord(ctx context.Context, r dnsResolver, rec dnsRecord) dnsCheck {
+	c := dnsCheck{dnsRecord: rec}
+	name := lookupName(rec.Name)
+	var err error
+	switch rec.Type {
+	case "CNAME":
+		var cname string
+		cname, err = r.LookupCNAME(ctx, name)
+		if err != nil {
+			break
+		}
+		if normalizeDNSName(cname) != normalizeDNSName(name) {
+			c.Found = []string{normalizeDNSName(cname)}
+		}
+		c.OK = normalizeDNSName(cname) == normalizeDNSName(rec.Value)
+		if !c.OK && len(c.Found) > 0 {
+			// LookupCNAME returns the end of the chain, so a target that is
+			// itself an alias never matches by name; accept it when both
+			// resolve to the same addresses.
+			have, _ := r.LookupHost(ctx, name)
+			want, _ := r.LookupHost(ctx, rec.Value)
+			c.OK = len(have) > 0 && slices.ContainsFunc(have, func(a string) bool { return slices.Contains(want, a) })
+		}
+	case "A", "AAAA":
+		network := "ip4"
+		if rec.Type == "AAAA" {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = r.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			c.Found = append(c.Found, ip.String())
+		}
+		c.OK = slices.Contains(c.Found, rec.Value)
+	default:
+		c.Found, err = r.LookupTXT(ctx, name)
+		c.OK = slices.Contains(c.Found, rec.Value)
+	}
+	// NXDOMAIN/no data is just "missing"; anything else (timeouts, refused)
+	// is worth showing
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// diagnoseDomain checks every record a domain needs against r.
+func diagnoseDomain(ctx context.Context, r dnsResolver, item *api.DomainItem) *domainDiagnosis {
+	d := &domainDiagnosis{
+		Domain:     item.Domain,
+		Status:     item.Status.String(),
+		CertStatus: item.CertStatus.String(),
+		Missing:    []string{},
+	}
+	v := item.Verification
+	d.Errors = append(append(append(d.Errors, v.Ownership.Errors...), v.SSL.Errors...), v.DNS.Errors...)
+
+	satisfied := map[string]bool{}
+	failed := map[[2]string]bool{} // purpose, type
+	var purposes []string
+	for _, rec := range domainRecords(item) {
+		c := checkDNSRecord(ctx, r, rec)
+		d.Records = append(d.Records, c)
+		if !slices.Contains(purposes, rec.Purpose) {
+			purposes = append(purposes, rec.Purpose)
+		}
+		if !c.OK {
+			failed[[2]string{rec.Purpose, rec.Type}] = true
+		}
+	}
+	for _, c := range d.Records {
+		if !failed[[2]string{c.Purpose, c.Type}] {
+			satisfied[c.Purpose] = true
+		}
+	}
+	for _, p := range purposes {
+		if !satisfied[p] {
+			d.Missing = append(d.Missing, p)
+		}
+	}
+	return d
+}
+
+// domainActive reports whether a domain is fully set up: verified and with its
+// certificate issued.
+func domainActive(item *api.DomainItem) bool {
+	return item.Status == api.DomainStatusSuccess && item.CertStatus == api.DomainCertStatusCreated
+}
+
+// domainStage describes where a domain is in its setup, for -wait progress.
+func domainStage(item *api.DomainItem, d *domainDiagnosis) string {
+	switch {
+	case domainActive(item):
+		return "active"
+	case len(d.Missing) > 0:
+		return "waiting for DNS (" + strings.Join(d.Missing, ", ") + " records not found yet)"
+	case item.Status == api.DomainStatusError:
+		return "verification error: " + strings.Join(d.Errors, "; ")
+	case item.Status != api.DomainStatusSuccess:
+		return "DNS found; waiting for the api to verify the domain (" + item.Status.String() + ")"
+	default:
+		return "verified; issuing certificate (" + item.CertStatus.String() + ")"
+	}
+}
+
+// printDNSRecords writes the records to create as an aligned list.
+func printDNSRecords(w io.Writer, rs []dnsRecord) {
+	fmt.Fprintln(w, "Create these DNS records (one set per purpose; routing by CNAME or A/AAAA):")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range rs {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", r.Purpose, r.Type, r.Name, r.Value)
+	}
+	tw.Flush()
+}
+
+// waitDomain polls domain.get until the domain is active or timeout passes,
+// re-running the DNS diagnosis each round and writing every stage change to
+// w. Every poll runs under ctx cut off at that deadline, so none outlives the
+// wait. A zero timeout waits forever.
+func waitDomain(ctx context.Context, s api.Domain, r dnsResolver, req *api.DomainGet, interval, timeout time.Duration, w io.Writer) (*api.DomainItem, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var last string
+	printed := false
+	for {
+		item, err := s.Get(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("timed out after %s waiting for %s", timeout, req.Domain)
+			}
+			return nil, err
+		}
+		if !printed && len(domainRecords(item)) > 0 {
+			// the api fills in dnsConfig/verification asynchronously after
+			// create, so print the records once they are known
+			printDNSRecords(w, domainRecords(item))
+			printed = true
+		}
+		stage := domainStage(item, diagnoseDomain(ctx, r, item))
+		if stage != last {
+			fmt.Fprintf(w, "%s  %s: %s\n", time.Now().Format(time.TimeOnly), req.Domain, stage)
+			last = stage
+		}
+		if domainActive(item) {
+			return item, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out after %s waiting for %s (last stage: %s)", timeout, req.Domain, last)
+		case <-time.After(interval):
+		}
+	}
+}
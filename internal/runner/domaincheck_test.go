@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+// fakeResolver answers from fixed maps; anything else is NXDOMAIN.
+type fakeResolver struct {
+	cname map[string]string
+	txt   map[string][]string
+	ip    map[string][]string
+}
+
+func notFound(host string) error {
+	return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	if c, ok := r.cname[host]; ok {
+		return c + ".", nil
+	}
+	if _, ok := r.ip[host]; ok {
+		return host + ".", nil
+	}
+	return "", notFound(host)
+}
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if t, ok := r.txt[name]; ok {
+		return t, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r fakeResolver) LookupIP(_ context.Context, _, host string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, s := range r.ip[host] {
+		ips = append(ips, net.ParseIP(s))
+	}
+	if len(ips) == 0 {
+		return nil, notFound(host)
+	}
+	return ips, nil
+}
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if a, ok := r.ip[host]; ok {
+		return a, nil
+	}
+	return nil, notFound(host)
+}
+
+func testDomainItem() *api.DomainItem {
+	item := &api.DomainItem{Domain: "www.example.com"}
+	item.DNSConfig.CName = []string{"lb.deploys.app"}
+	item.DNSConfig.IPv4 = []string{"203.0.113.7"}
+	item.Verification.Ownership = api.DomainVerificationOwnership{Type: "txt", Name: "_deploys.www.example.com", Value: "tok"}
+	item.Verification.SSL.Records = []api.DomainVerificationSSLRecord{{TxtName: "_acme-challenge.www.example.com", TxtValue: "acme"}}
+	return item
+}
+
+func TestDiagnoseDomain(t *testing.T) {
+	item := testDomainItem()
+
+	// routed by A record (the CNAME alternative is absent), ownership set with
+	// the wrong value, ssl TXT missing
+	r := fakeResolver{
+		ip:  map[string][]string{"www.example.com": {"203.0.113.7"}},
+		txt: map[string][]string{"_deploys.www.example.com": {"other"}},
+	}
+	d := diagnoseDomain(context.Background(), r, item)
+	if got := strings.Join(d.Missing, ","); got != "ownership,ssl" {
+		t.Errorf("missing = %q; want ownership,ssl", got)
+	}
+	if len(d.Records) != 4 || d.Records[0].OK || !d.Records[1].OK || d.Records[2].Type != "TXT" {
+		t.Errorf("records = %+v", d.Records)
+	}
+	if row := d.Table()[3]; row[4] != "found other" {
+		t.Errorf("ownership row = %v", row)
+	}
+
+	r.txt["_deploys.www.example.com"] = []string{"tok"}
+	r.txt["_acme-challenge.www.example.com"] = []string{"acme"}
+	if d := diagnoseDomain(context.Background(), r, item); len(d.Missing) != 0 {
+		t.Errorf("all records present; missing = %v", d.Missing)
+	}
+}
+
+// A CNAME to an alias of the expected target is accepted by address.
+func TestCheckDNSRecordCNAMEChain(t *testing.T) {
+	r := fakeResolver{
+		cname: map[string]string{"www.example.com": "edge-1.deploys.app"},
+		ip:    map[string][]string{"www.example.com": {"203.0.113.7"}, "lb.deploys.app": {"203.0.113.7"}},
+	}
+	c := checkDNSRecord(context.Background(), r, dnsRecord{Type: "CNAME", Name: "www.example.com", Value: "lb.deploys.app"})
+	if !c.OK {
+		t.Errorf("check = %+v; want ok", c)
+	}
+}
+
+type fakeDomain struct {
+	api.Domain
+	items []*api.DomainItem
+}
+
+func (s *fakeDomain) Get(context.Context, *api.DomainGet) (*api.DomainItem, error) {
+	item := s.items[0]
+	if len(s.items) > 1 {
+		s.items = s.items[1:]
+	}
+	return item, nil
+}
+
+func TestWaitDomain(t *testing.T) {
+	pending := testDomainItem()
+	issuing := testDomainItem()
+	issuing.Status = api.DomainStatusSuccess
+	issuing.CertStatus = api.DomainCertStatusPendingCreate
+	active := testDomainItem()
+	active.Status = api.DomainStatusSuccess
+	active.CertStatus = api.DomainCertStatusCreated
+
+	r := fakeResolver{
+		cname: map[string]string{"www.example.com": "lb.deploys.app"},
+		txt:   map[string][]string{"_deploys.www.example.com": {"tok"}, "_acme-challenge.www.example.com": {"acme"}},
+	}
+	var out bytes.Buffer
+	s := &fakeDomain{items: []*api.DomainItem{pending, pending, issuing, active}}
+	item, err := waitDomain(context.Background(), s, r, &api.DomainGet{Domain: "www.example.com"}, time.Millisecond, time.Second, &out)
+	if err != nil || item != active {
+		t.Fatalf("waitDomain = %v, %v", item, err)
+	}
+	for _, want := range []string{"_deploys.www.example.com", "waiting for the api", "issuing certificate", "active"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("progress missing %q:\n%s", want, out.String())
+		}
+	}
+	// repeated polls in the same stage print once
+	if n := strings.Count(out.String(), "waiting for the api"); n != 1 {
+		t.Errorf("stage printed %d times", n)
+	}
+
+	s = &fakeDomain{items: []*api.DomainItem{pending}}
+	if _, err := waitDomain(context.Background(), s, fakeResolver{}, &api.DomainGet{Domain: "www.example.com"}, time.Millisecond, 20*time.Millisecond, &out); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v; want timeout", err)
+	}
+}
@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"os"
@@ -12,29 +11,23 @@ import (
 )
 
 func (rn Runner) dropbox(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("dropbox")
-	}
+	return rn.runGroup("dropbox", args, rn.dropboxLeaf)
+}
 
+func (rn *Runner) dropboxLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Dropbox()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("dropbox", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("dropbox", args[0])
+	switch sub {
 	case "list":
 		var req api.DropboxList
 		f.StringVar(&req.Project, "project", "", "project sid")
 		f.Var(timeFlag{&req.After}, "after", "only files after this time (RFC 3339 or YYYY-MM-DD)")
 		f.Var(timeFlag{&req.Before}, "before", "only files before this time (RFC 3339 or YYYY-MM-DD)")
 		f.IntVar(&req.Limit, "limit", 0, "max entries")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "metrics":
 		var (
 			req       api.DropboxMetrics
@@ -42,9 +35,12 @@ func (rn Runner) dropbox(args ...string) error {
 		)
 		f.StringVar(&req.Project, "project", "", "project sid")
 		f.StringVar(&timeRange, "time-range", "30d", "time range (7d, 30d, 90d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.UsageMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.TimeRange = api.UsageMetricsTimeRange(timeRange)
+			return s.Metrics(rn.ctx(), &req)
+		}
 	case "upload":
 		var opts client.DropboxUploadOptions
 		var file string
@@ -52,25 +48,33 @@ func (rn Runner) dropbox(args ...string) error {
 		f.StringVar(&file, "file", "", "path to the file to upload, or - for stdin (default stdin)")
 		f.StringVar(&opts.Filename, "filename", "", "filename recorded in the download (defaults to the base name of -file)")
 		f.IntVar(&opts.TTLDays, "ttl", 0, "download lifetime in days, 1-365 (default 1)")
-		f.Parse(args[1:])
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		c, ok := rn.API.(*client.Client)
-		if !ok {
-			return fmt.Errorf("dropbox upload requires the default api client")
-		}
+			c, ok := rn.API.(*client.Client)
+			if !ok {
+				return nil, fmt.Errorf("dropbox upload requires the default api client")
+			}
 
-		if file == "" || file == "-" {
-			opts.Content, err = io.ReadAll(os.Stdin)
-		} else {
-			opts.Content, err = os.ReadFile(file)
-			if opts.Filename == "" {
-				opts.Filename = filepath.Base(file)
+			if file == "" || file == "-" {
+				opts.Content, err = io.ReadAll(os.Stdin)
+			} else {
+				opts.Content, err = os.ReadFile(file)
+				if opts.Filename == "" {
+					opts.Filename = filepath.Base(file)
+				}
 			}
+			if err != nil {
+				return nil, err
+			}
+			// the journal records the upload, not its content
+			rec := opts
+			rec.Content = nil
+			o := rn.journalBegin("dropbox upload", journalTarget{Project: opts.Project, Name: opts.Filename}, &rec)
+			resp, err = c.DropboxUpload(rn.ctx(), &opts)
+			o.done(err)
+			return resp, err
 		}
-		if err != nil {
-			return err
-		}
-		resp, err = c.DropboxUpload(context.Background(), &opts)
 	case "upload-url":
 		var opts client.DropboxCreateUploadURLOptions
 		f.StringVar(&opts.Project, "project", "", "project sid")
@@ -80,16 +84,15 @@ func (rn Runner) dropbox(args ...string) error {
 		f.Int64Var(&opts.MaxSize, "max-size", 0, "maximum upload size in bytes (server clamps to its cap, default 5 GiB)")
 		f.IntVar(&opts.TTLDays, "ttl", 0, "download lifetime in days, 1-365 (default 1)")
 		f.IntVar(&opts.Expires, "expires", 0, "upload-URL lifetime in seconds, 1-3600 (default 900)")
-		f.Parse(args[1:])
+		return func(args []string) (any, error) {
+			f.Parse(args)
 
-		c, ok := rn.API.(*client.Client)
-		if !ok {
-			return fmt.Errorf("dropbox upload-url requires the default api client")
+			c, ok := rn.API.(*client.Client)
+			if !ok {
+				return nil, fmt.Errorf("dropbox upload-url requires the default api client")
+			}
+			return c.DropboxCreateUploadURL(rn.ctx(), &opts)
 		}
-		resp, err = c.DropboxCreateUploadURL(context.Background(), &opts)
-	}
-	if err != nil {
-		return err
 	}
-	return rn.print(resp)
+	return nil
 }
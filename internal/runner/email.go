@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -9,21 +8,12 @@ import (
 )
 
 func (rn Runner) email(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("email")
-	}
+	return rn.runGroup("email", args, rn.emailLeaf)
+}
 
+func (rn *Runner) emailLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Email()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("email", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("email", args[0])
+	switch sub {
 	case "send":
 		var (
 			req         api.EmailSend
@@ -40,36 +30,47 @@ func (rn Runner) email(args ...string) error {
 		f.StringVar(&typ, "type", "text", "body type (text, html)")
 		f.StringVar(&content, "content", "", "body content")
 		f.StringVar(&contentFile, "content-file", "", "read body content from file")
-		f.Parse(args[1:])
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		for _, addr := range splitComma(to) {
-			req.To = append(req.To, api.EmailAddr{Email: addr})
-		}
-		switch typ {
-		case "text", string(api.EmailTypeText):
-			req.Body.Type = api.EmailTypeText
-		case "html", string(api.EmailTypeHTML):
-			req.Body.Type = api.EmailTypeHTML
-		default:
-			return fmt.Errorf("invalid body type: '%s'", typ)
-		}
-		if contentFile != "" {
-			b, ferr := os.ReadFile(contentFile)
-			if ferr != nil {
-				return ferr
+			for _, addr := range splitComma(to) {
+				req.To = append(req.To, api.EmailAddr{Email: addr})
 			}
-			content = string(b)
+			// a -f request file may carry the body; -type and -content override it
+			if req.Body.Type == "" || visitedFlags(f.FlagSet)["type"] {
+				switch typ {
+				case "text", string(api.EmailTypeText):
+					req.Body.Type = api.EmailTypeText
+				case "html", string(api.EmailTypeHTML):
+					req.Body.Type = api.EmailTypeHTML
+				default:
+					return nil, fmt.Errorf("invalid body type: '%s'", typ)
+				}
+			}
+			if contentFile != "" {
+				b, ferr := os.ReadFile(contentFile)
+				if ferr != nil {
+					return nil, ferr
+				}
+				content = string(b)
+			}
+			if content != "" {
+				req.Body.Content = content
+			}
+			o := rn.journalBegin("email send", journalTarget{Project: req.Project, Name: req.Subject}, &req)
+			resp, err = s.Send(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		req.Body.Content = content
-		resp, err = s.Send(context.Background(), &req)
 	case "list":
 		var req api.EmailList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
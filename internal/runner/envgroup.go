@@ -1,27 +1,16 @@
 package runner
 
 import (
-	"context"
-
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) envGroup(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("envgroup")
-	}
+	return rn.runGroup("envgroup", args, rn.envGroupLeaf)
+}
 
+func (rn *Runner) envGroupLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.EnvGroup()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("envgroup", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("envgroup", args[0])
+	switch sub {
 	case "create":
 		var (
 			req api.EnvGroupCreate
@@ -30,23 +19,35 @@ func (rn Runner) envGroup(args ...string) error {
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "env group name")
 		f.Var(&env, "env", "env KEY=VALUE (repeatable)")
-		f.Parse(args[1:])
-		req.Env, err = parseKV(env)
-		if err != nil {
-			return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Env, err = parseKV(env)
+			if err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("envgroup create", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		resp, err = s.Create(context.Background(), &req)
 	case "get":
 		var req api.EnvGroupGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "env group name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.EnvGroupList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "update":
 		var (
 			req       api.EnvGroupUpdate
@@ -59,26 +60,45 @@ func (rn Runner) envGroup(args ...string) error {
 		f.Var(&env, "env", "env KEY=VALUE, replaces all existing env (repeatable)")
 		f.Var(&addEnv, "add-env", "env KEY=VALUE to add to existing env (repeatable)")
 		f.StringVar(&removeEnv, "remove-env", "", "env keys to remove (comma separated values)")
-		f.Parse(args[1:])
-		req.Env, err = parseKV(env)
-		if err != nil {
-			return err
-		}
-		req.AddEnv, err = parseKV(addEnv)
-		if err != nil {
-			return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Env, err = parseKV(env)
+			if err != nil {
+				return nil, err
+			}
+			req.AddEnv, err = parseKV(addEnv)
+			if err != nil {
+				return nil, err
+			}
+			req.RemoveEnv = splitComma(removeEnv)
+			o := rn.journalBegin("envgroup update", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		req.RemoveEnv = splitComma(removeEnv)
-		resp, err = s.Update(context.Background(), &req)
 	case "delete":
-		var req api.EnvGroupDelete
+		var (
+			req   api.EnvGroupDelete
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "env group name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.BoolVar(&force, "force", false, "delete even if deployments still use it")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.checkRefs("envgroup", req.Project, "", req.Name, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("envgroup delete", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
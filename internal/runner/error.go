@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -14,21 +13,12 @@ import (
 // issues with a triage lifecycle. It mirrors the flat-group pattern (see cache,
 // disk): IsHelpArg → group usage, subFlagSet per leaf, rn.print(resp).
 func (rn Runner) errorGroup(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("error")
-	}
+	return rn.runGroup("error", args, rn.errorGroupLeaf)
+}
 
+func (rn *Runner) errorGroupLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Errors()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("error", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("error", args[0])
+	switch sub {
 	case "list":
 		var req api.ErrorList
 		f.StringVar(&req.Project, "project", "", "project id")
@@ -38,26 +28,33 @@ func (rn Runner) errorGroup(args ...string) error {
 		f.StringVar(&req.Sort, "sort", "", "sort order: lastSeen (default), firstSeen, count")
 		f.IntVar(&req.Limit, "limit", 0, "max issues per page (default 50, max 200)")
 		f.StringVar(&req.Cursor, "cursor", "", "opaque page cursor from a previous response's nextCursor")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.ErrorGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.StringVar(&req.ID, "id", "", "error issue id")
-		f.Parse(args[1:])
-		got, gerr := s.Get(context.Background(), &req)
-		if gerr != nil {
-			return gerr
-		}
-		// In table mode print the issue summary, then the sample stack and recent
-		// occurrences, which the result's flat Table() omits. Other output modes
-		// (yaml/json) carry the full struct already, so fall through to print.
-		if rn.OutputMode == "" || rn.OutputMode == "table" {
-			return rn.printErrorIssueDetail(got)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			got, gerr := s.Get(rn.ctx(), &req)
+			if gerr != nil {
+				return nil, gerr
+			}
+			// In table mode print the issue summary, then the sample stack and recent
+			// occurrences, which the result's flat Table() omits. Other output modes
+			// (yaml/json) carry the full struct already, so fall through to print.
+			if (rn.OutputMode == "" || rn.OutputMode == "table") && !rn.watching() {
+				return nil, rn.printErrorIssueDetail(got)
+			}
+			resp = got
+			return resp, err
 		}
-		resp = got
 	case "update":
 		var req api.ErrorUpdate
 		f.StringVar(&req.Project, "project", "", "project id")
@@ -65,8 +62,14 @@ func (rn Runner) errorGroup(args ...string) error {
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.StringVar(&req.ID, "id", "", "error issue id")
 		f.StringVar(&req.Status, "status", "", "new triage status: resolved, open (reopen), or muted")
-		f.Parse(args[1:])
-		resp, err = s.Update(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("error update", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "report":
 		// report sends a single, minimal error event (one ErrorReport in Events).
 		// Frames are optional — omitting them fingerprints by Type alone, which is
@@ -83,20 +86,26 @@ func (rn Runner) errorGroup(args ...string) error {
 		f.StringVar(&title, "title", "", "optional display line (type + first message)")
 		f.StringVar(&sample, "sample", "", "optional full stack-trace text")
 		f.StringVar(&pod, "pod", "", "reporting instance/host (default \"reported\")")
-		f.Parse(args[1:])
-		req.Events = []api.ErrorReport{{
-			Kind:   kind,
-			Type:   typ,
-			Title:  title,
-			Sample: sample,
-			Pod:    pod,
-		}}
-		resp, err = s.Create(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			// a -f request file may carry the events instead
+			if typ != "" || len(req.Events) == 0 {
+				req.Events = []api.ErrorReport{{
+					Kind:   kind,
+					Type:   typ,
+					Title:  title,
+					Sample: sample,
+					Pod:    pod,
+				}}
+			}
+			o := rn.journalBegin("error report", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 // printErrorIssueDetail renders an error issue's full detail in table mode: the
@@ -32,13 +32,15 @@ type command struct {
 // subcommand describes one leaf command. args is an optional positional/flag
 // hint shown in the usage line; hidden keeps an entry out of the group listing
 // while still feeding its description to the subcommand banner (e.g. the
-// internal "set image" leaf, listed to users as "set").
+// internal "set image" leaf, listed to users as "set"). destructive marks a
+// leaf that must be confirmed (see confirmDestructive) and notes it in -h.
 type subcommand struct {
-	name    string
-	aliases []string
-	args    string
-	short   string
-	hidden  bool
+	name        string
+	aliases     []string
+	args        string
+	short       string
+	hidden      bool
+	destructive bool
 }
 
 // commands is the full CLI surface, in the order shown by the top-level help.
@@ -47,8 +49,9 @@ type subcommand struct {
 // groups, and each group method's `switch args[0]` for the leaves). Adding a
 // subcommand means adding both a `case` and an entry here; otherwise its -h
 // banner renders without a description. TestRegistryDescriptions guards that
-// every listed entry is described, but it cannot see a `case` that was never
-// listed — keep them in sync by hand.
+// every listed entry is described, and TestDispatchCasesHavePages reads the
+// switches from the source so a `case` that was never listed fails the build's
+// tests (and would be missing from `deploys docs`).
 var commands = []command{
 	{
 		name:  "auth",
@@ -62,6 +65,28 @@ var commands = []command{
 			{name: "token", args: "[-endpoint url] [-force]", short: "print the resolved bearer token (for scripts)"},
 		},
 	},
+	{
+		name:  "config",
+		short: "default project and location (active context and .deploys.yaml)",
+		subs: []subcommand{
+			{name: "set", args: "<key> <value>", short: "set endpoint, account, project, or location on the active context"},
+			{name: "unset", args: "<key>", short: "clear a setting on the active context"},
+			{name: "get", args: "<key>", short: "print a setting of the active context"},
+			{name: "show", short: "print the effective settings (environment, .deploys.yaml, context) and where each comes from"},
+		},
+	},
+	{
+		name:  "context",
+		short: "named bundles of endpoint, account, project, and location",
+		subs: []subcommand{
+			{name: "list", short: "list contexts, marking the active one"},
+			{name: "current", short: "print the active context's name"},
+			{name: "create", args: "<name> [-endpoint url] [-email account] [-project p] [-location l]", short: "create a context"},
+			{name: "update", args: "<name> [-endpoint url] [-email account] [-project p] [-location l]", short: "change a context's settings"},
+			{name: "use", args: "<name>", short: "make a context the active one"},
+			{name: "delete", args: "<name>", short: "delete a context"},
+		},
+	},
 	{
 		name:  "me",
 		short: "identity and access for the current credential",
@@ -111,7 +136,7 @@ var commands = []command{
 			{name: "list", short: "list your projects"},
 			{name: "get", short: "show a project"},
 			{name: "update", args: "[-name] [-billingaccount]", short: "update a project's name or billing account"},
-			{name: "delete", short: "delete a project"},
+			{name: "delete", args: "[-yes] [-force]", short: "delete a project", destructive: true},
 			{name: "usage", short: "show a project's resource usage"},
 		},
 	},
@@ -138,7 +163,7 @@ var commands = []command{
 			{name: "list", short: "list deployments"},
 			{name: "get", args: "[-revision n]", short: "show a deployment (optionally a specific revision)"},
 			{name: "deploy", short: "create or update a deployment (a merge over the previous revision)"},
-			{name: "delete", short: "delete a deployment"},
+			{name: "delete", args: "[-yes] [-force]", short: "delete a deployment (refuses while routes or GitHub links reference it)", destructive: true},
 			{name: "revisions", short: "list a deployment's revisions"},
 			{name: "pause", short: "pause a deployment"},
 			{name: "resume", short: "resume a paused deployment"},
@@ -147,7 +172,8 @@ var commands = []command{
 			{name: "metrics", args: "[-time-range 1h|6h|12h|1d]", short: "show deployment metrics"},
 			{name: "status", short: "show pod health and failure reasons"},
 			{name: "logs", args: "[-pod p] [-previous] [-tail n] [-follow]", short: "read a bounded snapshot of recent container logs"},
-			{name: "extend-ttl", args: "-name n -ttl s", short: "re-stamp a preview's auto-delete window to now+ttl (keep-alive)"},
+			{name: "logs-history", aliases: []string{"logsHistory"}, args: "-since <t> [-until <t>] [-pod p] [-limit n] [-reverse] [-cursor c]", short: "page through retained container logs over a time window"},
+			{name: "extend-ttl", aliases: []string{"extendTTL"}, args: "-name n -ttl s", short: "re-stamp a preview's auto-delete window to now+ttl (keep-alive)"},
 			// "set" is the user-facing listing; "set image" is the hidden leaf that
 			// backs its banner. They share wording so the listing and banner agree.
 			{name: "set", short: "roll out a new image (set image <name> -image <ref>)"},
@@ -169,7 +195,8 @@ var commands = []command{
 		name:  "domain",
 		short: "custom domains and edge cache",
 		subs: []subcommand{
-			{name: "create", args: "-domain [-wildcard]", short: "create a custom domain"},
+			{name: "create", args: "-domain [-wildcard] [-wait [-resolver addr] [-wait-timeout d]]", short: "create a custom domain (-wait: print DNS records, wait until active)"},
+			{name: "check", args: "-domain [-resolver addr]", short: "check the domain's required DNS records against live DNS"},
 			{name: "get", args: "-domain", short: "show a domain"},
 			{name: "list", short: "list domains"},
 			{name: "delete", args: "-domain", short: "delete a domain"},
@@ -184,6 +211,9 @@ var commands = []command{
 			{name: "get", args: "-domain -path", short: "show a route"},
 			{name: "list", short: "list routes"},
 			{name: "delete", args: "-domain -path", short: "delete a route"},
+			{name: "export", args: "[-o file] [-include-secrets]", short: "write the location's route table as yaml"},
+			{name: "sync", args: "-f <routes.yaml> [-prune] [-dry-run]", short: "create/update routes to match a route table (-prune deletes the rest)"},
+			{name: "match", args: "<url>", short: "explain which route serves a URL (and the candidates it beat)"},
 		},
 	},
 	{
@@ -192,10 +222,13 @@ var commands = []command{
 		subs: []subcommand{
 			{name: "get", short: "show the WAF zone"},
 			{name: "list", short: "list WAF zones in a project"},
-			{name: "set", args: "-f <spec.yaml> [-description]", short: "apply a WAF zone from a YAML spec"},
+			{name: "set", args: "-f <spec.yaml> [-description] [-keep n]", short: "apply a WAF zone from a YAML spec (snapshots the current zone first)"},
+			{name: "init", args: "-preset a,b[:args] [-merge] [-o file] | -list", short: "generate a WAF spec from composable preset rule packs"},
+			{name: "history", short: "list local snapshots of the WAF zone"},
+			{name: "restore", args: "-at <timestamp> [-keep n]", short: "re-apply a local WAF zone snapshot"},
 			{name: "delete", short: "delete the WAF zone"},
-			{name: "metrics", args: "[-time-range 1h|6h|12h|1d|7d|30d]", short: "show WAF request metrics"},
-			{name: "limitmetrics", args: "[-time-range 1h|6h|12h|1d|7d|30d]", short: "show WAF rate-limit metrics"},
+			{name: "metrics", args: "[-time-range 1h|6h|12h|1d|7d|30d] [-report]", short: "show WAF request metrics (-report: blocked vs allowed, top rules)"},
+			{name: "limitmetrics", args: "[-time-range 1h|6h|12h|1d|7d|30d] [-report [-threshold r]]", short: "show WAF rate-limit metrics (-report: frequently hit limits)"},
 		},
 	},
 	{
@@ -204,9 +237,12 @@ var commands = []command{
 		subs: []subcommand{
 			{name: "get", short: "show the cache-override zone"},
 			{name: "list", short: "list cache zones in a project"},
-			{name: "set", args: "-f <spec.yaml> [-description]", short: "replace the cache zone's overrides from a YAML spec"},
+			{name: "set", args: "-f <spec.yaml> [-description] [-keep n]", short: "replace the cache zone's overrides from a YAML spec (snapshots the current zone first)"},
+			{name: "init", args: "-preset a,b[:args] [-merge] [-o file] | -list", short: "generate a cache spec from composable preset overrides"},
+			{name: "history", short: "list local snapshots of the cache zone"},
+			{name: "restore", args: "-at <timestamp> [-keep n]", short: "re-apply a local cache zone snapshot"},
 			{name: "delete", short: "delete the cache zone"},
-			{name: "metrics", args: "[-time-range 1h|6h|12h|1d|7d|30d]", short: "show edge cache metrics"},
+			{name: "metrics", args: "[-time-range 1h|6h|12h|1d|7d|30d] [-report]", short: "show edge cache metrics (-report: hit ratio, bandwidth saved)"},
 		},
 	},
 	{
@@ -215,7 +251,9 @@ var commands = []command{
 		subs: []subcommand{
 			{name: "get", short: "show the transform zone"},
 			{name: "list", short: "list transform zones in a project"},
-			{name: "set", args: "-f <spec.yaml> [-description]", short: "replace the transform zone's rules from a YAML spec"},
+			{name: "set", args: "-f <spec.yaml> [-description] [-keep n]", short: "replace the transform zone's rules from a YAML spec (snapshots the current zone first)"},
+			{name: "history", short: "list local snapshots of the transform zone"},
+			{name: "restore", args: "-at <timestamp> [-keep n]", short: "re-apply a local transform zone snapshot"},
 			{name: "delete", short: "delete the transform zone"},
 		},
 	},
@@ -227,7 +265,7 @@ var commands = []command{
 			{name: "get", short: "show a disk"},
 			{name: "list", short: "list disks"},
 			{name: "update", args: "-size <Gi>", short: "resize a disk"},
-			{name: "delete", short: "delete a disk"},
+			{name: "delete", args: "[-yes] [-force]", short: "delete a disk (refuses while a deployment mounts it)", destructive: true},
 			{name: "metrics", args: "[-time-range 1h|6h|12h|1d|2d|7d|30d]", short: "show disk usage metrics"},
 		},
 	},
@@ -239,7 +277,7 @@ var commands = []command{
 			{name: "create", args: "-server -username -password", short: "create a pull secret"},
 			{name: "get", short: "show a pull secret"},
 			{name: "list", short: "list pull secrets"},
-			{name: "delete", short: "delete a pull secret"},
+			{name: "delete", args: "[-force]", short: "delete a pull secret (refuses while deployments use it)"},
 		},
 	},
 	{
@@ -250,7 +288,7 @@ var commands = []command{
 			{name: "create", args: "-gsa <google-sa>", short: "create a workload identity"},
 			{name: "get", short: "show a workload identity"},
 			{name: "list", short: "list workload identities"},
-			{name: "delete", short: "delete a workload identity"},
+			{name: "delete", args: "[-force]", short: "delete a workload identity (refuses while deployments use it)"},
 		},
 	},
 	{
@@ -284,10 +322,10 @@ var commands = []command{
 			{name: "tags", args: "-repository", short: "list a repository's tags"},
 			{name: "manifests", args: "-repository", short: "list a repository's manifests"},
 			{name: "storage", short: "show registry storage usage"},
-			{name: "delete", args: "-repository", short: "delete a repository"},
+			{name: "delete", args: "-repository [-yes] [-force]", short: "delete a repository", destructive: true},
 			{name: "deletemanifest", args: "-repository -digest", short: "delete a manifest by digest"},
 			{name: "untag", args: "-repository -tag", short: "remove a tag"},
-			{name: "gc", args: "[-dry-run]", short: "garbage-collect manifests no deployment uses"},
+			{name: "gc", args: "[-dry-run] [-yes] [-force]", short: "garbage-collect manifests no deployment uses", destructive: true},
 			{name: "metrics", args: "[-time-range 7d|30d|90d]", short: "show registry storage metrics"},
 		},
 	},
@@ -300,7 +338,7 @@ var commands = []command{
 			{name: "get", args: "-name", short: "show an env group"},
 			{name: "list", short: "list env groups"},
 			{name: "update", args: "-name [-env|-add-env|-remove-env]", short: "update an env group's variables"},
-			{name: "delete", args: "-name", short: "delete an env group"},
+			{name: "delete", args: "-name [-force]", short: "delete an env group (refuses while deployments use it)"},
 		},
 	},
 	{
@@ -320,6 +358,14 @@ var commands = []command{
 			{name: "upload-url", args: "-project <sid> [-filename -content-type -min-size -max-size -ttl -expires]", short: "mint a signed upload URL to hand off (recipient PUTs the file, no token needed)"},
 		},
 	},
+	{
+		name:  "collector",
+		short: "internal usage-collector endpoints (collector token auth)",
+		subs: []subcommand{
+			{name: "location", args: "-location <id>", short: "list the projects (and routed domains) a location collects usage for"},
+			{name: "push", args: "-kind <kind> -f <usage.yaml> [-location]", short: "push usage buckets (project, deployment, disk, waf, ratelimit, cacheoverride, cacheresult)"},
+		},
+	},
 	{
 		name:  "github",
 		short: "link GitHub repositories for build-and-deploy",
@@ -368,6 +414,27 @@ var commands = []command{
 			{name: "pull", args: "-name [-ack -limit -follow -poll -interval]", short: "fetch a pull channel's change events (ack to advance; -follow streams over SSE)"},
 		},
 	},
+	{
+		name:  "mcp",
+		short: "serve the api to AI assistants over the Model Context Protocol",
+		subs: []subcommand{
+			{name: "serve", args: "[-read-only] [-allow patterns]", short: "serve api methods as mcp tools over stdio"},
+		},
+	},
+}
+
+// standaloneCommands are the top-level commands that are not groups with
+// subcommands (login and logout aside, which alias auth's), in help order.
+var standaloneCommands = []subcommand{
+	{name: "refs", args: "<type> <name>", short: "list what still references a resource (deployment, envgroup, pullsecret, workloadidentity, disk)"},
+	{name: "api", args: "<resource.method> [-data json|@file|-] | -list", short: "call any api method by name with a json request body"},
+	{name: "dev-server", args: "[-listen addr] [-state file] [-seed file]", short: "serve an in-memory api for testing scripts offline"},
+	{name: "history", args: "[-limit n] [-project p]", short: "list recent operations from the local journal"},
+	{name: "undo", args: "[id] [-dry-run]", short: "revert a journaled operation"},
+	{name: "completion", args: "bash|zsh|fish|powershell", short: "print a shell completion script"},
+	{name: "docs", args: "-out <dir> [-format man|markdown]", short: "write the command reference as man pages or Markdown"},
+	{name: "version", short: "print the cli version"},
+	{name: "check-update", short: "check whether a newer cli version is available"},
 }
 
 // lookupCommand finds a group by its canonical name or any alias.
@@ -429,15 +496,20 @@ func PrintUsage(w io.Writer) {
 		}
 		fmt.Fprintf(tw, "  %s\t%s\n", name, strings.Join(subs, ", "))
 	}
-	// version and check-update are standalone, API-less utility commands, not
-	// groups with subcommands, so they live outside the registry above.
-	fmt.Fprintf(tw, "  %s\t%s\n", "version", "print the cli version")
-	fmt.Fprintf(tw, "  %s\t%s\n", "check-update", "check whether a newer cli version is available")
+	for _, c := range standaloneCommands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.short)
+	}
 	tw.Flush()
 
 	fmt.Fprint(w, "\nFlags:\n")
-	fmt.Fprint(w, "  -output table|yaml|json|toon   output mode (or the -oyaml, -ojson, -otable, -otoon shorthands)\n")
+	fmt.Fprint(w, "  -output table|yaml|json|toon|csv|tsv|markdown   output mode (or the -oyaml, -ojson, -otable, -otoon, -ocsv, -otsv, -omarkdown shorthands)\n")
+	fmt.Fprint(w, "  -output template=<tmpl>   render with a Go template (or jsonpath=<expr>) over the -ojson value\n")
+	fmt.Fprint(w, "  -columns a,b -sort-by c   show only these table columns / sort rows by a column (-c descending)\n")
+	fmt.Fprint(w, "  -wide, -no-headers        extra table columns, never truncated / omit the header row\n")
 	fmt.Fprint(w, "  -account email            use a specific stored account for this command\n")
+	fmt.Fprint(w, "  -v, -vv                   before the command: log each http call to stderr / also its headers and bodies, secrets redacted\n")
+	fmt.Fprint(w, "  -timeout 15s -retries 2   before the command: each api call attempt's time limit / retries after a 429, 5xx, or network error\n")
+	fmt.Fprint(w, "  -backoff 500ms -max-backoff 10s   first wait between retries, doubling up to the max\n")
 
 	fmt.Fprint(w, "\nEnvironment:\n")
 	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
@@ -448,6 +520,14 @@ func PrintUsage(w io.Writer) {
 	fmt.Fprint(tw, "  DEPLOYS_ACCOUNT\tselect a stored account by email (same as -account)\n")
 	fmt.Fprint(tw, "  DEPLOYS_AUTH_ENDPOINT\toverride the auth (login) server\n")
 	fmt.Fprint(tw, "  DEPLOYS_CONFIG_DIR\toverride the config/credentials directory\n")
+	fmt.Fprint(tw, "  DEPLOYS_CONTEXT\tuse this context instead of the current one\n")
+	fmt.Fprint(tw, "  DEPLOYS_PROJECT\tdefault for an unset -project (over the context)\n")
+	fmt.Fprint(tw, "  DEPLOYS_LOCATION\tdefault for an unset -location (over the context)\n")
+	fmt.Fprint(tw, "  DEPLOYS_DEBUG\t1 traces http calls like -v, 2 like -vv\n")
+	fmt.Fprint(tw, "  DEPLOYS_TIMEOUT, DEPLOYS_RETRIES\tdefaults for -timeout and -retries\n")
+	fmt.Fprint(tw, "  DEPLOYS_BACKOFF, DEPLOYS_MAX_BACKOFF\tdefaults for -backoff and -max-backoff\n")
+	fmt.Fprint(tw, "  DEPLOYS_RECORD\tsave each api call to this directory, secrets redacted\n")
+	fmt.Fprint(tw, "  DEPLOYS_REPLAY\tanswer api calls from a DEPLOYS_RECORD directory, offline\n")
 	tw.Flush()
 
 	fmt.Fprint(w, "\nRun \"deploys <command> -h\" for a command's subcommands and flags.\n")
@@ -490,10 +570,17 @@ func writeGroupUsage(w io.Writer, c *command) {
 	fmt.Fprintf(w, "\nRun \"deploys %s <subcommand> -h\" for a subcommand's flags.\n", c.name)
 }
 
+// flagPrinter is the part of a flag set writeSubUsage renders: a plain
+// *flag.FlagSet or a leafFlagSet.
+type flagPrinter interface {
+	SetOutput(io.Writer)
+	PrintDefaults()
+}
+
 // writeSubUsage writes a leaf command's help: its description, a usage line, and
 // the flag list (rendered from the live flag set, so it always matches what the
 // command actually accepts).
-func writeSubUsage(w io.Writer, f *flag.FlagSet, group, sub string) {
+func writeSubUsage(w io.Writer, f flagPrinter, group, sub string) {
 	c := lookupCommand(group)
 	entry := c.lookupSub(sub)
 
@@ -506,7 +593,11 @@ func writeSubUsage(w io.Writer, f *flag.FlagSet, group, sub string) {
 		usage += " " + entry.args
 	}
 	usage += " [flags]"
-	fmt.Fprintf(w, "%s\n\nFlags:\n", usage)
+	fmt.Fprintf(w, "%s\n\n", usage)
+	if entry != nil && entry.destructive {
+		fmt.Fprint(w, "Destructive: on a terminal, type the resource name to confirm; otherwise pass -yes.\nNames protected in the config dir's protected.yaml also need -force.\n\n")
+	}
+	fmt.Fprint(w, "Flags:\n")
 
 	f.SetOutput(w)
 	f.PrintDefaults()
@@ -538,10 +629,18 @@ func (rn Runner) unknownSub(group, sub string) error {
 // that prints writeSubUsage on -h/-help/--help. registerFlags binds the shared
 // -output flag to this same Runner so the chosen output mode reaches print();
 // a pointer receiver keeps that binding pointing at the caller's Runner.
-func (rn *Runner) subFlagSet(group, sub string) *flag.FlagSet {
+func (rn *Runner) subFlagSet(group, sub string) *leafFlagSet {
 	f := flag.NewFlagSet("deploys "+group+" "+sub, flag.ExitOnError)
 	f.SetOutput(rn.output())
 	rn.registerFlags(f)
 	f.Usage = func() { writeSubUsage(rn.output(), f, group, sub) }
-	return f
+	lf := &leafFlagSet{FlagSet: f, group: group, command: group + " " + sub}
+	if e := lookupCommand(group).lookupSub(sub); e != nil {
+		lf.command = group + " " + e.name
+	}
+	if !noScopeFill[lf.command] {
+		lf.scope = rn.scope
+	}
+	rn.registerWatchFlags(lf)
+	return lf
 }
@@ -2,7 +2,12 @@ package runner
 
 import (
 	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -154,8 +159,8 @@ func TestRunUnknownCommand(t *testing.T) {
 
 // Every registry entry must carry a description and resolve by its name and
 // aliases, so no -h banner or listing renders blank. This guards the registry's
-// internal consistency; it cannot see a dispatch `case` that was never listed
-// (see the INVARIANT comment on commands).
+// internal consistency; TestDispatchCasesHavePages covers the other direction
+// (a dispatch `case` that was never listed).
 func TestRegistryDescriptions(t *testing.T) {
 	for _, c := range commands {
 		if c.name == "" || c.short == "" {
@@ -185,6 +190,163 @@ func TestRegistryDescriptions(t *testing.T) {
 	}
 }
 
+// TestDispatchCasesHavePages closes the gap the registry invariant leaves open:
+// it reads every `case` of the dispatch switches from the source and checks
+// that the generated reference has a page for it, so a case that was never
+// added to the registry fails here instead of shipping undocumented.
+func TestDispatchCasesHavePages(t *testing.T) {
+	dir := t.TempDir()
+	if err := (Runner{Output: tempOut(t)}).Run("docs", "-out", dir); err != nil {
+		t.Fatal(err)
+	}
+	hasPage := func(base string) bool {
+		m1, _ := filepath.Glob(filepath.Join(dir, base+".md"))
+		m2, _ := filepath.Glob(filepath.Join(dir, base+"-*.md"))
+		return len(m1)+len(m2) > 0
+	}
+
+	top, groups := dispatchCases(t)
+	if len(top) == 0 || len(groups) == 0 {
+		t.Fatal("found no dispatch switches")
+	}
+	for _, name := range top {
+		base := "deploys-" + name
+		if c := lookupCommand(name); c != nil {
+			base = "deploys-" + c.name
+		}
+		if !hasPage(base) {
+			t.Errorf("deploys %s: dispatched but has no page", name)
+		}
+	}
+	for _, gc := range groups {
+		group, prefix, _ := strings.Cut(gc[0], " ")
+		c := lookupCommand(group)
+		e := c.lookupSub(strings.TrimSpace(prefix + " " + gc[1]))
+		if e == nil {
+			t.Errorf("deploys %s %s: dispatched but not in the registry", gc[0], gc[1])
+			continue
+		}
+		if isListing(c, e.name) {
+			continue // its nested leaf has the page
+		}
+		if !hasPage("deploys-" + c.name + "-" + strings.ReplaceAll(e.name, " ", "-")) {
+			t.Errorf("deploys %s %s: dispatched but has no page", gc[0], gc[1])
+		}
+	}
+}
+
+// dispatchCases reads the dispatch switches from the package source: Run's
+// `switch args[0]` cases, and the `switch sub` cases of each group's leaf
+// builder paired with the group its runGroup call names.
+func dispatchCases(t *testing.T) (top []string, groups [][2]string) {
+	t.Helper()
+	files := parseRunner(t)
+	leaves := leafGroups(files)
+	for _, file := range files {
+		for _, d := range file.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			group := leaves[fd.Name.Name]
+			if fd.Name.Name != "Run" && group == "" {
+				continue
+			}
+			var cases []string
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				sw, ok := n.(*ast.SwitchStmt)
+				if !ok || !(isArgs0(sw.Tag) || isIdent(sw.Tag, "sub")) {
+					return true
+				}
+				for _, s := range sw.Body.List {
+					for _, e := range s.(*ast.CaseClause).List {
+						if v := stringLit(e); v != "" {
+							cases = append(cases, v)
+						}
+					}
+				}
+				return true
+			})
+			if group == "" {
+				top = append(top, cases...)
+				continue
+			}
+			for _, c := range cases {
+				groups = append(groups, [2]string{group, c})
+			}
+		}
+	}
+	return top, groups
+}
+
+// parseRunner parses the package's non-test files.
+func parseRunner(t *testing.T) []*ast.File {
+	t.Helper()
+	names, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ast.File
+	fset := token.NewFileSet()
+	for _, fn := range names {
+		if strings.HasSuffix(fn, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, fn, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// leafGroups maps each group's leaf builder, by name, to the group: the
+// `rn.runGroup("<group>", args, rn.<builder>)` calls of the group commands.
+func leafGroups(files []*ast.File) map[string]string {
+	leaves := map[string]string{}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 3 {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "runGroup" {
+				return true
+			}
+			if leaf, ok := call.Args[2].(*ast.SelectorExpr); ok {
+				leaves[leaf.Sel.Name] = stringLit(call.Args[0])
+			}
+			return true
+		})
+	}
+	return leaves
+}
+
+func isArgs0(e ast.Expr) bool {
+	ix, ok := e.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	id, ok := ix.X.(*ast.Ident)
+	lit, ok2 := ix.Index.(*ast.BasicLit)
+	return ok && ok2 && id.Name == "args" && lit.Value == "0"
+}
+
+func isIdent(e ast.Expr, name string) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+func stringLit(e ast.Expr) string {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, _ := strconv.Unquote(lit.Value)
+	return s
+}
+
 func indexOf(group string) int {
 	for i := range commands {
 		if commands[i].name == group {
@@ -0,0 +1,781 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+// journalFile is the local operation journal: one JSON entry per line, oldest
+// first, written after each journaled command succeeds. It holds the prior
+// state of what was changed (env values included), so it is private to the
+// user like the credentials file.
+const journalFile = "journal.jsonl"
+
+// journalKeep caps the journal; the oldest entries are dropped past it.
+const journalKeep = 500
+
+// journalTarget names the resource an operation changed. Email is set for role
+// grant/revoke/bind, where Name is the role id, and for billing members, where
+// Name is the billing account id; billing operations have no Project.
+type journalTarget struct {
+	Project  string `json:"project" yaml:"project"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Email    string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+func (t journalTarget) String() string {
+	var parts []string
+	if t.Project != "" {
+		parts = append(parts, t.Project)
+	}
+	if t.Location != "" {
+		parts = append(parts, t.Location)
+	}
+	if t.Name != "" {
+		parts = append(parts, t.Name)
+	}
+	s := strings.Join(parts, "/")
+	if t.Email != "" {
+		s += " " + t.Email
+	}
+	return s
+}
+
+// journalEntry is one recorded operation. Prior is the resource as the
+// matching get returned it just before the change, absent when it did not
+// exist; NoPrior explains a prior state that could not be read.
+type journalEntry struct {
+	ID       int             `json:"id"`
+	Time     time.Time       `json:"time"`
+	Actor    string          `json:"actor"`
+	Endpoint string          `json:"endpoint"`
+	Op       string          `json:"op"` // "<group> <sub>", e.g. "envgroup delete"
+	Target   journalTarget   `json:"target"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Prior    json.RawMessage `json:"prior,omitempty"`
+	NoPrior  string          `json:"noPrior,omitempty"`
+	Undoes   int             `json:"undoes,omitempty"`
+	UndoneBy int             `json:"undoneBy,omitempty"`
+}
+
+func (e *journalEntry) group() string {
+	g, _, _ := strings.Cut(e.Op, " ")
+	return g
+}
+
+func journalPath() (string, error) {
+	d, err := auth.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, journalFile), nil
+}
+
+// loadJournal reads every entry, oldest first; a missing journal is empty.
+func loadJournal() ([]journalEntry, error) {
+	fn, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []journalEntry
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 16<<20)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("parse %s:%d: %w", fn, n, err)
+		}
+		list = append(list, e)
+	}
+	return list, s.Err()
+}
+
+// saveJournal rewrites the journal (keeping the newest journalKeep entries)
+// through a temp file, so a crash never leaves it half written.
+func saveJournal(list []journalEntry) error {
+	fn, err := journalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0o700); err != nil {
+		return err
+	}
+	if len(list) > journalKeep {
+		list = list[len(list)-journalKeep:]
+	}
+	var buf bytes.Buffer
+	for _, e := range list {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// appendJournal assigns e the next id and writes it; undoes, when set, marks
+// that entry as undone by e. It holds the journal's lock across the read and
+// rewrite, so concurrent commands never drop each other's entries.
+func appendJournal(e *journalEntry) error {
+	unlock, err := auth.Lock(journalFile + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	list, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(list) > 0 {
+		e.ID = list[len(list)-1].ID + 1
+	}
+	for i := range list {
+		if e.Undoes != 0 && list[i].ID == e.Undoes {
+			list[i].UndoneBy = e.ID
+		}
+	}
+	return saveJournal(append(list, *e))
+}
+
+// journalEndpoint is the endpoint commands talk to, normalized like the
+// credentials store keys it.
+func (rn Runner) journalEndpoint() string {
+	return auth.NormalizeEndpoint(rn.Endpoint)
+}
+
+// journalActor names who the command ran as, following the client's
+// credential resolution order (see newAPIClient in main).
+func (rn Runner) journalActor(endpoint string) string {
+	if u := os.Getenv("DEPLOYS_AUTH_USER"); u != "" && os.Getenv("DEPLOYS_AUTH_PASS") != "" {
+		return u
+	}
+	if os.Getenv("DEPLOYS_TOKEN") != "" {
+		return "DEPLOYS_TOKEN"
+	}
+	if acct, err := auth.Lookup(rn.selector(), endpoint); err == nil && acct != nil {
+		return acct.Email
+	}
+	return "google-adc"
+}
+
+// readPrior fetches what an operation in group is about to change. A resource
+// that does not exist yet is (nil, nil). For role grant/revoke it is the
+// user's role binding in the project.
+func readPrior(ctx context.Context, a api.Interface, group string, t journalTarget) (any, error) {
+	var (
+		v        any
+		err      error
+		notFound error
+	)
+	switch group {
+	case "deployment":
+		v, err = a.Deployment().Get(ctx, &api.DeploymentGet{Project: t.Project, Location: t.Location, Name: t.Name})
+		notFound = api.ErrDeploymentNotFound
+	case "envgroup":
+		v, err = a.EnvGroup().Get(ctx, &api.EnvGroupGet{Project: t.Project, Name: t.Name})
+		notFound = api.ErrEnvGroupNotFound
+	case "waf":
+		v, err = a.WAF().Get(ctx, &api.WAFGet{Project: t.Project, Location: t.Location})
+		notFound = api.ErrWAFZoneNotFound
+	case "cache":
+		v, err = a.Cache().Get(ctx, &api.CacheGet{Project: t.Project, Location: t.Location})
+		notFound = api.ErrCacheZoneNotFound
+	case "transform":
+		v, err = a.Transform().Get(ctx, &api.TransformGet{Project: t.Project, Location: t.Location})
+		notFound = api.ErrTransformZoneNotFound
+	case "scheduler":
+		v, err = a.Scheduler().Get(ctx, &api.SchedulerGet{Project: t.Project, Name: t.Name})
+		notFound = api.ErrSchedulerJobNotFound
+	case "role":
+		users, err := a.Role().Users(ctx, &api.RoleUsers{Project: t.Project})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users.Items {
+			if strings.EqualFold(u.Email, t.Email) {
+				return u, nil
+			}
+		}
+		return &api.RoleUsersItem{Email: t.Email, Roles: []string{}}, nil
+	default:
+		return nil, fmt.Errorf("no prior state for %s", group)
+	}
+	if errors.Is(err, notFound) {
+		return nil, nil
+	}
+	return v, err
+}
+
+// journalOnly are the journaled operations undo cannot revert, each with what
+// to do instead. They are recorded for history alone, so their prior state is
+// not read.
+var journalOnly = map[string]string{
+	"project update":           "its previous settings were not recorded; set them back with deploys project update",
+	"project delete":           "a deleted project can't be restored",
+	"deployment pause":         "whether it was already paused is not recorded; resume it with deploys deployment resume",
+	"deployment resume":        "whether it was already running is not recorded; pause it with deploys deployment pause",
+	"disk update":              "a resized disk can't be shrunk back",
+	"disk delete":              "a deleted disk and its data can't be restored",
+	"route create":             "the route it replaced, if any, was not recorded; remove it with deploys route delete",
+	"route delete":             "the deleted route was not recorded; re-create it with deploys route create",
+	"route sync":               "the previous routes were not recorded; sync from an earlier route export",
+	"domain create":            "remove it with deploys domain delete",
+	"domain delete":            "a deleted domain must be created and verified again",
+	"pullsecret create":        "remove it with deploys pullsecret delete",
+	"pullsecret delete":        "its credentials are not kept; re-create it with deploys pullsecret create",
+	"workloadidentity create":  "remove it with deploys workloadidentity delete",
+	"workloadidentity delete":  "re-create it with deploys workloadidentity create",
+	"serviceaccount create":    "remove it with deploys serviceaccount delete",
+	"serviceaccount update":    "its previous name and description were not recorded",
+	"serviceaccount delete":    "a deleted service account and its keys can't be restored",
+	"serviceaccount createkey": "remove the key with deploys serviceaccount deletekey",
+	"serviceaccount deletekey": "a deleted key can't be restored; create a new one",
+	"registry delete":          "deleted images can't be restored",
+	"registry gc":              "collected images can't be restored",
+	"scheduler create":         "remove it with deploys scheduler delete",
+	"scheduler update":         "its previous settings were not recorded",
+	"scheduler delete":         "its settings and auth secret are not kept; re-create it with deploys scheduler create",
+	"notification create":      "remove it with deploys notification delete",
+	"notification update":      "its previous settings were not recorded",
+	"notification delete":      "its settings and secret are not kept; re-create it with deploys notification create",
+	"project create":           "remove it with deploys project delete",
+	"role create":              "remove it with deploys role delete",
+	"role delete":              "its permissions were not recorded; re-create it with deploys role create",
+	"role bind":                "the roles it replaced were not recorded; bind them again with deploys role bind",
+	"deployment restart":       "a restart can't be reverted",
+	"deployment extend-ttl":    "the previous expiry was not recorded; set it again with deploys deployment extend-ttl",
+	"disk create":              "remove it with deploys disk delete",
+	"github link":              "remove it with deploys github unlink",
+	"github unlink":            "re-link it with deploys github link",
+	"github update":            "its previous settings were not recorded",
+	"registry deletemanifest":  "a deleted manifest can't be restored",
+	"registry untag":           "push the image again to re-tag it",
+	"billing create":           "remove it with deploys billing delete",
+	"billing update":           "its previous settings were not recorded",
+	"billing delete":           "a deleted billing account can't be restored",
+	"billing add-member":       "remove the member with deploys billing remove-member",
+	"billing remove-member":    "their role was not recorded; add them back with deploys billing add-member",
+	"me generate-token":        "revoke it with deploys me revoke-token",
+	"me revoke-token":          "a revoked token can't be restored; generate a new one",
+	"error update":             "its previous status was not recorded; set it with deploys error update",
+	"error report":             "a reported error event can't be withdrawn",
+	"domain purgecache":        "purged files are cached again on their next request",
+	"scheduler trigger":        "a triggered run can't be reverted",
+	"email send":               "a sent email can't be recalled",
+	"dropbox upload":           "an upload can't be withdrawn; its link expires after its ttl",
+}
+
+// journalOp is a journaled command in flight: the prior state is read before
+// the change, and the entry is written only once the change succeeds.
+type journalOp struct {
+	entry journalEntry
+}
+
+// journalBegin reads the prior state of what op is about to change. The
+// journal never blocks the command it records: a prior state that cannot be
+// read is noted (that entry just can't be undone) and the command proceeds.
+func (rn Runner) journalBegin(op string, t journalTarget, req any) *journalOp {
+	endpoint := rn.journalEndpoint()
+	o := &journalOp{entry: journalEntry{
+		Time:     time.Now().UTC(),
+		Actor:    rn.journalActor(endpoint),
+		Endpoint: endpoint,
+		Op:       op,
+		Target:   t,
+	}}
+	o.entry.Request, _ = json.Marshal(req)
+	if _, ok := journalOnly[op]; ok {
+		return o
+	}
+	prior, err := readPrior(rn.ctx(), rn.API, o.entry.group(), t)
+	if err == nil && prior != nil {
+		o.entry.Prior, err = json.Marshal(prior)
+	}
+	if err != nil {
+		o.entry.NoPrior = err.Error()
+		fmt.Fprintf(os.Stderr, "warning: journal: could not read the current %s (%v); this operation will not be undoable\n", o.entry.group(), err)
+	}
+	return o
+}
+
+// done records the operation if it succeeded (err == nil). A journal that
+// cannot be written is a warning: the change itself already happened.
+func (o *journalOp) done(err error) {
+	if err != nil {
+		return
+	}
+	if err := appendJournal(&o.entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: journal: %v\n", err)
+	}
+}
+
+// undoPlan is the inverse of a journal entry: the request that reverts it
+// and, so the undo is journaled (and undoable) in turn, its op and target.
+type undoPlan struct {
+	Op      string
+	Target  journalTarget
+	Summary string
+	Req     any
+}
+
+// planUndo computes the inverse of e, or an error saying why it has none.
+func planUndo(e *journalEntry) (*undoPlan, error) {
+	if e.UndoneBy != 0 {
+		return nil, fmt.Errorf("#%d was already undone by #%d", e.ID, e.UndoneBy)
+	}
+	t := e.Target
+	cannot := func(format string, a ...any) error {
+		return fmt.Errorf("#%d (%s %s) can't be undone: %s", e.ID, e.Op, t, fmt.Sprintf(format, a...))
+	}
+	if why, ok := journalOnly[e.Op]; ok {
+		return nil, cannot("%s", why)
+	}
+	if e.NoPrior != "" {
+		return nil, fmt.Errorf("#%d can't be undone: its prior state was not recorded (%s)", e.ID, e.NoPrior)
+	}
+
+	switch e.Op {
+	case "deployment deploy", "deployment set image", "deployment rollback":
+		if e.Prior == nil {
+			return nil, cannot("the deployment did not exist before; remove it with deploys deployment delete")
+		}
+		var prior api.DeploymentItem
+		if err := json.Unmarshal(e.Prior, &prior); err != nil {
+			return nil, err
+		}
+		return &undoPlan{
+			Op:      "deployment rollback",
+			Target:  t,
+			Summary: fmt.Sprintf("roll back to revision %d (%s)", prior.Revision, prior.Image),
+			Req:     &api.DeploymentRollback{Project: t.Project, Location: t.Location, Name: t.Name, Revision: int(prior.Revision)},
+		}, nil
+	case "deployment delete":
+		msg := "a deleted deployment and its revisions can't be restored"
+		var prior api.DeploymentItem
+		if e.Prior != nil && json.Unmarshal(e.Prior, &prior) == nil && prior.Image != "" {
+			msg += fmt.Sprintf("; it last ran %s (redeploy it with deploys deployment deploy)", prior.Image)
+		}
+		return nil, cannot("%s", msg)
+
+	case "envgroup create":
+		return &undoPlan{
+			Op:      "envgroup delete",
+			Target:  t,
+			Summary: "delete the env group",
+			Req:     &api.EnvGroupDelete{Project: t.Project, Name: t.Name},
+		}, nil
+	case "envgroup update", "envgroup delete":
+		if e.Prior == nil {
+			return nil, cannot("the env group did not exist before")
+		}
+		var prior api.EnvGroupItem
+		if err := json.Unmarshal(e.Prior, &prior); err != nil {
+			return nil, err
+		}
+		if e.Op == "envgroup update" {
+			return &undoPlan{
+				Op:      "envgroup update",
+				Target:  t,
+				Summary: fmt.Sprintf("restore the previous %d env values", len(prior.Env)),
+				Req:     &api.EnvGroupUpdate{Project: t.Project, Name: t.Name, Env: prior.Env},
+			}, nil
+		}
+		return &undoPlan{
+			Op:      "envgroup create",
+			Target:  t,
+			Summary: fmt.Sprintf("re-create the env group with its %d env values", len(prior.Env)),
+			Req:     &api.EnvGroupCreate{Project: t.Project, Name: t.Name, Env: prior.Env},
+		}, nil
+
+	case "role grant", "role revoke":
+		var prior api.RoleUsersItem
+		if e.Prior != nil {
+			if err := json.Unmarshal(e.Prior, &prior); err != nil {
+				return nil, err
+			}
+		}
+		had := slices.Contains(prior.Roles, t.Name)
+		if e.Op == "role grant" {
+			if had {
+				return nil, cannot("%s already had role %s, so the grant changed nothing", t.Email, t.Name)
+			}
+			return &undoPlan{
+				Op:      "role revoke",
+				Target:  t,
+				Summary: fmt.Sprintf("revoke role %s from %s", t.Name, t.Email),
+				Req:     &api.RoleRevoke{Project: t.Project, Role: t.Name, Email: t.Email},
+			}, nil
+		}
+		if !had {
+			return nil, cannot("%s did not have role %s, so the revoke changed nothing", t.Email, t.Name)
+		}
+		return &undoPlan{
+			Op:      "role grant",
+			Target:  t,
+			Summary: fmt.Sprintf("re-grant role %s to %s", t.Name, t.Email),
+			Req:     &api.RoleGrant{Project: t.Project, Role: t.Name, Email: t.Email},
+		}, nil
+
+	case "scheduler pause", "scheduler resume":
+		if e.Prior == nil {
+			return nil, cannot("the job did not exist before")
+		}
+		var prior api.SchedulerItem
+		if err := json.Unmarshal(e.Prior, &prior); err != nil {
+			return nil, err
+		}
+		if e.Op == "scheduler pause" {
+			if prior.Paused {
+				return nil, cannot("the job was already paused, so the pause changed nothing")
+			}
+			return &undoPlan{
+				Op:      "scheduler resume",
+				Target:  t,
+				Summary: "resume the job",
+				Req:     &api.SchedulerResume{Project: t.Project, Name: t.Name},
+			}, nil
+		}
+		if !prior.Paused {
+			return nil, cannot("the job was not paused, so the resume changed nothing")
+		}
+		return &undoPlan{
+			Op:      "scheduler pause",
+			Target:  t,
+			Summary: "pause the job again",
+			Req:     &api.SchedulerPause{Project: t.Project, Name: t.Name},
+		}, nil
+
+	case "waf set", "waf restore", "waf delete",
+		"cache set", "cache restore", "cache delete",
+		"transform set", "transform restore", "transform delete":
+		return planZoneUndo(e, cannot)
+	}
+	return nil, cannot("%s is not an operation undo knows how to invert", e.Op)
+}
+
+// planZoneUndo restores a zone to its prior rules, or deletes it when the
+// operation created it.
+func planZoneUndo(e *journalEntry, cannot func(string, ...any) error) (*undoPlan, error) {
+	kind := e.group()
+	t := e.Target
+	if e.Prior == nil {
+		if strings.HasSuffix(e.Op, " delete") {
+			return nil, cannot("no %s zone existed before", kind)
+		}
+		var req any
+		switch kind {
+		case "waf":
+			req = &api.WAFDelete{Project: t.Project, Location: t.Location}
+		case "cache":
+			req = &api.CacheDelete{Project: t.Project, Location: t.Location}
+		case "transform":
+			req = &api.TransformDelete{Project: t.Project, Location: t.Location}
+		}
+		return &undoPlan{Op: kind + " delete", Target: t, Summary: "delete the " + kind + " zone it created", Req: req}, nil
+	}
+
+	// the prior get result decodes into the set request, minus its read-only
+	// fields
+	var req any
+	switch kind {
+	case "waf":
+		req = &api.WAFSet{}
+	case "cache":
+		req = &api.CacheSet{}
+	case "transform":
+		req = &api.TransformSet{}
+	}
+	if err := json.Unmarshal(e.Prior, req); err != nil {
+		return nil, err
+	}
+	return &undoPlan{Op: kind + " set", Target: t, Summary: "restore the previous " + kind + " zone", Req: req}, nil
+}
+
+// applyUndo sends an undo plan's request.
+func (rn Runner) applyUndo(ctx context.Context, p *undoPlan) (any, error) {
+	switch req := p.Req.(type) {
+	case *api.DeploymentRollback:
+		return rn.API.Deployment().Rollback(ctx, req)
+	case *api.EnvGroupCreate:
+		return rn.API.EnvGroup().Create(ctx, req)
+	case *api.EnvGroupUpdate:
+		return rn.API.EnvGroup().Update(ctx, req)
+	case *api.EnvGroupDelete:
+		if err := req.Valid(); err != nil {
+			return nil, err
+		}
+		if err := rn.checkRefs("envgroup", req.Project, "", req.Name, false); err != nil {
+			return nil, err
+		}
+		return rn.API.EnvGroup().Delete(ctx, req)
+	case *api.RoleGrant:
+		return rn.API.Role().Grant(ctx, req)
+	case *api.RoleRevoke:
+		return rn.API.Role().Revoke(ctx, req)
+	case *api.SchedulerPause:
+		return rn.API.Scheduler().Pause(ctx, req)
+	case *api.SchedulerResume:
+		return rn.API.Scheduler().Resume(ctx, req)
+	case *api.WAFSet:
+		return rn.API.WAF().Set(ctx, req)
+	case *api.WAFDelete:
+		return rn.API.WAF().Delete(ctx, req)
+	case *api.CacheSet:
+		return rn.API.Cache().Set(ctx, req)
+	case *api.CacheDelete:
+		return rn.API.Cache().Delete(ctx, req)
+	case *api.TransformSet:
+		return rn.API.Transform().Set(ctx, req)
+	case *api.TransformDelete:
+		return rn.API.Transform().Delete(ctx, req)
+	}
+	return nil, fmt.Errorf("undo: unsupported request %T", p.Req)
+}
+
+// historyItem is the displayed form of a journal entry; the recorded request
+// and prior state (which can hold env values) stay in the file.
+type historyItem struct {
+	ID       int           `json:"id" yaml:"id"`
+	Time     time.Time     `json:"time" yaml:"time"`
+	Actor    string        `json:"actor" yaml:"actor"`
+	Endpoint string        `json:"endpoint" yaml:"endpoint"`
+	Op       string        `json:"op" yaml:"op"`
+	Target   journalTarget `json:"target" yaml:"target"`
+	Undo     string        `json:"undo" yaml:"undo"`
+}
+
+type historyList struct {
+	Items []historyItem `json:"items" yaml:"items"`
+}
+
+func (l *historyList) Table() [][]string {
+	table := [][]string{
+		{"ID", "TIME", "ACTOR", "ENDPOINT", "OP", "RESOURCE", "UNDO"},
+	}
+	for _, x := range l.Items {
+		table = append(table, []string{
+			strconv.Itoa(x.ID),
+			x.Time.Local().Format(time.RFC3339),
+			x.Actor,
+			x.Endpoint,
+			x.Op,
+			x.Target.String(),
+			x.Undo,
+		})
+	}
+	return table
+}
+
+// undoStatus summarizes whether `deploys undo` can revert e.
+func undoStatus(e *journalEntry) string {
+	var s string
+	switch {
+	case e.UndoneBy != 0:
+		s = "undone by #" + strconv.Itoa(e.UndoneBy)
+	default:
+		if _, err := planUndo(e); err != nil {
+			s = "no"
+		} else {
+			s = "yes"
+		}
+	}
+	if e.Undoes != 0 {
+		s += " (undo of #" + strconv.Itoa(e.Undoes) + ")"
+	}
+	return s
+}
+
+func newHistoryItem(e *journalEntry) historyItem {
+	return historyItem{
+		ID:       e.ID,
+		Time:     e.Time,
+		Actor:    e.Actor,
+		Endpoint: e.Endpoint,
+		Op:       e.Op,
+		Target:   e.Target,
+		Undo:     undoStatus(e),
+	}
+}
+
+// history handles `deploys history`: the journal, newest first.
+func (rn Runner) history(args ...string) error {
+	_, run := rn.historyLeaf()
+	return run(args)
+}
+
+func (rn *Runner) historyLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("history", writeHistoryUsage)
+	var (
+		limit   int
+		project string
+	)
+	f.IntVar(&limit, "limit", 20, "entries to show (0 shows all)")
+	f.StringVar(&project, "project", "", "only operations on this project")
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeHistoryUsage(rn.output())
+			return nil
+		}
+		f.Parse(args)
+
+		list, err := loadJournal()
+		if err != nil {
+			return err
+		}
+		res := historyList{Items: []historyItem{}}
+		for i := len(list) - 1; i >= 0; i-- {
+			if limit > 0 && len(res.Items) == limit {
+				break
+			}
+			if project != "" && list[i].Target.Project != project {
+				continue
+			}
+			res.Items = append(res.Items, newHistoryItem(&list[i]))
+		}
+		return rn.print(&res)
+	}
+}
+
+// undo handles `deploys undo [id]`: revert a journaled operation (by default
+// the newest one that is neither undone nor itself an undo) by applying its
+// inverse, which is journaled in turn.
+func (rn Runner) undo(args ...string) error {
+	_, run := rn.undoLeaf()
+	return run(args)
+}
+
+func (rn *Runner) undoLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("undo", writeUndoUsage)
+	var (
+		id     int
+		dryRun bool
+	)
+	f.BoolVar(&dryRun, "dry-run", false, "print what undo would do without applying it")
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeUndoUsage(rn.output())
+			return nil
+		}
+		if len(args) > 0 && !isFlag(args[0]) {
+			n, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+			if err != nil {
+				return fmt.Errorf("undo: invalid id %q", args[0])
+			}
+			id, args = n, args[1:]
+		}
+		f.Parse(args)
+		if id == 0 && f.Arg(0) != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(f.Arg(0), "#"))
+			if err != nil {
+				return fmt.Errorf("undo: invalid id %q", f.Arg(0))
+			}
+			id = n
+		}
+
+		list, err := loadJournal()
+		if err != nil {
+			return err
+		}
+		var e *journalEntry
+		for i := len(list) - 1; i >= 0; i-- {
+			if (id != 0 && list[i].ID == id) || (id == 0 && list[i].UndoneBy == 0 && list[i].Undoes == 0) {
+				e = &list[i]
+				break
+			}
+		}
+		if e == nil {
+			if id != 0 {
+				return fmt.Errorf("undo: no journal entry #%d (see deploys history)", id)
+			}
+			return fmt.Errorf("undo: nothing to undo")
+		}
+
+		if cur := rn.journalEndpoint(); e.Endpoint != cur {
+			return fmt.Errorf("undo: #%d was made against %s, but commands now use %s (switch back with DEPLOYS_ENDPOINT or a context to undo it)", e.ID, e.Endpoint, cur)
+		}
+		p, err := planUndo(e)
+		if err != nil {
+			return fmt.Errorf("undo: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "undo #%d (%s %s): %s\n", e.ID, e.Op, e.Target, p.Summary)
+		if dryRun {
+			return nil
+		}
+
+		// an undo that replaces or deletes a zone snapshots it first, like set
+		kind, _, _ := strings.Cut(p.Op, " ")
+		if z, ok := rn.zoneSnapshotsFor(kind); ok {
+			if err := z.snapshot(p.Target.Project, p.Target.Location, defaultSnapshotKeep); err != nil {
+				return fmt.Errorf("undo #%d: %w", e.ID, err)
+			}
+		}
+		o := rn.journalBegin(p.Op, p.Target, p.Req)
+		o.entry.Undoes = e.ID
+		_, err = rn.applyUndo(rn.ctx(), p)
+		o.done(err)
+		if err != nil {
+			return fmt.Errorf("undo #%d: %w", e.ID, err)
+		}
+		return rn.print(&historyList{Items: []historyItem{newHistoryItem(&o.entry)}})
+	}
+}
+
+// standaloneFlagSet is the flag set of a top-level command outside the
+// registry (history, undo), with its usage banner as -h.
+func (rn *Runner) standaloneFlagSet(name string, usage func(w io.Writer)) *flag.FlagSet {
+	f := flag.NewFlagSet("deploys "+name, flag.ExitOnError)
+	f.SetOutput(rn.output())
+	rn.registerFlags(f)
+	f.Usage = func() {
+		usage(rn.output())
+		fmt.Fprint(rn.output(), "\nFlags:\n")
+		f.PrintDefaults()
+	}
+	return f
+}
+
+func writeHistoryUsage(w io.Writer) {
+	fmt.Fprint(w, "history — list recent operations from the local journal\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys history [-limit n] [-project p]\n")
+}
+
+func writeUndoUsage(w io.Writer) {
+	fmt.Fprint(w, "undo — revert a journaled operation (default: the newest one not yet undone that is not itself an undo)\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys undo [id] [-dry-run]\n")
+}
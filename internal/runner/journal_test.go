@@ -0,0 +1,477 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"go/ast"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+// journalAPI keeps env groups and role bindings in memory so commands can be
+// run, journaled, and undone end to end.
+type journalAPI struct {
+	api.Interface
+	groups map[string]map[string]string
+	roles  map[string][]string // email -> roles
+	waf    *api.WAFItem
+}
+
+func (a *journalAPI) EnvGroup() api.EnvGroup { return journalEnvGroup{a: a} }
+func (a *journalAPI) Role() api.Role         { return journalRole{a: a} }
+func (a *journalAPI) WAF() api.WAF           { return journalWAF{a: a} }
+
+type journalEnvGroup struct {
+	api.EnvGroup
+	a *journalAPI
+}
+
+func (s journalEnvGroup) Get(_ context.Context, m *api.EnvGroupGet) (*api.EnvGroupItem, error) {
+	env, ok := s.a.groups[m.Name]
+	if !ok {
+		return nil, api.ErrEnvGroupNotFound
+	}
+	return &api.EnvGroupItem{Project: m.Project, Name: m.Name, Env: maps.Clone(env)}, nil
+}
+
+func (s journalEnvGroup) Create(_ context.Context, m *api.EnvGroupCreate) (*api.Empty, error) {
+	s.a.groups[m.Name] = maps.Clone(m.Env)
+	return &api.Empty{}, nil
+}
+
+func (s journalEnvGroup) Update(_ context.Context, m *api.EnvGroupUpdate) (*api.Empty, error) {
+	s.a.groups[m.Name] = maps.Clone(m.Env)
+	return &api.Empty{}, nil
+}
+
+func (s journalEnvGroup) Delete(_ context.Context, m *api.EnvGroupDelete) (*api.Empty, error) {
+	delete(s.a.groups, m.Name)
+	return &api.Empty{}, nil
+}
+
+type journalRole struct {
+	api.Role
+	a *journalAPI
+}
+
+func (s journalRole) Users(context.Context, *api.RoleUsers) (*api.RoleUsersResult, error) {
+	var res api.RoleUsersResult
+	for email, roles := range s.a.roles {
+		res.Items = append(res.Items, &api.RoleUsersItem{Email: email, Roles: slices.Clone(roles)})
+	}
+	return &res, nil
+}
+
+func (s journalRole) Grant(_ context.Context, m *api.RoleGrant) (*api.Empty, error) {
+	s.a.roles[m.Email] = append(s.a.roles[m.Email], m.Role)
+	return &api.Empty{}, nil
+}
+
+func (s journalRole) Revoke(_ context.Context, m *api.RoleRevoke) (*api.Empty, error) {
+	s.a.roles[m.Email] = slices.DeleteFunc(s.a.roles[m.Email], func(r string) bool { return r == m.Role })
+	return &api.Empty{}, nil
+}
+
+type journalWAF struct {
+	api.WAF
+	a *journalAPI
+}
+
+func (s journalWAF) Get(context.Context, *api.WAFGet) (*api.WAFItem, error) {
+	if s.a.waf == nil {
+		return nil, api.ErrWAFZoneNotFound
+	}
+	x := *s.a.waf
+	return &x, nil
+}
+
+func (s journalWAF) Set(_ context.Context, m *api.WAFSet) (*api.Empty, error) {
+	s.a.waf = &api.WAFItem{Project: m.Project, Location: m.Location, Description: m.Description}
+	return &api.Empty{}, nil
+}
+
+func (s journalWAF) Delete(context.Context, *api.WAFDelete) (*api.Empty, error) {
+	s.a.waf = nil
+	return &api.Empty{}, nil
+}
+
+func newJournalRunner(t *testing.T) (Runner, *journalAPI) {
+	t.Helper()
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Setenv("DEPLOYS_TOKEN", "tok")
+	a := &journalAPI{
+		groups: map[string]map[string]string{"shared": {"A": "1"}},
+		roles:  map[string][]string{"bob@example.com": {"viewer"}},
+	}
+	return Runner{Output: tempOut(t), API: a}, a
+}
+
+func TestJournalUndoEnvGroup(t *testing.T) {
+	rn, a := newJournalRunner(t)
+
+	if err := rn.Run("envgroup", "update", "-project", "acme", "-name", "shared", "-env", "A=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("envgroup", "delete", "-project", "acme", "-name", "shared", "-force"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.groups["shared"]; ok {
+		t.Fatal("group not deleted")
+	}
+
+	// the newest entry (the delete) is undone first: the group comes back with
+	// the values it had when deleted
+	if err := rn.Run("undo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.groups["shared"]; got["A"] != "2" {
+		t.Fatalf("after undoing delete: %v", got)
+	}
+	// then the update, by id
+	if err := rn.Run("undo", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.groups["shared"]; got["A"] != "1" {
+		t.Fatalf("after undoing update: %v", got)
+	}
+
+	list, err := loadJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 4 {
+		t.Fatalf("journal has %d entries, want 4", len(list))
+	}
+	if list[0].UndoneBy != 4 || list[1].UndoneBy != 3 || list[2].Undoes != 2 || list[3].Undoes != 1 {
+		t.Errorf("undo links: %+v", list)
+	}
+	if list[0].Actor != "DEPLOYS_TOKEN" || list[0].Endpoint == "" {
+		t.Errorf("actor/endpoint: %q %q", list[0].Actor, list[0].Endpoint)
+	}
+
+	err = rn.Run("undo", "1")
+	if err == nil || !strings.Contains(err.Error(), "already undone by #4") {
+		t.Errorf("undo twice: %v", err)
+	}
+}
+
+func TestJournalUndoRole(t *testing.T) {
+	rn, a := newJournalRunner(t)
+
+	if err := rn.Run("role", "grant", "-project", "acme", "-role", "admin", "-email", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("role", "revoke", "-project", "acme", "-role", "viewer", "-email", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("undo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("undo", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.roles["bob@example.com"]; !slices.Equal(got, []string{"viewer"}) {
+		t.Errorf("roles after undo = %v, want [viewer]", got)
+	}
+
+	// granting a role the user already holds changes nothing to undo
+	if err := rn.Run("role", "grant", "-project", "acme", "-role", "viewer", "-email", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	err := rn.Run("undo")
+	if err == nil || !strings.Contains(err.Error(), "can't be undone") {
+		t.Errorf("undo no-op grant: %v", err)
+	}
+}
+
+func TestJournalUndoZoneSnapshots(t *testing.T) {
+	rn, a := newJournalRunner(t)
+	a.waf = &api.WAFItem{Project: "acme", Location: "loc", Description: "old"}
+	if err := rn.Run("waf", "delete", "-project", "acme", "-location", "loc"); err != nil {
+		t.Fatal(err)
+	}
+	// the zone is set again before the delete is undone; the undo replaces it,
+	// so it is snapshotted first like a set
+	a.waf = &api.WAFItem{Project: "acme", Location: "loc", Description: "new"}
+	if err := rn.Run("undo"); err != nil {
+		t.Fatal(err)
+	}
+	if a.waf == nil || a.waf.Description != "old" {
+		t.Fatalf("zone after undo = %+v", a.waf)
+	}
+	list, err := listZoneSnapshots("waf", "acme", "loc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got api.WAFItem
+	if len(list) != 1 || readZoneSnapshot("waf", "acme", "loc", "latest", &got) != nil || got.Description != "new" {
+		t.Errorf("snapshots = %+v (latest %+v), want the replaced zone", list, got)
+	}
+}
+
+func TestJournalUndoEndpointMismatch(t *testing.T) {
+	rn, _ := newJournalRunner(t)
+	if err := rn.Run("envgroup", "create", "-project", "acme", "-name", "new", "-env", "K=V"); err != nil {
+		t.Fatal(err)
+	}
+	rn.Endpoint = "https://staging.example.com/"
+	err := rn.Run("undo")
+	if err == nil || !strings.Contains(err.Error(), "was made against") {
+		t.Errorf("undo across endpoints: %v", err)
+	}
+}
+
+func TestJournalUndoSkipsUndos(t *testing.T) {
+	rn, a := newJournalRunner(t)
+	if err := rn.Run("role", "grant", "-project", "acme", "-role", "admin", "-email", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Run("undo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest entry is the undo itself, which a bare undo leaves alone
+	err := rn.Run("undo")
+	if err == nil || !strings.Contains(err.Error(), "nothing to undo") {
+		t.Errorf("undo after undo: %v", err)
+	}
+	if got := a.roles["bob@example.com"]; !slices.Equal(got, []string{"viewer"}) {
+		t.Errorf("roles = %v, want [viewer]", got)
+	}
+}
+
+func TestAppendJournalConcurrent(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+
+	const n = 8
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := appendJournal(&journalEntry{Op: "envgroup create"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	list, err := loadJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != n {
+		t.Fatalf("journal has %d entries, want %d", len(list), n)
+	}
+	for i, e := range list {
+		if e.ID != i+1 {
+			t.Errorf("entry %d has id %d", i, e.ID)
+		}
+	}
+}
+
+func TestPlanUndo(t *testing.T) {
+	raw := func(v any) json.RawMessage {
+		b, _ := json.Marshal(v)
+		return b
+	}
+	target := journalTarget{Project: "acme", Location: "loc", Name: "web"}
+
+	cases := []struct {
+		name    string
+		entry   journalEntry
+		wantReq any
+		wantErr string
+	}{
+		{
+			name:    "redeploy previous revision",
+			entry:   journalEntry{Op: "deployment deploy", Target: target, Prior: raw(&api.DeploymentItem{Revision: 7, Image: "web:1"})},
+			wantReq: &api.DeploymentRollback{Project: "acme", Location: "loc", Name: "web", Revision: 7},
+		},
+		{
+			name:    "first deploy",
+			entry:   journalEntry{Op: "deployment deploy", Target: target},
+			wantErr: "did not exist before",
+		},
+		{
+			name:    "deployment delete",
+			entry:   journalEntry{Op: "deployment delete", Target: target, Prior: raw(&api.DeploymentItem{Image: "web:1"})},
+			wantErr: "it last ran web:1",
+		},
+		{
+			name:    "restore zone",
+			entry:   journalEntry{Op: "waf set", Target: target, Prior: raw(&api.WAFItem{Project: "acme", Location: "loc", Description: "old"})},
+			wantReq: &api.WAFSet{Project: "acme", Location: "loc", Description: "old"},
+		},
+		{
+			name:    "zone created by set",
+			entry:   journalEntry{Op: "cache set", Target: target},
+			wantReq: &api.CacheDelete{Project: "acme", Location: "loc"},
+		},
+		{
+			name:    "resume paused job",
+			entry:   journalEntry{Op: "scheduler pause", Target: journalTarget{Project: "acme", Name: "nightly"}, Prior: raw(&api.SchedulerItem{Name: "nightly"})},
+			wantReq: &api.SchedulerResume{Project: "acme", Name: "nightly"},
+		},
+		{
+			name:    "resume of running job",
+			entry:   journalEntry{Op: "scheduler resume", Target: target, Prior: raw(&api.SchedulerItem{Name: "web"})},
+			wantErr: "changed nothing",
+		},
+		{
+			name:    "recorded only",
+			entry:   journalEntry{Op: "disk delete", Target: target},
+			wantErr: "can't be undone: a deleted disk",
+		},
+		{
+			name:    "prior not recorded",
+			entry:   journalEntry{Op: "transform set", Target: target, NoPrior: "forbidden"},
+			wantErr: "prior state was not recorded",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := planUndo(&tc.entry)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(p.Req)
+			want, _ := json.Marshal(tc.wantReq)
+			if string(got) != string(want) {
+				t.Errorf("req = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// notJournaled are the registry leaves that change nothing the api holds, so
+// the journal has nothing to record for them.
+var notJournaled = map[string]string{
+	"auth login":         "local credentials",
+	"auth logout":        "local credentials",
+	"auth switch":        "local credentials",
+	"config set":         "local config",
+	"config unset":       "local config",
+	"context create":     "local config",
+	"context update":     "local config",
+	"context use":        "local config",
+	"context delete":     "local config",
+	"waf init":           "writes a local spec file",
+	"cache init":         "writes a local spec file",
+	"collector push":     "idempotent usage upsert with a location's collector token",
+	"dropbox upload-url": "issues an upload url; nothing changes until it is used",
+	"site publish":       "uploads a release that nothing serves until it is deployed",
+	"notification test":  "sends a test event; the channel is unchanged",
+	"notification pull":  "consumes the channel's own event cursor",
+	"mcp serve":          "the server's tools are not cli commands",
+}
+
+// readOnlySubs are the verbs that only read.
+var readOnlySubs = []string{
+	"get", "list", "show", "current", "status", "token", "authorized", "permissions",
+	"list-tokens", "report", "skus", "project", "invoices", "invoice", "list-members",
+	"usage", "users", "revisions", "metrics", "limitmetrics", "logs", "logs-history",
+	"check", "export", "match", "history", "tags", "manifests", "storage",
+	"deliveries", "location", "downloadinvoice", "downloadreceipt",
+}
+
+// journaledAs maps the leaves that journal under another command's op.
+var journaledAs = map[string]string{
+	"deployment set": "deployment set image",
+	"site deploy":    "deployment deploy",
+	"site preview":   "deployment deploy",
+}
+
+// journaledOps scans the package source for the ops its commands journal:
+// journalBegin literals, plus the zone set/restore ops journaled by
+// zoneSnapshots.apply under the group of the leaf builder that calls it.
+func journaledOps(t *testing.T) map[string]bool {
+	t.Helper()
+	files := parseRunner(t)
+	leaves := leafGroups(files)
+	ops := map[string]bool{}
+	for _, file := range files {
+		for _, d := range file.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			group := leaves[fd.Name.Name]
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				var op string
+				switch sel.Sel.Name {
+				case "journalBegin":
+					if op := stringLit(call.Args[0]); op != "" {
+						ops[op] = true
+					}
+				case "apply":
+					if len(call.Args) > 1 {
+						op = stringLit(call.Args[1])
+					}
+				case "restore":
+					op = "restore"
+				}
+				if group != "" && op != "" {
+					ops[group+" "+op] = true
+				}
+				return true
+			})
+		}
+	}
+	return ops
+}
+
+// TestMutatingCommandsJournaled walks the command registry: every leaf that
+// changes api state must be journaled, and every journaled op must either be
+// one undo can invert or say in journalOnly why it can't.
+func TestMutatingCommandsJournaled(t *testing.T) {
+	ops := journaledOps(t)
+	for _, c := range commands {
+		for _, s := range c.subs {
+			leaf := c.name + " " + s.name
+			if _, ok := notJournaled[leaf]; ok || slices.Contains(readOnlySubs, s.name) {
+				continue
+			}
+			op := leaf
+			if as, ok := journaledAs[leaf]; ok {
+				op = as
+			}
+			if !ops[op] {
+				t.Errorf("%s changes state but is not journaled", leaf)
+			}
+		}
+	}
+	for op := range journalOnly {
+		if !ops[op] {
+			t.Errorf("journalOnly lists %s, which no command journals", op)
+		}
+	}
+	for op := range ops {
+		if _, ok := journalOnly[op]; ok {
+			continue
+		}
+		_, err := planUndo(&journalEntry{ID: 1, Op: op})
+		if err != nil && strings.Contains(err.Error(), "not an operation undo knows") {
+			t.Errorf("%s is journaled but neither undoable nor in journalOnly", op)
+		}
+	}
+}
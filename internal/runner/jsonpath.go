@@ -0,0 +1,565 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed kubectl-style JSONPath template: literal text mixed
+// with {expressions}, {"quoted literals"}, and {range expr}...{end} loops. An
+// expression is a path of .field, ['field'], [n], [a:b], [*] or .*, ..field
+// (recursive descent), and [?(@.field op value)] filters. Like kubectl, a
+// missing field yields nothing rather than an error, and the values an
+// expression matches are printed space-separated.
+type jsonPath struct {
+	nodes []jpNode
+}
+
+// jpNode is a jpText, a jpExpr, or a jpRange.
+type jpNode any
+
+type jpText string
+
+type jpExpr struct {
+	root  bool // starts at $ (the whole value) instead of the current one
+	steps []jpStep
+}
+
+type jpRange struct {
+	expr jpExpr
+	body []jpNode
+}
+
+type jpStepKind int
+
+const (
+	jpField jpStepKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpRecurse // name "" matches every descendant
+	jpFilter
+)
+
+type jpStep struct {
+	kind       jpStepKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jpCond
+}
+
+// jpCond is a filter condition: left op right, or just left (exists).
+type jpCond struct {
+	left  jpExpr
+	op    string
+	right any     // a literal, when rexpr is nil
+	rexpr *jpExpr // a path on the right-hand side
+}
+
+// parseJSONPath parses a template. One without any {...} is taken as a single
+// expression, so jsonpath=.items[0].name works too.
+func parseJSONPath(s string) (*jsonPath, error) {
+	if !strings.Contains(s, "{") {
+		s = "{" + s + "}"
+	}
+	var (
+		stack  = [][]jpNode{nil}
+		ranges []jpExpr
+	)
+	add := func(n jpNode) {
+		stack[len(stack)-1] = append(stack[len(stack)-1], n)
+	}
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			add(jpText(s))
+			break
+		}
+		if i > 0 {
+			add(jpText(s[:i]))
+		}
+		j := jpClosing(s, i, '{', '}')
+		if j < 0 {
+			return nil, fmt.Errorf("jsonpath: unclosed { in %q", s)
+		}
+		action := strings.TrimSpace(s[i+1 : j])
+		s = s[j+1:]
+		switch {
+		case action == "end":
+			if len(ranges) == 0 {
+				return nil, fmt.Errorf("jsonpath: {end} without {range}")
+			}
+			body := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			add(jpRange{expr: ranges[len(ranges)-1], body: body})
+			ranges = ranges[:len(ranges)-1]
+		case strings.HasPrefix(action, "range "):
+			e, err := parseJPExpr(strings.TrimSpace(strings.TrimPrefix(action, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, e)
+			stack = append(stack, nil)
+		case strings.HasPrefix(action, `"`) || strings.HasPrefix(action, "'"):
+			lit, err := jpUnquote(action)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath: bad literal %s", action)
+			}
+			add(jpText(lit))
+		default:
+			e, err := parseJPExpr(action)
+			if err != nil {
+				return nil, err
+			}
+			add(e)
+		}
+	}
+	if len(ranges) > 0 {
+		return nil, fmt.Errorf("jsonpath: {range} without {end}")
+	}
+	return &jsonPath{nodes: stack[0]}, nil
+}
+
+// jpClosing returns the index of the close that matches the open at s[i],
+// skipping quoted text, or -1.
+func jpClosing(s string, i int, open, close byte) int {
+	depth := 0
+	var quote byte
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == open:
+			depth++
+		case c == close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// jpUnquote reads a "double" (Go escapes) or 'single' (verbatim) quoted string.
+func jpUnquote(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+func parseJPExpr(s string) (jpExpr, error) {
+	var e jpExpr
+	orig := s
+	switch {
+	case strings.HasPrefix(s, "$"):
+		e.root = true
+		s = s[1:]
+	case strings.HasPrefix(s, "@"):
+		s = s[1:]
+	}
+	bad := func(why string) (jpExpr, error) {
+		return jpExpr{}, fmt.Errorf("jsonpath: %s in %q", why, orig)
+	}
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			s = s[2:]
+			if strings.HasPrefix(s, "*") {
+				e.steps = append(e.steps, jpStep{kind: jpRecurse})
+				s = s[1:]
+				continue
+			}
+			name, rest := jpName(s)
+			if name == "" {
+				return bad("missing field after ..")
+			}
+			e.steps = append(e.steps, jpStep{kind: jpRecurse, name: name})
+			s = rest
+		case s[0] == '.':
+			s = s[1:]
+			if strings.HasPrefix(s, "*") {
+				e.steps = append(e.steps, jpStep{kind: jpWildcard})
+				s = s[1:]
+				continue
+			}
+			name, rest := jpName(s)
+			if name == "" {
+				if s == "" {
+					continue // a bare "." is the current value
+				}
+				return bad("missing field after .")
+			}
+			e.steps = append(e.steps, jpStep{kind: jpField, name: name})
+			s = rest
+		case s[0] == '[':
+			j := jpClosing(s, 0, '[', ']')
+			if j < 0 {
+				return bad("unclosed [")
+			}
+			st, err := parseJPBracket(strings.TrimSpace(s[1:j]))
+			if err != nil {
+				return bad(err.Error())
+			}
+			e.steps = append(e.steps, st)
+			s = s[j+1:]
+		default:
+			return bad(fmt.Sprintf("unexpected %q", s))
+		}
+	}
+	return e, nil
+}
+
+// jpName reads a field name up to the next . or [ (or blank, which no
+// field name holds).
+func jpName(s string) (name, rest string) {
+	i := strings.IndexAny(s, ".[ \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func parseJPBracket(s string) (jpStep, error) {
+	switch {
+	case s == "*":
+		return jpStep{kind: jpWildcard}, nil
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		c, err := parseJPCond(strings.TrimSpace(s[2 : len(s)-1]))
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpFilter, filter: c}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := jpUnquote(s)
+		if err != nil {
+			return jpStep{}, fmt.Errorf("bad field name %s", s)
+		}
+		return jpStep{kind: jpField, name: name}, nil
+	case strings.Contains(s, ":"):
+		a, b, _ := strings.Cut(s, ":")
+		st := jpStep{kind: jpSlice}
+		for _, p := range []struct {
+			s   string
+			dst **int
+		}{{a, &st.start}, {b, &st.end}} {
+			if p.s = strings.TrimSpace(p.s); p.s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(p.s)
+			if err != nil {
+				return jpStep{}, fmt.Errorf("bad slice [%s]", s)
+			}
+			*p.dst = &n
+		}
+		return st, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return jpStep{}, fmt.Errorf("bad index [%s]", s)
+	}
+	return jpStep{kind: jpIndex, index: n}, nil
+}
+
+var jpOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseJPCond(s string) (*jpCond, error) {
+	// find the operator outside quotes
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		for _, op := range jpOps {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			left, err := parseJPExpr(strings.TrimSpace(s[:i]))
+			if err != nil {
+				return nil, err
+			}
+			cond := &jpCond{left: left, op: op}
+			right := strings.TrimSpace(s[i+len(op):])
+			switch {
+			case strings.HasPrefix(right, "@") || strings.HasPrefix(right, "$"):
+				r, err := parseJPExpr(right)
+				if err != nil {
+					return nil, err
+				}
+				cond.rexpr = &r
+			case strings.HasPrefix(right, "'") || strings.HasPrefix(right, `"`):
+				if cond.right, err = jpUnquote(right); err != nil {
+					return nil, fmt.Errorf("bad literal %s", right)
+				}
+			case right == "true" || right == "false":
+				cond.right = right == "true"
+			case right == "null":
+			default:
+				f, err := strconv.ParseFloat(right, 64)
+				if err != nil {
+					return nil, fmt.Errorf("bad literal %s", right)
+				}
+				cond.right = f
+			}
+			return cond, nil
+		}
+	}
+	left, err := parseJPExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	return &jpCond{left: left}, nil
+}
+
+// execute writes the template evaluated against root, a generic JSON value
+// (see jsonData).
+func (p *jsonPath) execute(w io.Writer, root any) error {
+	return execJPNodes(w, p.nodes, root, root)
+}
+
+func execJPNodes(w io.Writer, nodes []jpNode, root, cur any) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case jpText:
+			if _, err := io.WriteString(w, string(n)); err != nil {
+				return err
+			}
+		case jpExpr:
+			vals := n.eval(root, cur)
+			strs := make([]string, len(vals))
+			for i, v := range vals {
+				s, err := jpFormat(v)
+				if err != nil {
+					return err
+				}
+				strs[i] = s
+			}
+			if _, err := io.WriteString(w, strings.Join(strs, " ")); err != nil {
+				return err
+			}
+		case jpRange:
+			for _, v := range n.expr.eval(root, cur) {
+				if err := execJPNodes(w, n.body, root, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e jpExpr) eval(root, cur any) []any {
+	vals := []any{cur}
+	if e.root {
+		vals = []any{root}
+	}
+	for _, st := range e.steps {
+		vals = st.apply(root, vals)
+	}
+	return vals
+}
+
+func (st jpStep) apply(root any, vals []any) []any {
+	var res []any
+	for _, v := range vals {
+		switch st.kind {
+		case jpField:
+			if m, ok := v.(map[string]any); ok {
+				if x, ok := m[st.name]; ok {
+					res = append(res, x)
+				}
+			}
+		case jpWildcard:
+			res = append(res, jpChildren(v)...)
+		case jpIndex:
+			if a, ok := v.([]any); ok {
+				i := st.index
+				if i < 0 {
+					i += len(a)
+				}
+				if i >= 0 && i < len(a) {
+					res = append(res, a[i])
+				}
+			}
+		case jpSlice:
+			if a, ok := v.([]any); ok {
+				start, end := jpBound(st.start, 0, len(a)), jpBound(st.end, len(a), len(a))
+				if start < end {
+					res = append(res, a[start:end]...)
+				}
+			}
+		case jpRecurse:
+			res = append(res, jpDescend(v, st.name)...)
+		case jpFilter:
+			if a, ok := v.([]any); ok {
+				for _, x := range a {
+					if st.filter.match(root, x) {
+						res = append(res, x)
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+// jpBound resolves a slice bound: default when unset, negative from the end,
+// clamped to [0, n].
+func jpBound(p *int, def, n int) int {
+	if p == nil {
+		return def
+	}
+	i := *p
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// jpChildren returns an array's elements or an object's values (in key order).
+func jpChildren(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		res := make([]any, len(keys))
+		for i, k := range keys {
+			res[i] = v[k]
+		}
+		return res
+	}
+	return nil
+}
+
+// jpDescend returns, at every depth below v, the values of field name (or
+// every value, for an empty name).
+func jpDescend(v any, name string) []any {
+	var res []any
+	if m, ok := v.(map[string]any); ok && name != "" {
+		if x, ok := m[name]; ok {
+			res = append(res, x)
+		}
+	}
+	for _, c := range jpChildren(v) {
+		if name == "" {
+			res = append(res, c)
+		}
+		res = append(res, jpDescend(c, name)...)
+	}
+	return res
+}
+
+func (c *jpCond) match(root, cur any) bool {
+	left := c.left.eval(root, cur)
+	if c.op == "" {
+		return len(left) > 0 && left[0] != nil && left[0] != false
+	}
+	if len(left) == 0 {
+		return false
+	}
+	right := c.right
+	if c.rexpr != nil {
+		r := c.rexpr.eval(root, cur)
+		if len(r) == 0 {
+			return false
+		}
+		right = r[0]
+	}
+	l := left[0]
+	if lf, ok := jpNumber(l); ok {
+		if rf, ok := jpNumber(right); ok {
+			return jpCompare(c.op, cmpFloat(lf, rf))
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := right.(string); ok {
+			return jpCompare(c.op, strings.Compare(ls, rs))
+		}
+	}
+	switch c.op {
+	case "==":
+		return l == right
+	case "!=":
+		return l != right
+	}
+	return false
+}
+
+func jpNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func jpCompare(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// jpFormat prints a matched value: strings and scalars as-is, objects and
+// arrays as compact JSON.
+func jpFormat(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+func TestJSONPath(t *testing.T) {
+	data, err := jsonData(map[string]any{
+		"project": "acme",
+		"items": []map[string]any{
+			{"name": "web", "revision": 12, "url": "https://web.acme.app", "tags": []string{"a", "b"}},
+			{"name": "worker", "revision": 3, "paused": true},
+			{"name": "api", "revision": 7, "url": "https://api.acme.app"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct{ expr, want string }{
+		{"{.project}", "acme"},
+		{".project", "acme"},
+		{"{$.project}", "acme"},
+		{"{.items[*].name}", "web worker api"},
+		{"{.items[0].revision}", "12"},
+		{"{.items[-1].name}", "api"},
+		{"{.items[1:].name}", "worker api"},
+		{"{.items[:2].name}", "web worker"},
+		{"{.items[0].tags}", `["a","b"]`},
+		{"{.items[0]['url']}", "https://web.acme.app"},
+		{"{.items[?(@.name=='api')].url}", "https://api.acme.app"},
+		{"{.items[?(@.revision > 5)].name}", "web api"},
+		{"{.items[?(@.paused)].name}", "worker"},
+		{`{.items[?(@.name != "web")].name}`, "worker api"},
+		{"{..url}", "https://web.acme.app https://api.acme.app"},
+		{"{.missing}", ""},
+		{`{range .items[*]}{.name}={.revision}{"\n"}{end}`, "web=12\nworker=3\napi=7\n"},
+		{"project {.project}: {.items[0].name}", "project acme: web"},
+	}
+	for _, c := range cases {
+		p, err := parseJSONPath(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		var b strings.Builder
+		if err := p.execute(&b, data); err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if b.String() != c.want {
+			t.Errorf("%s = %q; want %q", c.expr, b.String(), c.want)
+		}
+	}
+
+	for _, bad := range []string{"{.items[", "{range .items[*]}{.name}", "{end}", "{.items[x]}", "{.a b}"} {
+		if _, err := parseJSONPath(bad); err == nil {
+			t.Errorf("%s: want parse error", bad)
+		}
+	}
+}
+
+// template= and jsonpath= see the same json-named fields -ojson prints.
+func TestPrint_Expr(t *testing.T) {
+	res := &api.DeploymentListResult{Items: []*api.DeploymentListItem{
+		{Name: "web", Revision: 12, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Name: "worker", Revision: 3},
+	}}
+	cases := map[string]string{
+		`template={{range .items}}{{.name}} {{.revision}}{{"\n"}}{{end}}`: "web 12\nworker 3\n",
+		`go-template={{(index .items 0).createdAt}}`:                      "2026-01-02T03:04:05Z",
+		`jsonpath={.items[*].name}`:                                       "web worker",
+	}
+	for mode, want := range cases {
+		out := tempOut(t)
+		if err := (Runner{Output: out, OutputMode: mode}).print(res); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if got := readOut(t, out); got != want {
+			t.Errorf("%s = %q; want %q", mode, got, want)
+		}
+	}
+	for _, mode := range []string{"template={{.items", "jsonpath={.items["} {
+		if err := (Runner{Output: tempOut(t), OutputMode: mode}).print(res); err == nil {
+			t.Errorf("%s: want error", mode)
+		}
+	}
+}
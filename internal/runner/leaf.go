@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+)
+
+// leafRun runs a leaf whose flags are declared: it parses args (the words
+// after the subcommand) and returns what to print, or nil when the leaf
+// printed its own output.
+type leafRun func(args []string) (any, error)
+
+// leafBuilder declares the flags of a group's sub on f and returns its run, or
+// nil for a sub the group does not have. Declaring runs nothing, so completion
+// and the generated docs get the real flag sets, defaults and usage strings
+// included, from the same code the commands parse with.
+type leafBuilder func(f *leafFlagSet, sub string) leafRun
+
+// runGroup runs `deploys <group> <sub> [flags]` through the group's builder.
+func (rn *Runner) runGroup(group string, args []string, leaf leafBuilder) error {
+	if len(args) == 0 || IsHelpArg(args[0]) {
+		return rn.groupUsage(group)
+	}
+	f := rn.subFlagSet(group, args[0])
+	run := leaf(f, args[0])
+	if run == nil {
+		return rn.unknownSub(group, args[0])
+	}
+	resp, err := run(args[1:])
+	if err != nil || resp == nil {
+		return err
+	}
+	return rn.print(resp)
+}
+
+// printed adapts the run of a leaf that prints its own output.
+func printed(run func(args []string) error) leafRun {
+	return func(args []string) (any, error) {
+		return nil, run(args)
+	}
+}
+
+// groupLeaf returns the builder of a registry group.
+func (rn *Runner) groupLeaf(group string) leafBuilder {
+	switch group {
+	case "auth":
+		return rn.authGroupLeaf
+	case "config":
+		return rn.configLeaf
+	case "context":
+		return rn.contextLeaf
+	case "me":
+		return rn.meLeaf
+	case "billing":
+		return rn.billingLeaf
+	case "location":
+		return rn.locationLeaf
+	case "project":
+		return rn.projectLeaf
+	case "role":
+		return rn.roleLeaf
+	case "deployment":
+		return rn.deploymentLeaf
+	case "error":
+		return rn.errorGroupLeaf
+	case "domain":
+		return rn.domainLeaf
+	case "route":
+		return rn.routeLeaf
+	case "waf":
+		return rn.wafLeaf
+	case "cache":
+		return rn.cacheLeaf
+	case "transform":
+		return rn.transformLeaf
+	case "disk":
+		return rn.diskLeaf
+	case "pullsecret":
+		return rn.pullSecretLeaf
+	case "workloadidentity":
+		return rn.workloadIdentityLeaf
+	case "serviceaccount":
+		return rn.serviceAccountLeaf
+	case "email":
+		return rn.emailLeaf
+	case "registry":
+		return rn.registryLeaf
+	case "envgroup":
+		return rn.envGroupLeaf
+	case "auditlog":
+		return rn.auditLogLeaf
+	case "dropbox":
+		return rn.dropboxLeaf
+	case "collector":
+		return rn.collectorLeaf
+	case "github":
+		return rn.githubLeaf
+	case "site":
+		return rn.siteLeaf
+	case "scheduler":
+		return rn.schedulerLeaf
+	case "notification":
+		return rn.notificationLeaf
+	case "mcp":
+		return rn.mcpLeaf
+	}
+	return nil
+}
+
+// standaloneLeaf declares the flags of a standalone command on a new flag set
+// and returns it with the command's run; nil for a command without flags.
+func (rn *Runner) standaloneLeaf(name string) (*flag.FlagSet, func(args []string) error) {
+	switch name {
+	case "login":
+		return rn.loginLeaf()
+	case "logout":
+		return rn.logoutLeaf()
+	case "refs":
+		return rn.refsLeaf()
+	case "api":
+		return rn.apiLeaf()
+	case "dev-server":
+		return rn.devServerLeaf()
+	case "history":
+		return rn.historyLeaf()
+	case "undo":
+		return rn.undoLeaf()
+	case "docs":
+		return rn.docsLeaf()
+	case "version":
+		return rn.versionLeaf()
+	case "check-update":
+		return rn.checkUpdateLeaf()
+	}
+	return nil, nil
+}
+
+// leafFlags returns the flag set of a registry entry: sub of group, or the
+// standalone command group when sub is empty. A group's listing of a nested
+// leaf (deployment's "set") has none of its own.
+func leafFlags(group, sub string) *flag.FlagSet {
+	out, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil
+	}
+	defer out.Close()
+
+	rn := &Runner{API: offlineAPI(), Output: out}
+	if sub == "" {
+		f, _ := rn.standaloneLeaf(group)
+		return f
+	}
+	leaf := rn.groupLeaf(group)
+	if c := lookupCommand(group); leaf == nil || c == nil || isListing(c, sub) {
+		return nil
+	}
+	f := rn.subFlagSet(group, sub)
+	if leaf(f, sub) == nil {
+		return nil
+	}
+	return f.FlagSet
+}
+
+// offlineAPI is an api client whose every call fails without leaving the
+// process.
+func offlineAPI() api.Interface {
+	return &client.Client{HTTPClient: &http.Client{Transport: offlineTransport{}}}
+}
+
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
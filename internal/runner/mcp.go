@@ -0,0 +1,357 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+)
+
+func (rn Runner) mcp(args ...string) error {
+	return rn.runGroup("mcp", args, rn.mcpLeaf)
+}
+
+func (rn *Runner) mcpLeaf(f *leafFlagSet, sub string) leafRun {
+	switch sub {
+	case "serve":
+		var (
+			readOnly bool
+			allow    string
+		)
+		f.BoolVar(&readOnly, "read-only", false, "expose only the tools that read (get, list, status, metrics, logs, ...)")
+		f.StringVar(&allow, "allow", "", "expose only the methods matching these patterns (comma separated, e.g. deployment.*,route.list)")
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			tools, err := mcpTools(readOnly, splitComma(allow))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "deploys mcp: serving %d tools on stdio\n", len(tools))
+			srv := mcpServer{api: mcpAPI(rn.API), tools: tools, version: displayVersion(rn.Version)}
+			return nil, srv.serve(rn.ctx(), os.Stdin, rn.output())
+		}
+	}
+	return nil
+}
+
+// mcpAPI tags the api client's calls with the mcp audit channel.
+func mcpAPI(a api.Interface) api.Interface {
+	c, ok := a.(*client.Client)
+	if !ok {
+		return a
+	}
+	cc := *c
+	cc.Channel = api.AuditChannelMCP
+	return &cc
+}
+
+// mcpServices are the api services for the platform's own agents, not users;
+// they are never tools.
+var mcpServices = map[string]bool{"Deployer": true, "Collector": true}
+
+// mcpReadVerbs match (as path.Match patterns) the method names of the rpcs
+// that only read, the tools -read-only keeps.
+var mcpReadVerbs = []string{
+	"get*", "list*", "lookup*", "download*", "*Metrics", "metrics", "status", "logs", "logsHistory",
+	"revisions", "usage", "permissions", "authorized", "users", "deliveries", "report", "skus", "policy", "project",
+}
+
+func readOnlyRPC(name string) bool {
+	_, method, _ := strings.Cut(name, ".")
+	for _, v := range mcpReadVerbs {
+		if ok, _ := path.Match(v, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mcpTool is an api method exposed over mcp.
+type mcpTool struct {
+	apiMethod
+	readOnly bool
+}
+
+// toolName is the method's name in the charset mcp clients accept.
+func (t *mcpTool) toolName() string {
+	return strings.ReplaceAll(t.Name, ".", "_")
+}
+
+// description is the cli command's help line for the method, when one wraps
+// it.
+func (t *mcpTool) description() string {
+	resource, method, _ := strings.Cut(t.Name, ".")
+	if c := lookupCommand(resource); c != nil {
+		if e := c.lookupSub(method); e != nil {
+			return e.short + " (api " + t.Name + ")"
+		}
+	}
+	return "call the deploys api method " + t.Name
+}
+
+// mcpTools returns the api methods to expose: all but the agents' services,
+// narrowed to the readers under readOnly and to the names matching one of
+// allow when given.
+func mcpTools(readOnly bool, allow []string) ([]mcpTool, error) {
+	for _, p := range allow {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("-allow %q: %w", p, err)
+		}
+	}
+	var tools []mcpTool
+	for _, m := range apiMethods() {
+		t := mcpTool{apiMethod: m, readOnly: readOnlyRPC(m.Name)}
+		if mcpServices[m.Service] || (readOnly && !t.readOnly) {
+			continue
+		}
+		if len(allow) > 0 && !slices.ContainsFunc(allow, func(p string) bool {
+			ok, _ := path.Match(p, m.Name)
+			return ok
+		}) {
+			continue
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// mcpProtocolVersions are the mcp revisions served, newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// mcpServer serves tools, calling them on api.
+type mcpServer struct {
+	api     api.Interface
+	tools   []mcpTool
+	version string
+}
+
+// serve speaks mcp over newline-delimited JSON-RPC on in and out until in
+// ends.
+func (s *mcpServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rerr := s.handle(ctx, &req)
+		if len(req.ID) == 0 {
+			continue // a notification
+		}
+		if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func (s *mcpServer) handle(ctx context.Context, req *rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &p)
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "deploys", "version": s.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		list := make([]map[string]any, len(s.tools))
+		for i := range s.tools {
+			t := &s.tools[i]
+			list[i] = map[string]any{
+				"name":        t.toolName(),
+				"description": t.description(),
+				"inputSchema": jsonSchema(t.Request, nil),
+				"annotations": map[string]any{"readOnlyHint": t.readOnly},
+			}
+		}
+		return map[string]any{"tools": list}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		i := slices.IndexFunc(s.tools, func(t mcpTool) bool { return t.toolName() == p.Name })
+		if i < 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		args := p.Arguments
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		// a failed call is a tool result, so the model sees why
+		res, err := s.tools[i].call(ctx, s.api, args)
+		if err != nil {
+			return mcpText(err.Error(), true), nil
+		}
+		b, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return mcpText(err.Error(), true), nil
+		}
+		return mcpText(string(b), false), nil
+	}
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func mcpText(s string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": s}},
+		"isError": isError,
+	}
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+)
+
+// jsonSchema describes the json encoding of t. A type with its own json
+// encoding is described by the kind of value its zero value encodes to.
+// seen stops a recursive type.
+func jsonSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.Struct && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType)) {
+		b, _ := json.Marshal(reflect.Zero(t).Interface())
+		switch {
+		case len(b) == 0:
+		case b[0] == '"':
+			return map[string]any{"type": "string"}
+		case b[0] == '-' || (b[0] >= '0' && b[0] <= '9'):
+			return map[string]any{"type": "number"}
+		}
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]any{"type": "object"}
+		}
+		seen = withSeen(seen, t)
+		props := map[string]any{}
+		structProperties(t, seen, props)
+		return map[string]any{"type": "object", "properties": props}
+	}
+	return map[string]any{}
+}
+
+// structProperties adds the json fields of struct t to props, those of
+// embedded structs included.
+func structProperties(t reflect.Type, seen map[reflect.Type]bool, props map[string]any) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			structProperties(ft, seen, props)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if slices.Contains(strings.Split(opts, ","), "string") {
+			props[name] = map[string]any{"type": "string"}
+			continue
+		}
+		props[name] = jsonSchema(sf.Type, seen)
+	}
+}
+
+// withSeen returns seen with t added, leaving seen as it was for t's
+// siblings.
+func withSeen(seen map[reflect.Type]bool, t reflect.Type) map[reflect.Type]bool {
+	next := make(map[reflect.Type]bool, len(seen)+1)
+	for k := range seen {
+		next[k] = true
+	}
+	next[t] = true
+	return next
+}
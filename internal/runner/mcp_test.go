@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+	"github.com/deploys-app/api/client"
+)
+
+func TestMCPServe(t *testing.T) {
+	_, a := newJournalRunner(t)
+	tools, err := mcpTools(false, []string{"envgroup.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := mcpServer{api: a, tools: tools, version: "v1.2.3"}
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"envgroup_get","arguments":{"project":"acme","name":"shared"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"envgroup_get","arguments":{"project":"acme","name":"missing"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"deployment_deploy"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := srv.serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		ID     json.RawMessage `json:"id"`
+		Result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+			Tools           []struct {
+				Name        string         `json:"name"`
+				InputSchema map[string]any `json:"inputSchema"`
+				Annotations map[string]any `json:"annotations"`
+			} `json:"tools"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			IsError bool `json:"isError"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	var resps []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	// the notification gets no response
+	if len(resps) != 7 {
+		t.Fatalf("got %d responses:\n%s", len(resps), out.String())
+	}
+
+	if got := resps[0].Result.ProtocolVersion; got != "2024-11-05" {
+		t.Errorf("protocolVersion = %q", got)
+	}
+
+	names := map[string]int{}
+	for i, tl := range resps[1].Result.Tools {
+		names[tl.Name] = i
+	}
+	i, ok := names["envgroup_get"]
+	if !ok || len(names) != 5 {
+		t.Fatalf("tools: %v", names)
+	}
+	get := resps[1].Result.Tools[i]
+	if props, _ := get.InputSchema["properties"].(map[string]any); props["project"] == nil {
+		t.Errorf("envgroup_get schema: %v", get.InputSchema)
+	}
+	if get.Annotations["readOnlyHint"] != true || resps[1].Result.Tools[names["envgroup_delete"]].Annotations["readOnlyHint"] != false {
+		t.Error("readOnlyHint not set by verb")
+	}
+
+	if r := resps[2].Result; r.IsError || len(r.Content) != 1 || !strings.Contains(r.Content[0].Text, `"A": "1"`) {
+		t.Errorf("envgroup_get: %+v", r)
+	}
+	// an api error is a tool result, not a protocol error
+	if r := resps[3]; r.Error != nil || !r.Result.IsError || r.Result.Content[0].Text != api.ErrEnvGroupNotFound.Error() {
+		t.Errorf("failed call: %+v", r)
+	}
+	if r := resps[4]; r.Error == nil || r.Error.Code != rpcInvalidParams {
+		t.Errorf("tool not served: %+v", r)
+	}
+	if r := resps[5]; r.Error == nil || r.Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method: %+v", r)
+	}
+	if r := resps[6]; r.Error == nil || r.Error.Code != rpcParseError || string(r.ID) != "null" {
+		t.Errorf("parse error: %+v", r)
+	}
+}
+
+func TestMCPTools(t *testing.T) {
+	tools, err := mcpTools(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	has := map[string]bool{}
+	for _, tl := range tools {
+		has[tl.Name] = true
+		if tl.Service == "Deployer" || tl.Service == "Collector" {
+			t.Errorf("%s served", tl.Name)
+		}
+	}
+	for _, name := range []string{"deployment.get", "deployment.list", "deployment.logs", "role.users", "billing.skus"} {
+		if !has[name] {
+			t.Errorf("-read-only: %s missing", name)
+		}
+	}
+	for _, name := range []string{"deployment.deploy", "deployment.delete", "envgroup.update", "role.grant"} {
+		if has[name] {
+			t.Errorf("-read-only: %s served", name)
+		}
+	}
+
+	tools, err = mcpTools(false, []string{"deployment.*", "route.list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tl := range tools {
+		if !strings.HasPrefix(tl.Name, "deployment.") && tl.Name != "route.list" {
+			t.Errorf("-allow: %s served", tl.Name)
+		}
+	}
+
+	if _, err := mcpTools(false, []string{"["}); err == nil {
+		t.Error("bad pattern: want an error")
+	}
+}
+
+func TestMCPAPIChannel(t *testing.T) {
+	c := &client.Client{Channel: api.AuditChannelCLI}
+	got, ok := mcpAPI(c).(*client.Client)
+	if !ok || got.Channel != api.AuditChannelMCP {
+		t.Errorf("channel = %v", got)
+	}
+	if c.Channel != api.AuditChannelCLI {
+		t.Error("the runner's client changed")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	s := jsonSchema(reflect.TypeFor[*api.DeploymentDeploy](), nil)
+	props := s["properties"].(map[string]any)
+	for field, want := range map[string]string{"project": "string", "type": "string", "env": "object", "command": "array", "port": "integer"} {
+		p, _ := props[field].(map[string]any)
+		if p["type"] != want {
+			t.Errorf("%s: %v; want type %s", field, props[field], want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,35 +13,29 @@ import (
 )
 
 func (rn Runner) notification(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("notification")
-	}
+	return rn.runGroup("notification", args, rn.notificationLeaf)
+}
 
+func (rn *Runner) notificationLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Notification()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("notification", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("notification", args[0])
-
+	switch sub {
 	case "list":
 		var req api.NotificationList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.NotificationGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "channel name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "create":
 		var (
 			req         api.NotificationCreate
@@ -65,15 +58,39 @@ func (rn Runner) notification(args ...string) error {
 		f.Var(&events, "event", "resource.action event to subscribe to: *, deployment.*, *.delete, deployment.deploy (repeatable; empty = all)")
 		f.Var(&outcomes, "outcome", "outcome to subscribe to: success or failure (repeatable; empty = all)")
 		f.BoolVar(&disabled, "disabled", false, "create the channel disabled")
-		f.Parse(args[1:])
-		req.Config = api.NotificationConfig{Type: typ, URL: url, Secret: secret, InsecureSkipVerify: insecureTLS, PullTTLSeconds: pullTTL}
-		req.Subscription = api.NotificationSubscription{
-			Events:   []string(events),
-			Outcomes: []string(outcomes),
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			// a -f request file may carry the config and subscription; the flags
+			// passed override its fields
+			set := visitedFlags(f.FlagSet)
+			if set["type"] {
+				req.Config.Type = typ
+			}
+			if set["url"] {
+				req.Config.URL = url
+			}
+			if set["secret"] {
+				req.Config.Secret = secret
+			}
+			if set["insecure-tls"] {
+				req.Config.InsecureSkipVerify = insecureTLS
+			}
+			if set["pull-ttl"] {
+				req.Config.PullTTLSeconds = pullTTL
+			}
+			if len(events) > 0 {
+				req.Subscription.Events = []string(events)
+			}
+			if len(outcomes) > 0 {
+				req.Subscription.Outcomes = []string(outcomes)
+			}
+			req.Disabled = disabled
+			o := rn.journalBegin("notification create", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		req.Disabled = disabled
-		resp, err = s.Create(context.Background(), &req)
-
 	case "update":
 		// Merge semantics: seed from the existing channel, override only the flags
 		// the user explicitly passed (visitedFlags). The signing secret is never
@@ -101,59 +118,76 @@ func (rn Runner) notification(args ...string) error {
 		f.Var(&events, "event", "resource.action event to subscribe to: *, deployment.*, *.delete (repeatable; replaces all)")
 		f.Var(&outcomes, "outcome", "outcome to subscribe to (repeatable; replaces all)")
 		f.BoolVar(&disabled, "disabled", false, "disable the channel")
-		f.Parse(args[1:])
-		set := visitedFlags(f)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			set := visitedFlags(f.FlagSet)
 
-		// A distinct name avoids shadowing the outer err so a later Update error
-		// still surfaces after the switch.
-		cur, getErr := s.Get(context.Background(), &api.NotificationGet{Project: req.Project, Name: req.Name})
-		if getErr != nil {
-			return getErr
-		}
-		req.Config = cur.Config // Type + URL + InsecureSkipVerify; Secret stays empty so it is preserved
-		req.Subscription = cur.Subscription
-		req.Disabled = cur.Disabled
+			// A distinct name avoids shadowing the outer err so a later Update error
+			// still surfaces after the switch.
+			cur, getErr := s.Get(rn.ctx(), &api.NotificationGet{Project: req.Project, Name: req.Name})
+			if getErr != nil {
+				return nil, getErr
+			}
+			// a -f request file's config or subscription replaces the existing one
+			if req.Config == (api.NotificationConfig{}) {
+				req.Config = cur.Config // Type + URL + InsecureSkipVerify; Secret stays empty so it is preserved
+			}
+			if len(req.Subscription.Events) == 0 && len(req.Subscription.Outcomes) == 0 {
+				req.Subscription = cur.Subscription
+			}
+			req.Disabled = cur.Disabled
 
-		if set["type"] {
-			req.Config.Type = typ
-		}
-		if set["url"] {
-			req.Config.URL = url
-		}
-		if set["secret"] {
-			req.Config.Secret = secret
-		}
-		if set["insecure-tls"] {
-			req.Config.InsecureSkipVerify = insecureTLS
-		}
-		if set["pull-ttl"] {
-			req.Config.PullTTLSeconds = pullTTL
-		}
-		if len(events) > 0 {
-			req.Subscription.Events = []string(events)
-		}
-		if len(outcomes) > 0 {
-			req.Subscription.Outcomes = []string(outcomes)
-		}
-		if set["disabled"] {
-			req.Disabled = disabled
+			if set["type"] {
+				req.Config.Type = typ
+			}
+			if set["url"] {
+				req.Config.URL = url
+			}
+			if set["secret"] {
+				req.Config.Secret = secret
+			}
+			if set["insecure-tls"] {
+				req.Config.InsecureSkipVerify = insecureTLS
+			}
+			if set["pull-ttl"] {
+				req.Config.PullTTLSeconds = pullTTL
+			}
+			if len(events) > 0 {
+				req.Subscription.Events = []string(events)
+			}
+			if len(outcomes) > 0 {
+				req.Subscription.Outcomes = []string(outcomes)
+			}
+			if set["disabled"] {
+				req.Disabled = disabled
+			}
+			o := rn.journalBegin("notification update", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		resp, err = s.Update(context.Background(), &req)
-
 	case "delete":
 		var req api.NotificationDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "channel name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("notification delete", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "test":
 		var req api.NotificationTest
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "channel name")
-		f.Parse(args[1:])
-		resp, err = s.Test(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Test(rn.ctx(), &req)
+		}
 	case "deliveries":
 		var (
 			req    api.NotificationDeliveries
@@ -165,11 +199,13 @@ func (rn Runner) notification(args ...string) error {
 		f.IntVar(&req.Limit, "limit", 0, "max delivery entries (default 50, max 100)")
 		f.Var(timeFlag{&after}, "after", "only entries after this time (RFC3339 or YYYY-MM-DD)")
 		f.Var(timeFlag{&before}, "before", "only entries before this time (RFC3339 or YYYY-MM-DD)")
-		f.Parse(args[1:])
-		req.After = after
-		req.Before = before
-		resp, err = s.Deliveries(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.After = after
+			req.Before = before
+			return s.Deliveries(rn.ctx(), &req)
+		}
 	case "pull":
 		// Consume a pull channel's change events. The server stores the cursor;
 		// pass -ack <cursor> (from a previous pull) to acknowledge that batch and
@@ -188,16 +224,16 @@ func (rn Runner) notification(args ...string) error {
 		f.BoolVar(&follow, "follow", false, "stream new changes as they land (over SSE), until interrupted")
 		f.BoolVar(&poll, "poll", false, "with -follow, use RPC polling instead of the SSE stream")
 		f.DurationVar(&interval, "interval", 2*time.Second, "poll interval between empty batches when following with -poll")
-		f.Parse(args[1:])
-		if follow {
-			return rn.followNotificationPull(s, &req, interval, poll)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			if follow {
+				return nil, rn.followNotificationPull(s, &req, interval, poll)
+			}
+			return s.Pull(rn.ctx(), &req)
 		}
-		resp, err = s.Pull(context.Background(), &req)
 	}
-	if err != nil {
-		return err
-	}
-	return rn.print(resp)
+	return nil
 }
 
 // followNotificationPull streams a pull channel's changes until interrupted. By
@@ -212,7 +248,7 @@ func (rn Runner) followNotificationPull(s api.Notification, req *api.Notificatio
 		return rn.pollNotificationPull(s, req, interval)
 	}
 
-	ctx := context.Background()
+	ctx := rn.ctx()
 	for {
 		err := c.NotificationPullStream(ctx, req, func(_ int64, ev api.ChangeEventPayload) error {
 			return rn.printNotificationEvent(ev)
@@ -237,7 +273,7 @@ func (rn Runner) followNotificationPull(s api.Notification, req *api.Notificatio
 // interrupt mid-batch redelivers it (at-least-once).
 func (rn Runner) pollNotificationPull(s api.Notification, req *api.NotificationPull, interval time.Duration) error {
 	for {
-		res, err := s.Pull(context.Background(), req)
+		res, err := s.Pull(rn.ctx(), req)
 		if err != nil {
 			return err
 		}
@@ -255,9 +291,13 @@ func (rn Runner) pollNotificationPull(s api.Notification, req *api.NotificationP
 
 // printNotificationEvent renders one streamed change, honoring -output: a compact
 // JSON line (NDJSON, ideal for an agent) for json, a YAML document for yaml, a
-// TOON document for toon, and a single tab-separated line (time, actor, action,
-// resource, outcome) otherwise.
+// TOON document for toon, the expression's output for template=/jsonpath=, and
+// a single tab-separated line (time, actor, action, resource, outcome)
+// otherwise.
 func (rn Runner) printNotificationEvent(ev api.ChangeEventPayload) error {
+	if templateOutput(rn.OutputMode) {
+		return rn.printExpr(ev)
+	}
 	switch rn.OutputMode {
 	case "json":
 		b, err := json.Marshal(ev)
@@ -0,0 +1,525 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deploys-app/api"
+	"gopkg.in/yaml.v2"
+)
+
+// Presets are starter rule packs for `waf init` / `cache init`. Each renders
+// into ordinary rules/limits/overrides with an empty ID (the server assigns
+// one) and a description naming the preset, so the generated spec can be
+// reviewed, edited, and applied with `set` like any hand-written one. The
+// expressions use the parapet request.* CEL surface; they are compiled
+// server-side all-or-nothing, so a preset that doesn't fit a zone fails the
+// set loudly rather than being half-applied.
+
+// wafPreset is one composable WAF rule pack. args describes the optional or
+// required ":arg" suffix for help output ("" when the preset takes none).
+type wafPreset struct {
+	name  string
+	args  string
+	short string
+	build func(args []string) ([]api.WAFRule, []api.WAFLimit, error)
+}
+
+// cachePreset is one composable cache-override pack.
+type cachePreset struct {
+	name  string
+	args  string
+	short string
+	build func(args []string) ([]api.CacheOverride, error)
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var wafPresets = []wafPreset{
+	{
+		name:  "login-rate-limit",
+		args:  "[:path]",
+		short: "limit POSTs to a login path to 10/min per IP (default /login)",
+		build: func(args []string) ([]api.WAFRule, []api.WAFLimit, error) {
+			path, err := presetPath(args, "/login")
+			if err != nil {
+				return nil, nil, err
+			}
+			return nil, []api.WAFLimit{{
+				Description: "preset login-rate-limit: " + path,
+				Key:         []string{"ip"},
+				Rate:        10,
+				Window:      "1m",
+				Filter:      fmt.Sprintf(`request.method == "POST" && request.path.startsWith(%q)`, path),
+			}}, nil
+		},
+	},
+	{
+		name:  "block-bad-bots",
+		short: "block well-known vulnerability scanners by user agent",
+		build: func(args []string) ([]api.WAFRule, []api.WAFLimit, error) {
+			if len(args) > 0 {
+				return nil, nil, fmt.Errorf("block-bad-bots takes no arguments")
+			}
+			return []api.WAFRule{{
+				Description: "preset block-bad-bots",
+				Expression:  `request.userAgent.matches("(?i)(sqlmap|nikto|nmap|masscan|zgrab|nuclei|wpscan|dirbuster|gobuster)")`,
+				Action:      api.WAFActionBlock,
+				Priority:    10,
+			}}, nil, nil
+		},
+	},
+	{
+		name:  "block-sensitive-files",
+		short: "block probes for dotfiles and backups (/.env, /.git, *.bak, ...)",
+		build: func(args []string) ([]api.WAFRule, []api.WAFLimit, error) {
+			if len(args) > 0 {
+				return nil, nil, fmt.Errorf("block-sensitive-files takes no arguments")
+			}
+			return []api.WAFRule{{
+				Description: "preset block-sensitive-files",
+				Expression:  `request.path.matches("(?i)(/\\.(env|git|svn|hg|ds_store)|\\.(bak|old|swp|sql)$)")`,
+				Action:      api.WAFActionBlock,
+				Status:      404,
+				Message:     "Not Found",
+				Priority:    10,
+			}}, nil, nil
+		},
+	},
+	{
+		name:  "geo-allow",
+		args:  ":CC,CC...",
+		short: "block every country except the listed ISO codes",
+		build: func(args []string) ([]api.WAFRule, []api.WAFLimit, error) {
+			cc, err := presetCountries("geo-allow", args)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []api.WAFRule{{
+				Description: "preset geo-allow: " + strings.Join(cc, ","),
+				Expression:  fmt.Sprintf("!(request.country in %s)", celStringList(cc)),
+				Action:      api.WAFActionBlock,
+				Priority:    20,
+			}}, nil, nil
+		},
+	},
+	{
+		name:  "geo-block",
+		args:  ":CC,CC...",
+		short: "block the listed ISO country codes",
+		build: func(args []string) ([]api.WAFRule, []api.WAFLimit, error) {
+			cc, err := presetCountries("geo-block", args)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []api.WAFRule{{
+				Description: "preset geo-block: " + strings.Join(cc, ","),
+				Expression:  fmt.Sprintf("request.country in %s", celStringList(cc)),
+				Action:      api.WAFActionBlock,
+				Priority:    20,
+			}}, nil, nil
+		},
+	},
+}
+
+var cachePresets = []cachePreset{
+	{
+		name:  "static-assets",
+		args:  "[:ttl]",
+		short: "cache static assets (css/js/images/fonts) at the edge (default ttl 24h)",
+		build: func(args []string) ([]api.CacheOverride, error) {
+			ttl, err := presetDuration(args, "24h")
+			if err != nil {
+				return nil, err
+			}
+			return []api.CacheOverride{{
+				Description: "preset static-assets",
+				Action:      "cache",
+				Filter:      `request.method == "GET" && request.path.matches("(?i)\\.(css|js|mjs|map|png|jpe?g|gif|webp|avif|svg|ico|woff2?|ttf|otf)$")`,
+				TTL:         ttl,
+				Policy:      "balanced",
+				Status:      []int{200},
+			}}, nil
+		},
+	},
+	{
+		name:  "api-no-cache",
+		args:  "[:prefix]",
+		short: "never cache requests under an API path prefix (default /api/)",
+		build: func(args []string) ([]api.CacheOverride, error) {
+			path, err := presetPath(args, "/api/")
+			if err != nil {
+				return nil, err
+			}
+			return []api.CacheOverride{{
+				Description: "preset api-no-cache: " + path,
+				Action:      "bypass",
+				Filter:      fmt.Sprintf("request.path.startsWith(%q)", path),
+			}}, nil
+		},
+	},
+}
+
+func lookupWAFPreset(name string) *wafPreset {
+	for i := range wafPresets {
+		if wafPresets[i].name == name {
+			return &wafPresets[i]
+		}
+	}
+	return nil
+}
+
+func lookupCachePreset(name string) *cachePreset {
+	for i := range cachePresets {
+		if cachePresets[i].name == name {
+			return &cachePresets[i]
+		}
+	}
+	return nil
+}
+
+// presetRef is one parsed -preset element: a preset name and its arguments.
+// open records that the element carried a ':' so later bare elements extend
+// its argument list.
+type presetRef struct {
+	name string
+	args []string
+	open bool
+}
+
+// parsePresets splits a -preset value into preset references. Elements are
+// comma separated and a preset's arguments follow a ':'; because arguments are
+// themselves comma separated, an element that is not a known preset name
+// continues the argument list of the previous preset that took a ':', so
+// "login-rate-limit,geo-allow:TH,SG" is two presets, geo-allow taking [TH SG].
+func parsePresets(s string, known func(string) bool) ([]presetRef, error) {
+	var refs []presetRef
+	for _, tok := range splitComma(s) {
+		name, arg, hasArg := strings.Cut(tok, ":")
+		if known(name) {
+			ref := presetRef{name: name, open: hasArg}
+			if hasArg && arg != "" {
+				ref.args = append(ref.args, arg)
+			}
+			refs = append(refs, ref)
+			continue
+		}
+		if len(refs) == 0 || hasArg || !refs[len(refs)-1].open {
+			return nil, fmt.Errorf("unknown preset %q", name)
+		}
+		refs[len(refs)-1].args = append(refs[len(refs)-1].args, tok)
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no presets given (-preset)")
+	}
+	return refs, nil
+}
+
+// buildWAFPresets renders the -preset value into rules and limits, in order.
+func buildWAFPresets(s string) ([]api.WAFRule, []api.WAFLimit, error) {
+	refs, err := parsePresets(s, func(n string) bool { return lookupWAFPreset(n) != nil })
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w (available: %s)", err, wafPresetNames())
+	}
+	var (
+		rules  []api.WAFRule
+		limits []api.WAFLimit
+	)
+	for _, ref := range refs {
+		r, l, err := lookupWAFPreset(ref.name).build(ref.args)
+		if err != nil {
+			return nil, nil, fmt.Errorf("preset %s: %w", ref.name, err)
+		}
+		rules = append(rules, r...)
+		limits = append(limits, l...)
+	}
+	return rules, limits, nil
+}
+
+// buildCachePresets renders the -preset value into overrides, in order.
+func buildCachePresets(s string) ([]api.CacheOverride, error) {
+	refs, err := parsePresets(s, func(n string) bool { return lookupCachePreset(n) != nil })
+	if err != nil {
+		return nil, fmt.Errorf("%w (available: %s)", err, cachePresetNames())
+	}
+	var overrides []api.CacheOverride
+	for _, ref := range refs {
+		o, err := lookupCachePreset(ref.name).build(ref.args)
+		if err != nil {
+			return nil, fmt.Errorf("preset %s: %w", ref.name, err)
+		}
+		overrides = append(overrides, o...)
+	}
+	return overrides, nil
+}
+
+// mergeWAFPresets appends preset rules and limits to an existing zone's,
+// skipping any whose description the zone already carries so re-running the
+// same init is idempotent. Existing entries keep their ids.
+func mergeWAFPresets(req *api.WAFSet, rules []api.WAFRule, limits []api.WAFLimit) {
+	have := map[string]bool{}
+	for _, r := range req.Rules {
+		have[r.Description] = true
+	}
+	for _, l := range req.Limits {
+		have[l.Description] = true
+	}
+	for _, r := range rules {
+		if !have[r.Description] {
+			req.Rules = append(req.Rules, r)
+		}
+	}
+	for _, l := range limits {
+		if !have[l.Description] {
+			req.Limits = append(req.Limits, l)
+		}
+	}
+}
+
+// mergeCachePresets is mergeWAFPresets for cache overrides.
+func mergeCachePresets(req *api.CacheSet, overrides []api.CacheOverride) {
+	have := map[string]bool{}
+	for _, o := range req.Overrides {
+		have[o.Description] = true
+	}
+	for _, o := range overrides {
+		if !have[o.Description] {
+			req.Overrides = append(req.Overrides, o)
+		}
+	}
+}
+
+// presetInfo is one row of `waf|cache init -list`.
+type presetInfo struct {
+	Name        string `json:"name" yaml:"name"`
+	Args        string `json:"args,omitempty" yaml:"args,omitempty"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type presetList []presetInfo
+
+func (l presetList) Table() [][]string {
+	table := [][]string{
+		{"PRESET", "ARGS", "DESCRIPTION"},
+	}
+	for _, x := range l {
+		table = append(table, []string{x.Name, x.Args, x.Description})
+	}
+	return table
+}
+
+func wafPresetList() presetList {
+	var l presetList
+	for _, p := range wafPresets {
+		l = append(l, presetInfo{Name: p.name, Args: p.args, Description: p.short})
+	}
+	return l
+}
+
+func cachePresetList() presetList {
+	var l presetList
+	for _, p := range cachePresets {
+		l = append(l, presetInfo{Name: p.name, Args: p.args, Description: p.short})
+	}
+	return l
+}
+
+func wafPresetNames() string {
+	var xs []string
+	for _, p := range wafPresets {
+		xs = append(xs, p.name+p.args)
+	}
+	return strings.Join(xs, ", ")
+}
+
+func cachePresetNames() string {
+	var xs []string
+	for _, p := range cachePresets {
+		xs = append(xs, p.name+p.args)
+	}
+	return strings.Join(xs, ", ")
+}
+
+// writeSpecFile renders a generated spec as yaml to fn, or to the runner's
+// output when fn is empty. An existing file is only replaced with force. apply
+// is the command that applies the file, shown in the confirmation.
+func (rn Runner) writeSpecFile(fn string, force bool, spec any, summary, apply string) error {
+	b, err := yaml.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if fn == "" {
+		_, err = rn.output().Write(b)
+		return err
+	}
+	if !force {
+		if _, err := os.Stat(fn); err == nil {
+			return fmt.Errorf("%s already exists (use -force to overwrite)", fn)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.WriteFile(fn, b, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(rn.output(), "wrote %s (%s); review it, then apply with: %s -f %s\n", fn, summary, apply, fn)
+	return nil
+}
+
+// wafInit handles `waf init`: render presets into a WAFSet spec, optionally
+// merged into the live zone.
+func (rn *Runner) wafInit(s api.WAF, f *leafFlagSet) func(args []string) error {
+	var (
+		req    api.WAFSet
+		preset string
+		out    string
+		merge  bool
+		force  bool
+		list   bool
+	)
+	f.StringVar(&preset, "preset", "", "comma separated presets: "+wafPresetNames())
+	f.StringVar(&req.Project, "project", "", "project id")
+	f.StringVar(&req.Location, "location", "", "location")
+	f.StringVar(&req.Description, "description", "", "zone description")
+	f.StringVar(&out, "o", "", "write the spec to this file (default stdout)")
+	f.BoolVar(&merge, "merge", false, "start from the live zone (waf get) and add the presets to it")
+	f.BoolVar(&force, "force", false, "overwrite an existing -o file")
+	f.BoolVar(&list, "list", false, "list the available presets and exit")
+	return func(args []string) error {
+		f.Parse(args)
+
+		if list {
+			return rn.print(wafPresetList())
+		}
+		rules, limits, err := buildWAFPresets(preset)
+		if err != nil {
+			return err
+		}
+		if merge {
+			cur, err := s.Get(rn.ctx(), &api.WAFGet{Project: req.Project, Location: req.Location})
+			switch {
+			case errors.Is(err, api.ErrWAFZoneNotFound):
+				// nothing live yet; the presets are the whole zone
+			case err != nil:
+				return err
+			default:
+				if req.Description == "" {
+					req.Description = cur.Description
+				}
+				req.Rules = cur.Rules
+				req.Limits = cur.Limits
+			}
+		}
+		mergeWAFPresets(&req, rules, limits)
+
+		summary := strconv.Itoa(len(req.Rules)) + " rules, " + strconv.Itoa(len(req.Limits)) + " limits"
+		return rn.writeSpecFile(out, force, &req, summary, "deploys waf set")
+	}
+}
+
+// cacheInit handles `cache init`, the cache-override counterpart of wafInit.
+func (rn *Runner) cacheInit(s api.Cache, f *leafFlagSet) func(args []string) error {
+	var (
+		req    api.CacheSet
+		preset string
+		out    string
+		merge  bool
+		force  bool
+		list   bool
+	)
+	f.StringVar(&preset, "preset", "", "comma separated presets: "+cachePresetNames())
+	f.StringVar(&req.Project, "project", "", "project id")
+	f.StringVar(&req.Location, "location", "", "location")
+	f.StringVar(&req.Description, "description", "", "zone description")
+	f.StringVar(&out, "o", "", "write the spec to this file (default stdout)")
+	f.BoolVar(&merge, "merge", false, "start from the live zone (cache get) and add the presets to it")
+	f.BoolVar(&force, "force", false, "overwrite an existing -o file")
+	f.BoolVar(&list, "list", false, "list the available presets and exit")
+	return func(args []string) error {
+		f.Parse(args)
+
+		if list {
+			return rn.print(cachePresetList())
+		}
+		overrides, err := buildCachePresets(preset)
+		if err != nil {
+			return err
+		}
+		if merge {
+			cur, err := s.Get(rn.ctx(), &api.CacheGet{Project: req.Project, Location: req.Location})
+			switch {
+			case errors.Is(err, api.ErrCacheZoneNotFound):
+			case err != nil:
+				return err
+			default:
+				if req.Description == "" {
+					req.Description = cur.Description
+				}
+				req.Overrides = cur.Overrides
+			}
+		}
+		mergeCachePresets(&req, overrides)
+
+		return rn.writeSpecFile(out, force, &req, strconv.Itoa(len(req.Overrides))+" overrides", "deploys cache set")
+	}
+}
+
+// presetPath reads an optional path argument, which must be absolute.
+func presetPath(args []string, def string) (string, error) {
+	switch len(args) {
+	case 0:
+		return def, nil
+	case 1:
+		if !strings.HasPrefix(args[0], "/") {
+			return "", fmt.Errorf("path %q must start with /", args[0])
+		}
+		return args[0], nil
+	default:
+		return "", fmt.Errorf("takes at most one path")
+	}
+}
+
+// presetDuration reads an optional Go duration argument.
+func presetDuration(args []string, def string) (string, error) {
+	switch len(args) {
+	case 0:
+		return def, nil
+	case 1:
+		if _, err := time.ParseDuration(args[0]); err != nil {
+			return "", fmt.Errorf("invalid duration %q", args[0])
+		}
+		return args[0], nil
+	default:
+		return "", fmt.Errorf("takes at most one duration")
+	}
+}
+
+// presetCountries validates a required list of ISO 3166-1 alpha-2 codes.
+func presetCountries(name string, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s needs country codes, e.g. %s:TH,SG", name, name)
+	}
+	cc := make([]string, 0, len(args))
+	for _, a := range args {
+		a = strings.ToUpper(a)
+		if !countryCode.MatchString(a) {
+			return nil, fmt.Errorf("invalid country code %q (want ISO 3166-1 alpha-2, e.g. TH)", a)
+		}
+		cc = append(cc, a)
+	}
+	return cc, nil
+}
+
+// celStringList renders a CEL list literal of quoted strings.
+func celStringList(xs []string) string {
+	q := make([]string, len(xs))
+	for i, x := range xs {
+		q[i] = strconv.Quote(x)
+	}
+	return "[" + strings.Join(q, ", ") + "]"
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+func TestParsePresets(t *testing.T) {
+	known := func(n string) bool { return lookupWAFPreset(n) != nil }
+
+	refs, err := parsePresets("login-rate-limit,block-bad-bots,geo-allow:TH,SG", known)
+	if err != nil {
+		t.Fatalf("parsePresets: %v", err)
+	}
+	want := []presetRef{
+		{name: "login-rate-limit"},
+		{name: "block-bad-bots"},
+		{name: "geo-allow", args: []string{"TH", "SG"}, open: true},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs = %+v; want %+v", refs, want)
+	}
+
+	for _, bad := range []string{"", "nope", "block-bad-bots,typo", "nope:x"} {
+		if _, err := parsePresets(bad, known); err == nil {
+			t.Errorf("parsePresets(%q) should fail", bad)
+		}
+	}
+}
+
+func TestBuildWAFPresets(t *testing.T) {
+	rules, limits, err := buildWAFPresets("login-rate-limit:/auth/login,geo-allow:th,SG")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if len(limits) != 1 || !strings.Contains(limits[0].Filter, `"/auth/login"`) {
+		t.Errorf("login-rate-limit limit = %+v", limits)
+	}
+	if len(rules) != 1 || rules[0].Expression != `!(request.country in ["TH", "SG"])` || rules[0].Action != api.WAFActionBlock {
+		t.Errorf("geo-allow rule = %+v", rules)
+	}
+	// the generated set must pass the api's structural validation
+	req := api.WAFSet{Project: "acme", Location: "loc", Rules: rules, Limits: limits}
+	if err := req.Valid(); err != nil {
+		t.Errorf("generated spec invalid: %v", err)
+	}
+
+	for _, bad := range []string{"geo-allow", "geo-block:THA", "login-rate-limit:login"} {
+		if _, _, err := buildWAFPresets(bad); err == nil {
+			t.Errorf("buildWAFPresets(%q) should fail", bad)
+		}
+	}
+}
+
+func TestBuildCachePresets(t *testing.T) {
+	overrides, err := buildCachePresets("static-assets:1h,api-no-cache")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	req := api.CacheSet{Project: "acme", Location: "loc", Overrides: overrides}
+	if err := req.Valid(); err != nil {
+		t.Errorf("generated spec invalid: %v", err)
+	}
+	if overrides[0].TTL != "1h" || overrides[1].Action != "bypass" {
+		t.Errorf("overrides = %+v", overrides)
+	}
+}
+
+// Merging into a live zone keeps its entries (and ids) and does not duplicate
+// a preset that was already applied.
+func TestMergeWAFPresetsIdempotent(t *testing.T) {
+	rules, limits, err := buildWAFPresets("block-bad-bots,login-rate-limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := api.WAFSet{Rules: []api.WAFRule{{ID: "keep", Description: "mine", Expression: "true"}}}
+	mergeWAFPresets(&req, rules, limits)
+	mergeWAFPresets(&req, rules, limits)
+	if len(req.Rules) != 2 || req.Rules[0].ID != "keep" || len(req.Limits) != 1 {
+		t.Errorf("merged = %+v", req)
+	}
+}
@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/deploys-app/api"
+)
+
+// refTypes are the resource types `deploys refs` (and the delete guard) can
+// reverse-look-up, keyed by every accepted spelling.
+var refTypes = map[string]string{
+	"deployment":       "deployment",
+	"deploy":           "deployment",
+	"d":                "deployment",
+	"envgroup":         "envgroup",
+	"eg":               "envgroup",
+	"pullsecret":       "pullsecret",
+	"ps":               "pullsecret",
+	"workloadidentity": "workloadidentity",
+	"wi":               "workloadidentity",
+	"disk":             "disk",
+}
+
+// resourceRef is one thing that still points at a resource.
+type resourceRef struct {
+	Kind     string `json:"kind" yaml:"kind"` // route|deployment|github
+	Name     string `json:"name" yaml:"name"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	Field    string `json:"field" yaml:"field"` // what in it refers to the resource
+}
+
+type refsResult struct {
+	Type    string        `json:"type" yaml:"type"`
+	Name    string        `json:"name" yaml:"name"`
+	Project string        `json:"project" yaml:"project"`
+	Refs    []resourceRef `json:"refs" yaml:"refs"`
+}
+
+func (r *refsResult) Table() [][]string {
+	table := [][]string{
+		{"KIND", "NAME", "LOCATION", "FIELD"},
+	}
+	for _, x := range r.Refs {
+		table = append(table, []string{x.Kind, x.Name, x.Location, x.Field})
+	}
+	return table
+}
+
+// findRefs scans a project for routes, deployments, and GitHub links that
+// reference the named resource. typ must be canonical (a refTypes value).
+// Location-scoped resources are only referenced from their own location;
+// location is ignored for env groups, which are project-wide.
+//
+// The api has no reverse index, so deployments are listed and then read one
+// by one (the list omits env groups, pull secret, identity, and disk).
+func findRefs(ctx context.Context, a api.Interface, typ, project, location, name string) ([]resourceRef, error) {
+	if typ == "envgroup" {
+		location = ""
+	}
+	refs := []resourceRef{}
+
+	if typ == "deployment" {
+		routes, err := a.Route().List(ctx, &api.RouteList{Project: project, Location: location})
+		if err != nil {
+			return nil, fmt.Errorf("list routes: %w", err)
+		}
+		for _, x := range routes.Items {
+			if x.Location != location && location != "" {
+				continue
+			}
+			if x.Deployment == name || x.Target == "deployment://"+name {
+				refs = append(refs, resourceRef{Kind: "route", Name: x.Domain + x.Path, Location: x.Location, Field: "target"})
+			}
+		}
+	} else {
+		list, err := a.Deployment().List(ctx, &api.DeploymentList{Project: project, Location: location})
+		if err != nil {
+			return nil, fmt.Errorf("list deployments: %w", err)
+		}
+		for _, x := range list.Items {
+			d, err := a.Deployment().Get(ctx, &api.DeploymentGet{Project: project, Location: x.Location, Name: x.Name})
+			if err != nil {
+				return nil, fmt.Errorf("get deployment %s: %w", x.Name, err)
+			}
+			var field string
+			switch {
+			case typ == "envgroup" && slices.Contains(d.EnvGroups, name):
+				field = "envGroups"
+			case typ == "pullsecret" && d.PullSecret == name:
+				field = "pullSecret"
+			case typ == "workloadidentity" && d.WorkloadIdentity == name:
+				field = "workloadIdentity"
+			case typ == "disk" && d.Disk != nil && d.Disk.Name == name:
+				field = "disk"
+			default:
+				continue
+			}
+			refs = append(refs, resourceRef{Kind: "deployment", Name: d.Name, Location: d.Location, Field: field})
+		}
+	}
+
+	// GitHub links carry the console's saved workflow inputs, which name a
+	// deployment and the env groups and pull secret it is deployed with.
+	if typ == "deployment" || typ == "envgroup" || typ == "pullsecret" {
+		links, err := a.GitHub().List(ctx, &api.GitHubList{Project: project})
+		if err != nil {
+			return nil, fmt.Errorf("list github links: %w", err)
+		}
+		for _, x := range links.Items {
+			w := x.WorkflowConfig
+			if w == nil || (location != "" && w.Location != "" && w.Location != location) {
+				continue
+			}
+			var field string
+			switch {
+			case typ == "deployment" && w.Name == name:
+				field = "workflowConfig.name"
+			case typ == "envgroup" && slices.Contains(w.EnvGroups, name):
+				field = "workflowConfig.envGroups"
+			case typ == "pullsecret" && w.PullSecret == name:
+				field = "workflowConfig.pullSecret"
+			default:
+				continue
+			}
+			refs = append(refs, resourceRef{Kind: "github", Name: x.Repository, Location: w.Location, Field: field})
+		}
+	}
+	return refs, nil
+}
+
+// checkRefs guards a delete: it refuses while anything still references the
+// resource, listing the references on stderr. force skips the scan. Callers
+// validate the delete request first, since an empty name would match every
+// resource that references nothing of its type.
+func (rn Runner) checkRefs(typ, project, location, name string, force bool) error {
+	if force {
+		return nil
+	}
+	if name == "" {
+		return fmt.Errorf("name required")
+	}
+	refs, err := findRefs(rn.ctx(), rn.API, typ, project, location, name)
+	if err != nil {
+		return fmt.Errorf("could not check what references %s %q: %w (use -force to delete without checking)", typ, name, err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%s %q is still referenced by:\n", typ, name)
+	for _, r := range refs {
+		loc := ""
+		if r.Location != "" {
+			loc = " (" + r.Location + ")"
+		}
+		fmt.Fprintf(os.Stderr, "  %s %s%s: %s\n", r.Kind, r.Name, loc, r.Field)
+	}
+	return fmt.Errorf("refusing to delete %s %q: %s left (use -force to delete anyway)", typ, name, pluralRefs(len(refs)))
+}
+
+func pluralRefs(n int) string {
+	if n == 1 {
+		return "1 reference"
+	}
+	return strconv.Itoa(n) + " references"
+}
+
+// refs handles `deploys refs <type> <name>`: the delete guard's reverse lookup
+// on its own.
+func (rn Runner) refs(args ...string) error {
+	_, run := rn.refsLeaf()
+	return run(args)
+}
+
+func (rn *Runner) refsLeaf() (*flag.FlagSet, func(args []string) error) {
+	f := rn.standaloneFlagSet("refs", writeRefsUsage)
+	var res refsResult
+	var location string
+	f.StringVar(&res.Project, "project", "", "project id")
+	f.StringVar(&location, "location", "", "location (for location-scoped resources)")
+	return f, func(args []string) error {
+		if len(args) == 0 || IsHelpArg(args[0]) {
+			writeRefsUsage(rn.output())
+			return nil
+		}
+
+		typ, ok := refTypes[args[0]]
+		if !ok {
+			return fmt.Errorf("refs: unknown type %q (want deployment, envgroup, pullsecret, workloadidentity, or disk)", args[0])
+		}
+		rest := args[1:]
+		if len(rest) > 0 && !isFlag(rest[0]) {
+			res.Name, rest = rest[0], rest[1:]
+		}
+		f.Parse(rest)
+		rn.scope.fillFlags("refs", f)
+		if res.Name == "" {
+			res.Name = f.Arg(0)
+		}
+		if res.Name == "" {
+			return fmt.Errorf("refs: name required (deploys refs %s <name>)", typ)
+		}
+		res.Type = typ
+
+		var err error
+		res.Refs, err = findRefs(rn.ctx(), rn.API, typ, res.Project, location, res.Name)
+		if err != nil {
+			return err
+		}
+		return rn.print(&res)
+	}
+}
+
+func writeRefsUsage(w io.Writer) {
+	fmt.Fprint(w, "refs — list what still references a resource (routes, deployments, GitHub links)\n\n")
+	fmt.Fprint(w, "Usage:\n  deploys refs deployment|envgroup|pullsecret|workloadidentity|disk <name> -project p [-location l]\n")
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+// refsAPI serves just the lists findRefs reads.
+type refsAPI struct {
+	api.Interface
+	routes      []*api.RouteItem
+	deployments []*api.DeploymentItem
+	links       []*api.GitHubLinkItem
+}
+
+func (a *refsAPI) Route() api.Route           { return refsRoute{a: a} }
+func (a *refsAPI) Deployment() api.Deployment { return refsDeployment{a: a} }
+func (a *refsAPI) GitHub() api.GitHub         { return refsGitHub{a: a} }
+
+type refsRoute struct {
+	api.Route
+	a *refsAPI
+}
+
+func (r refsRoute) List(context.Context, *api.RouteList) (*api.RouteListResult, error) {
+	return &api.RouteListResult{Items: r.a.routes}, nil
+}
+
+type refsDeployment struct {
+	api.Deployment
+	a *refsAPI
+}
+
+func (r refsDeployment) List(_ context.Context, m *api.DeploymentList) (*api.DeploymentListResult, error) {
+	var res api.DeploymentListResult
+	for _, d := range r.a.deployments {
+		if m.Location == "" || d.Location == m.Location {
+			res.Items = append(res.Items, &api.DeploymentListItem{Name: d.Name, Location: d.Location})
+		}
+	}
+	return &res, nil
+}
+
+func (r refsDeployment) Get(_ context.Context, m *api.DeploymentGet) (*api.DeploymentItem, error) {
+	for _, d := range r.a.deployments {
+		if d.Name == m.Name && d.Location == m.Location {
+			return d, nil
+		}
+	}
+	return nil, api.ErrDeploymentNotFound
+}
+
+type refsGitHub struct {
+	api.GitHub
+	a *refsAPI
+}
+
+func (r refsGitHub) List(context.Context, *api.GitHubList) (*api.GitHubListResult, error) {
+	return &api.GitHubListResult{Items: r.a.links}, nil
+}
+
+func newRefsAPI() *refsAPI {
+	return &refsAPI{
+		routes: []*api.RouteItem{
+			{Location: "loc", Domain: "example.com", Path: "/", Target: "deployment://web"},
+			{Location: "loc", Domain: "example.com", Path: "/v1", Deployment: "web"},
+			{Location: "loc", Domain: "example.com", Path: "/api", Target: "deployment://api"},
+		},
+		deployments: []*api.DeploymentItem{
+			{Name: "web", Location: "loc", EnvGroups: []string{"shared"}, PullSecret: "ghcr"},
+			{Name: "worker", Location: "loc", EnvGroups: []string{"shared", "worker"}, Disk: &api.DeploymentDisk{Name: "data"}},
+			{Name: "web", Location: "other", EnvGroups: []string{"shared"}, PullSecret: "ghcr"},
+		},
+		links: []*api.GitHubLinkItem{
+			{Repository: "acme/web", WorkflowConfig: &api.GitHubWorkflowConfig{Name: "web", Location: "loc", EnvGroups: []string{"shared"}}},
+			{Repository: "acme/docs"},
+		},
+	}
+}
+
+func TestFindRefs(t *testing.T) {
+	a := newRefsAPI()
+	ctx := context.Background()
+
+	refs, err := findRefs(ctx, a, "deployment", "acme", "loc", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []resourceRef{
+		{Kind: "route", Name: "example.com/", Location: "loc", Field: "target"},
+		{Kind: "route", Name: "example.com/v1", Location: "loc", Field: "target"},
+		{Kind: "github", Name: "acme/web", Location: "loc", Field: "workflowConfig.name"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("deployment refs = %+v\nwant %+v", refs, want)
+	}
+
+	// env groups are project-wide: every location's deployments count
+	refs, _ = findRefs(ctx, a, "envgroup", "acme", "loc", "shared")
+	if len(refs) != 4 {
+		t.Errorf("envgroup refs = %+v; want 3 deployments and 1 github link", refs)
+	}
+	// a pull secret is location-scoped
+	refs, _ = findRefs(ctx, a, "pullsecret", "acme", "loc", "ghcr")
+	if len(refs) != 1 || refs[0].Name != "web" || refs[0].Field != "pullSecret" {
+		t.Errorf("pullsecret refs = %+v", refs)
+	}
+	refs, _ = findRefs(ctx, a, "disk", "acme", "loc", "data")
+	if len(refs) != 1 || refs[0].Name != "worker" {
+		t.Errorf("disk refs = %+v", refs)
+	}
+	refs, _ = findRefs(ctx, a, "workloadidentity", "acme", "loc", "none")
+	if refs == nil || len(refs) != 0 {
+		t.Errorf("unreferenced = %#v; want empty, non-nil", refs)
+	}
+}
+
+func TestCheckRefs(t *testing.T) {
+	rn := Runner{API: newRefsAPI()}
+	err := rn.checkRefs("disk", "acme", "loc", "data", false)
+	if err == nil || !strings.Contains(err.Error(), "1 reference") || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("err = %v; want a refusal naming -force", err)
+	}
+	if err := rn.checkRefs("disk", "acme", "loc", "data", true); err != nil {
+		t.Errorf("-force: %v", err)
+	}
+	if err := rn.checkRefs("disk", "acme", "loc", "scratch", false); err != nil {
+		t.Errorf("unreferenced disk: %v", err)
+	}
+
+	// a delete without a name fails validation, not on unrelated references
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	rn = Runner{API: offlineAPI(), Output: tempOut(t)}
+	err = rn.Run("disk", "delete", "-project", "acme", "-location", "loc", "-yes")
+	if err == nil || strings.Contains(err.Error(), "refusing") || !strings.Contains(err.Error(), "name") {
+		t.Errorf("empty name: %v", err)
+	}
+}
@@ -1,81 +1,142 @@
 package runner
 
 import (
-	"context"
-
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) registry(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("registry")
-	}
+	return rn.runGroup("registry", args, rn.registryLeaf)
+}
 
+func (rn *Runner) registryLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Registry()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("registry", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("registry", args[0])
+	switch sub {
 	case "list":
 		var req api.RegistryList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.RegistryGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "tags":
 		var req api.RegistryGetTags
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
-		f.Parse(args[1:])
-		resp, err = s.GetTags(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.GetTags(rn.ctx(), &req)
+		}
 	case "manifests":
 		var req api.RegistryGetManifests
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
-		f.Parse(args[1:])
-		resp, err = s.GetManifests(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.GetManifests(rn.ctx(), &req)
+		}
 	case "storage":
 		var req api.RegistryGetProjectStorage
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.GetProjectStorage(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.GetProjectStorage(rn.ctx(), &req)
+		}
 	case "delete":
-		var req api.RegistryDelete
+		var (
+			req   api.RegistryDelete
+			yes   bool
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+		f.BoolVar(&force, "force", false, "allow deleting a protected repository")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.confirmDestructive("registry", "delete", req.Project, req.Repository, yes, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("registry delete", journalTarget{Project: req.Project, Name: req.Repository}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "deletemanifest":
 		var req api.RegistryDeleteManifest
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
 		f.StringVar(&req.Digest, "digest", "", "manifest digest")
-		f.Parse(args[1:])
-		resp, err = s.DeleteManifest(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("registry deletemanifest", journalTarget{Project: req.Project, Name: req.Repository + "@" + req.Digest}, &req)
+			resp, err = s.DeleteManifest(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "untag":
 		var req api.RegistryUntag
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Repository, "repository", "", "repository name")
 		f.StringVar(&req.Tag, "tag", "", "tag")
-		f.Parse(args[1:])
-		resp, err = s.Untag(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("registry untag", journalTarget{Project: req.Project, Name: req.Repository + ":" + req.Tag}, &req)
+			resp, err = s.Untag(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "gc":
-		var req api.RegistryGC
+		var (
+			req   api.RegistryGC
+			yes   bool
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.BoolVar(&req.DryRun, "dry-run", false, "preview what would be removed without deleting")
-		f.Parse(args[1:])
-		resp, err = s.GC(context.Background(), &req)
+		f.BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+		f.BoolVar(&force, "force", false, "allow collecting in a protected project")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if !req.DryRun {
+				// gc acts on the whole project's registry, so the project id is
+				// the name to confirm
+				if err := rn.confirmDestructive("registry", "gc", req.Project, req.Project, yes, force); err != nil {
+					return nil, err
+				}
+			}
+			var o *journalOp
+			if !req.DryRun {
+				o = rn.journalBegin("registry gc", journalTarget{Project: req.Project}, &req)
+			}
+			resp, err = s.GC(rn.ctx(), &req)
+			if o != nil {
+				o.done(err)
+			}
+			return resp, err
+		}
 	case "metrics":
 		var (
 			req       api.RegistryMetrics
@@ -83,12 +144,12 @@ func (rn Runner) registry(args ...string) error {
 		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&timeRange, "time-range", "30d", "time range (7d, 30d, 90d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.UsageMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.TimeRange = api.UsageMetricsTimeRange(timeRange)
+			return s.Metrics(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
@@ -0,0 +1,381 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+// Reports summarize the raw metrics time series that `waf metrics`, `waf
+// limitmetrics`, and `cache metrics` return. The structs carry the computed
+// summary for -ojson/-oyaml (so it can be alerted on); the table view adds an
+// ASCII sparkline per row for the chosen -time-range.
+
+// sparkWidth is the number of columns a sparkline is bucketed into.
+const sparkWidth = 24
+
+// sparkLevels is the ASCII ramp a sparkline is drawn with, lowest first. "_"
+// (not a space) marks an empty bucket so a sparkline never renders blank.
+const sparkLevels = "_.:-=+*#%@"
+
+// sparkline renders time-ordered [unixSeconds, value] points as an ASCII
+// sparkline of at most width columns, summing the points that share a column.
+// It is scaled to its own maximum, so it shows shape, not magnitude.
+func sparkline(points [][2]float64, width int) string {
+	if len(points) == 0 || width <= 0 {
+		return ""
+	}
+	first, last := points[0][0], points[len(points)-1][0]
+	n := min(width, len(points))
+	buckets := make([]float64, n)
+	for _, p := range points {
+		i := 0
+		if last > first {
+			i = int((p[0] - first) / (last - first) * float64(n-1))
+		}
+		buckets[i] += p[1]
+	}
+	var top float64
+	for _, b := range buckets {
+		top = math.Max(top, b)
+	}
+	var sb strings.Builder
+	for _, b := range buckets {
+		lvl := 0
+		if top > 0 && b > 0 {
+			// any non-zero bucket is at least the first visible level
+			lvl = 1 + int(b/top*float64(len(sparkLevels)-2))
+		}
+		sb.WriteByte(sparkLevels[lvl])
+	}
+	return sb.String()
+}
+
+// sumSeries adds several time series point-wise by timestamp.
+func sumSeries(series ...[][2]float64) [][2]float64 {
+	sum := map[float64]float64{}
+	for _, s := range series {
+		for _, p := range s {
+			sum[p[0]] += p[1]
+		}
+	}
+	res := make([][2]float64, 0, len(sum))
+	for t, v := range sum {
+		res = append(res, [2]float64{t, v})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+// ratio returns a/b, or 0 when b is 0.
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+func formatPercent(r float64) string {
+	return strconv.FormatFloat(r*100, 'f', 1, 64) + "%"
+}
+
+func formatCount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// reportRow is one line of a report table. points only feed the sparkline.
+type reportRow struct {
+	metric string
+	total  string
+	share  string
+	points [][2]float64
+	note   string
+}
+
+func reportTable(rows []reportRow) [][]string {
+	table := [][]string{
+		{"METRIC", "TOTAL", "SHARE", "TREND", "NOTE"},
+	}
+	for _, r := range rows {
+		table = append(table, []string{r.metric, r.total, r.share, sparkline(r.points, sparkWidth), r.note})
+	}
+	return table
+}
+
+// wafReport is `waf metrics -report`: how matched requests split by action and
+// which rules match most.
+type wafReport struct {
+	TimeRange    api.WAFMetricsTimeRange `json:"timeRange" yaml:"timeRange"`
+	Matched      float64                 `json:"matched" yaml:"matched"`
+	Blocked      float64                 `json:"blocked" yaml:"blocked"`
+	Allowed      float64                 `json:"allowed" yaml:"allowed"`
+	Logged       float64                 `json:"logged" yaml:"logged"`
+	BlockedRatio float64                 `json:"blockedRatio" yaml:"blockedRatio"` // blocked / matched
+	Rules        []wafReportRule         `json:"rules" yaml:"rules"`               // most matched first
+
+	series map[string][][2]float64
+}
+
+type wafReportRule struct {
+	RuleID string  `json:"ruleId" yaml:"ruleId"`
+	Action string  `json:"action" yaml:"action"`
+	Total  float64 `json:"total" yaml:"total"`
+	Share  float64 `json:"share" yaml:"share"` // of all matches
+
+	points [][2]float64
+}
+
+func newWAFReport(timeRange api.WAFMetricsTimeRange, res *api.WAFMetricsResult) *wafReport {
+	r := &wafReport{TimeRange: timeRange, series: map[string][][2]float64{}}
+	byAction := map[string][][][2]float64{}
+	for _, s := range res.Series {
+		r.Matched += s.Total
+		switch s.Action {
+		case "block":
+			r.Blocked += s.Total
+		case "allow":
+			r.Allowed += s.Total
+		default:
+			r.Logged += s.Total
+		}
+		byAction[s.Action] = append(byAction[s.Action], s.Points)
+		r.Rules = append(r.Rules, wafReportRule{RuleID: s.RuleID, Action: s.Action, Total: s.Total, points: s.Points})
+	}
+	for a, ss := range byAction {
+		r.series[a] = sumSeries(ss...)
+	}
+	r.BlockedRatio = ratio(r.Blocked, r.Matched)
+	for i := range r.Rules {
+		r.Rules[i].Share = ratio(r.Rules[i].Total, r.Matched)
+	}
+	sort.SliceStable(r.Rules, func(i, j int) bool { return r.Rules[i].Total > r.Rules[j].Total })
+	return r
+}
+
+func (r *wafReport) Table() [][]string {
+	var all [][][2]float64
+	for _, s := range r.series {
+		all = append(all, s)
+	}
+	rows := []reportRow{
+		{metric: "matched", total: formatCount(r.Matched), points: sumSeries(all...)},
+		{metric: "blocked", total: formatCount(r.Blocked), share: formatPercent(r.BlockedRatio), points: r.series["block"]},
+		{metric: "allowed", total: formatCount(r.Allowed), share: formatPercent(ratio(r.Allowed, r.Matched)), points: r.series["allow"]},
+		{metric: "logged", total: formatCount(r.Logged), share: formatPercent(ratio(r.Logged, r.Matched)), points: r.series["log"]},
+	}
+	for _, x := range r.Rules {
+		rows = append(rows, reportRow{
+			metric: "rule " + x.RuleID + " (" + x.Action + ")",
+			total:  formatCount(x.Total),
+			share:  formatPercent(x.Share),
+			points: x.points,
+		})
+	}
+	return reportTable(rows)
+}
+
+// wafLimitReport is `waf limitmetrics -report`: per limit, the share of its
+// decisions that were limited, flagging limits at or above Threshold.
+type wafLimitReport struct {
+	TimeRange api.WAFMetricsTimeRange `json:"timeRange" yaml:"timeRange"`
+	Threshold float64                 `json:"threshold" yaml:"threshold"`
+	Allowed   float64                 `json:"allowed" yaml:"allowed"`
+	Limited   float64                 `json:"limited" yaml:"limited"`
+	Limits    []wafLimitReportItem    `json:"limits" yaml:"limits"` // most limited share first
+	Hot       []string                `json:"hot" yaml:"hot"`       // ids of limits at/over Threshold
+}
+
+type wafLimitReportItem struct {
+	LimitID      string  `json:"limitId" yaml:"limitId"`
+	Allowed      float64 `json:"allowed" yaml:"allowed"`
+	Limited      float64 `json:"limited" yaml:"limited"`
+	LimitedRatio float64 `json:"limitedRatio" yaml:"limitedRatio"` // limited / (allowed + limited)
+	Hot          bool    `json:"hot" yaml:"hot"`
+
+	points [][2]float64 // limited decisions over time
+}
+
+func newWAFLimitReport(timeRange api.WAFMetricsTimeRange, threshold float64, res *api.WAFLimitMetricsResult) *wafLimitReport {
+	r := &wafLimitReport{TimeRange: timeRange, Threshold: threshold, Hot: []string{}}
+	idx := map[string]int{}
+	for _, s := range res.Series {
+		i, ok := idx[s.LimitID]
+		if !ok {
+			i = len(r.Limits)
+			idx[s.LimitID] = i
+			r.Limits = append(r.Limits, wafLimitReportItem{LimitID: s.LimitID})
+		}
+		it := &r.Limits[i]
+		if s.Result == "limited" {
+			it.Limited += s.Total
+			it.points = sumSeries(it.points, s.Points)
+			r.Limited += s.Total
+		} else {
+			it.Allowed += s.Total
+			r.Allowed += s.Total
+		}
+	}
+	for i := range r.Limits {
+		it := &r.Limits[i]
+		it.LimitedRatio = ratio(it.Limited, it.Allowed+it.Limited)
+		it.Hot = it.Limited > 0 && it.LimitedRatio >= threshold
+	}
+	sort.SliceStable(r.Limits, func(i, j int) bool { return r.Limits[i].LimitedRatio > r.Limits[j].LimitedRatio })
+	for _, it := range r.Limits {
+		if it.Hot {
+			r.Hot = append(r.Hot, it.LimitID)
+		}
+	}
+	return r
+}
+
+func (r *wafLimitReport) Table() [][]string {
+	var all [][][2]float64
+	for _, x := range r.Limits {
+		all = append(all, x.points)
+	}
+	rows := []reportRow{
+		{metric: "decisions", total: formatCount(r.Allowed + r.Limited)},
+		{metric: "limited", total: formatCount(r.Limited), share: formatPercent(ratio(r.Limited, r.Allowed+r.Limited)), points: sumSeries(all...)},
+	}
+	for _, x := range r.Limits {
+		row := reportRow{
+			metric: "limit " + x.LimitID,
+			total:  formatCount(x.Limited) + "/" + formatCount(x.Allowed+x.Limited),
+			share:  formatPercent(x.LimitedRatio),
+			points: x.points,
+		}
+		if x.Hot {
+			row.note = "frequently limited (>= " + formatPercent(r.Threshold) + ")"
+		}
+		rows = append(rows, row)
+	}
+	return reportTable(rows)
+}
+
+// cacheReport is `cache metrics -report`: the project's edge hit ratio and the
+// bytes served from cache (from cache.resultMetrics), plus which overrides
+// keep requests out of the cache (from cache.metrics). The api has no per-path
+// breakdown, so uncached traffic is attributed to the bypass overrides (and
+// misses) that cause it.
+type cacheReport struct {
+	TimeRange  api.WAFMetricsTimeRange `json:"timeRange" yaml:"timeRange"`
+	Requests   float64                 `json:"requests" yaml:"requests"`
+	Results    map[string]float64      `json:"results" yaml:"results"`       // requests by HIT/MISS/STALE/BYPASS
+	HitRatio   float64                 `json:"hitRatio" yaml:"hitRatio"`     // (HIT + STALE) / requests
+	BytesSaved float64                 `json:"bytesSaved" yaml:"bytesSaved"` // bytes served from cache (HIT + STALE)
+	BytesTotal float64                 `json:"bytesTotal" yaml:"bytesTotal"`
+	Overrides  []cacheReportOverride   `json:"overrides" yaml:"overrides"` // bypass (uncached) first, then by decisions
+
+	resultPoints map[string][][2]float64
+	savedPoints  [][2]float64
+}
+
+type cacheReportOverride struct {
+	OverrideID string  `json:"overrideId" yaml:"overrideId"`
+	Action     string  `json:"action" yaml:"action"`
+	Applied    float64 `json:"applied" yaml:"applied"`
+	Shadow     float64 `json:"shadow" yaml:"shadow"`
+	Errors     float64 `json:"errors" yaml:"errors"`
+
+	points [][2]float64
+}
+
+func newCacheReport(timeRange api.WAFMetricsTimeRange, results *api.CacheResultMetricsResult, overrides *api.CacheMetricsResult) *cacheReport {
+	r := &cacheReport{
+		TimeRange:    timeRange,
+		Results:      map[string]float64{},
+		resultPoints: map[string][][2]float64{},
+	}
+	var saved [][][2]float64
+	for _, s := range results.Series {
+		r.Requests += s.RequestsTotal
+		r.Results[s.Result] += s.RequestsTotal
+		r.BytesTotal += s.BytesTotal
+		r.resultPoints[s.Result] = sumSeries(r.resultPoints[s.Result], s.Requests)
+		if s.Result == "HIT" || s.Result == "STALE" {
+			r.BytesSaved += s.BytesTotal
+			saved = append(saved, s.Bytes)
+		}
+	}
+	r.HitRatio = ratio(r.Results["HIT"]+r.Results["STALE"], r.Requests)
+	r.savedPoints = sumSeries(saved...)
+
+	idx := map[string]int{}
+	for _, s := range overrides.Series {
+		key := s.OverrideID + "\x00" + s.Action
+		i, ok := idx[key]
+		if !ok {
+			i = len(r.Overrides)
+			idx[key] = i
+			r.Overrides = append(r.Overrides, cacheReportOverride{OverrideID: s.OverrideID, Action: s.Action})
+		}
+		o := &r.Overrides[i]
+		switch s.Result {
+		case "applied":
+			o.Applied += s.Total
+		case "shadow":
+			o.Shadow += s.Total
+		default:
+			o.Errors += s.Total
+		}
+		o.points = sumSeries(o.points, s.Points)
+	}
+	sort.SliceStable(r.Overrides, func(i, j int) bool {
+		a, b := r.Overrides[i], r.Overrides[j]
+		if (a.Action == "bypass") != (b.Action == "bypass") {
+			return a.Action == "bypass"
+		}
+		return a.Applied+a.Shadow+a.Errors > b.Applied+b.Shadow+b.Errors
+	})
+	return r
+}
+
+func (r *cacheReport) Table() [][]string {
+	var all [][][2]float64
+	for _, s := range r.resultPoints {
+		all = append(all, s)
+	}
+	rows := []reportRow{
+		{metric: "requests", total: formatCount(r.Requests), points: sumSeries(all...)},
+		{metric: "hit ratio", share: formatPercent(r.HitRatio), points: sumSeries(r.resultPoints["HIT"], r.resultPoints["STALE"])},
+	}
+	for _, res := range []string{"HIT", "STALE", "MISS", "BYPASS"} {
+		if _, ok := r.Results[res]; !ok {
+			continue
+		}
+		rows = append(rows, reportRow{
+			metric: strings.ToLower(res),
+			total:  formatCount(r.Results[res]),
+			share:  formatPercent(ratio(r.Results[res], r.Requests)),
+			points: r.resultPoints[res],
+		})
+	}
+	rows = append(rows, reportRow{
+		metric: "bandwidth saved",
+		total:  humanByteSize(int64(r.BytesSaved)),
+		share:  formatPercent(ratio(r.BytesSaved, r.BytesTotal)),
+		points: r.savedPoints,
+	})
+	for _, o := range r.Overrides {
+		row := reportRow{
+			metric: "override " + o.OverrideID + " (" + o.Action + ")",
+			total:  formatCount(o.Applied + o.Shadow + o.Errors),
+			points: o.points,
+		}
+		var notes []string
+		if o.Shadow > 0 {
+			notes = append(notes, fmt.Sprintf("%s shadow", formatCount(o.Shadow)))
+		}
+		if o.Errors > 0 {
+			notes = append(notes, fmt.Sprintf("%s errors", formatCount(o.Errors)))
+		}
+		row.note = strings.Join(notes, ", ")
+		rows = append(rows, row)
+	}
+	return reportTable(rows)
+}
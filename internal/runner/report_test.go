@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+func TestSparkline(t *testing.T) {
+	cases := []struct {
+		points [][2]float64
+		width  int
+		want   string
+	}{
+		{nil, 10, ""},
+		{[][2]float64{{0, 0}, {60, 0}}, 10, "__"},
+		{[][2]float64{{0, 0}, {60, 1}, {120, 9}}, 10, "_.@"},
+		// 4 points into 2 columns: each column sums two points
+		{[][2]float64{{0, 1}, {60, 1}, {120, 0}, {180, 0}}, 2, "@_"},
+	}
+	for _, c := range cases {
+		if got := sparkline(c.points, c.width); got != c.want {
+			t.Errorf("sparkline(%v, %d) = %q; want %q", c.points, c.width, got, c.want)
+		}
+	}
+}
+
+func TestCacheReport(t *testing.T) {
+	results := &api.CacheResultMetricsResult{Series: []*api.CacheResultSeries{
+		{Result: "HIT", Requests: [][2]float64{{0, 60}, {60, 10}}, RequestsTotal: 70, Bytes: [][2]float64{{0, 600}}, BytesTotal: 600},
+		{Result: "STALE", RequestsTotal: 10, BytesTotal: 100},
+		{Result: "MISS", RequestsTotal: 15, BytesTotal: 300},
+		{Result: "BYPASS", RequestsTotal: 5},
+	}}
+	overrides := &api.CacheMetricsResult{Series: []*api.CacheMetricsSeries{
+		{OverrideID: "static", Action: "cache", Result: "applied", Total: 50},
+		{OverrideID: "api", Action: "bypass", Result: "applied", Total: 4},
+		{OverrideID: "api", Action: "bypass", Result: "shadow", Total: 1},
+	}}
+	r := newCacheReport("1h", results, overrides)
+	if r.Requests != 100 || r.HitRatio != 0.8 || r.BytesSaved != 700 || r.BytesTotal != 1000 {
+		t.Errorf("report = %+v", r)
+	}
+	if len(r.Overrides) != 2 || r.Overrides[0].OverrideID != "api" || r.Overrides[0].Applied != 4 || r.Overrides[0].Shadow != 1 {
+		t.Errorf("bypass overrides should sort first and merge by id: %+v", r.Overrides)
+	}
+
+	table := r.Table()
+	if table[2][0] != "hit ratio" || table[2][2] != "80.0%" {
+		t.Errorf("hit ratio row = %v", table[2])
+	}
+
+	// the json form carries the computed summary, not the table
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"hitRatio":0.8`) || !strings.Contains(string(b), `"bytesSaved":700`) {
+		t.Errorf("json = %s", b)
+	}
+}
+
+func TestWAFLimitReportHot(t *testing.T) {
+	res := &api.WAFLimitMetricsResult{Series: []*api.WAFLimitMetricsSeries{
+		{LimitID: "login", Result: "allowed", Total: 90},
+		{LimitID: "login", Result: "limited", Total: 10, Points: [][2]float64{{0, 10}}},
+		{LimitID: "api", Result: "allowed", Total: 999},
+		{LimitID: "api", Result: "limited", Total: 1},
+	}}
+	r := newWAFLimitReport("1h", 0.05, res)
+	if len(r.Hot) != 1 || r.Hot[0] != "login" {
+		t.Errorf("hot = %v; want [login]", r.Hot)
+	}
+	if r.Limits[0].LimitID != "login" || r.Limits[0].LimitedRatio != 0.1 {
+		t.Errorf("limits = %+v", r.Limits)
+	}
+	if note := r.Table()[3][4]; !strings.Contains(note, "frequently limited") {
+		t.Errorf("hot limit note = %q", note)
+	}
+}
+
+func TestWAFReport(t *testing.T) {
+	res := &api.WAFMetricsResult{Series: []*api.WAFMetricsSeries{
+		{RuleID: "bots", Action: "block", Total: 30},
+		{RuleID: "office", Action: "allow", Total: 60},
+		{RuleID: "audit", Action: "log", Total: 10},
+	}}
+	r := newWAFReport("1h", res)
+	if r.Matched != 100 || r.BlockedRatio != 0.3 || r.Rules[0].RuleID != "office" {
+		t.Errorf("report = %+v", r)
+	}
+}
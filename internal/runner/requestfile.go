@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const requestFileUsage = "read the request from a yaml or json file (- for stdin); flags override its values"
+
+// notRequestFlags shape the output, not the request; a request file never
+// sets them.
+var notRequestFlags = map[string]bool{
+	"f": true, "output": true, "columns": true, "sort-by": true, "no-headers": true, "wide": true, "watch": true, "interval": true,
+}
+
+// stdinRequest is stdin read once: a -watch refresh parses the flags again.
+var stdinRequest struct {
+	once sync.Once
+	b    []byte
+	err  error
+}
+
+func readRequestFile(fn string) ([]byte, error) {
+	if fn != "-" {
+		return os.ReadFile(fn)
+	}
+	stdinRequest.once.Do(func() {
+		stdinRequest.b, stdinRequest.err = io.ReadAll(os.Stdin)
+	})
+	return stdinRequest.b, stdinRequest.err
+}
+
+// applyRequestFile decodes the request file args give -f (yaml, or json —
+// the -ojson shape replays as-is) into req, and returns its keys that name one
+// of f's flags not set in args as flag tokens, for parsing ahead of args. The
+// tokens let a command that builds part of its request from flags after
+// parsing (a KEY=VALUE list, a comma-separated value, a time) see the file's
+// values too; an explicit flag replaces the file's value, repeatable ones
+// included. Without -f it does nothing.
+func applyRequestFile(f *flag.FlagSet, args []string, req any) ([]string, error) {
+	explicit := argFlags(f, args)
+	fn := explicit["f"]
+	if fn == "" {
+		return nil, nil
+	}
+	b, err := readRequestFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	name := fn
+	if fn == "-" {
+		name = "stdin"
+	}
+	var m map[string]any
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		// encoding/json understands the api's `json:",string"` ids
+		err = json.Unmarshal(b, req)
+		if err == nil {
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			err = dec.Decode(&m)
+		}
+	} else {
+		err = yaml.Unmarshal(b, req)
+		if err == nil {
+			err = yaml.Unmarshal(b, &m)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request file %s: %w", name, err)
+	}
+	return requestFlagArgs(f, m, explicit), nil
+}
+
+// requestFlagArgs returns "-flag=value" tokens for the keys of m that name a
+// flag of f not in skip, ignoring case, hyphens, and underscores (timeRange is
+// -time-range). A scalar sets the flag; a list sets a repeatable flag once per
+// item, or a plain one to the comma-joined items; a map sets a repeatable
+// flag once per KEY=VALUE. Other values are left to the decoded request.
+func requestFlagArgs(f *flag.FlagSet, m map[string]any, skip map[string]string) []string {
+	flags := map[string]*flag.Flag{}
+	f.VisitAll(func(fl *flag.Flag) {
+		if _, ok := skip[fl.Name]; !ok && !notRequestFlags[fl.Name] {
+			flags[flagKey(fl.Name)] = fl
+		}
+	})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var args []string
+	for _, k := range keys {
+		fl := flags[flagKey(k)]
+		if fl == nil {
+			continue
+		}
+		set := func(v string) { args = append(args, "-"+fl.Name+"="+v) }
+		_, repeatable := fl.Value.(*multiFlag)
+		switch v := m[k].(type) {
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, x := range v {
+				s, ok := scalarString(x)
+				if !ok || (!repeatable && strings.Contains(s, ",")) {
+					items = nil
+					break
+				}
+				items = append(items, s)
+			}
+			switch {
+			case items == nil:
+			case repeatable:
+				for _, s := range items {
+					set(s)
+				}
+			default:
+				set(strings.Join(items, ","))
+			}
+		case map[string]any, map[any]any:
+			if !repeatable {
+				continue
+			}
+			for _, kv := range mapPairs(v) {
+				set(kv)
+			}
+		default:
+			if s, ok := scalarString(v); ok {
+				set(s)
+			}
+		}
+	}
+	return args
+}
+
+// flagKey normalizes a flag or field name for matching.
+func flagKey(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(s))
+}
+
+func scalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
+
+// mapPairs renders a map of scalars as sorted KEY=VALUE pairs.
+func mapPairs(v any) []string {
+	var res []string
+	add := func(k, x any) {
+		ks, ok1 := scalarString(k)
+		xs, ok2 := scalarString(x)
+		if ok1 && ok2 {
+			res = append(res, ks+"="+xs)
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			add(k, x)
+		}
+	case map[any]any:
+		for k, x := range v {
+			add(k, x)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// argFlags returns the flags args set and their last values, read the way f
+// will parse them: up to "--" or the first non-flag argument, skipping the
+// values of f's non-bool flags.
+func argFlags(f *flag.FlagSet, args []string) map[string]string {
+	set := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || !isFlag(a) {
+			break
+		}
+		n, ok := flagName(a)
+		if !ok {
+			break
+		}
+		_, value, hasValue := strings.Cut(a, "=")
+		fl := f.Lookup(n)
+		if !hasValue && fl != nil && !isBoolFlag(fl) && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		set[n] = value
+	}
+	return set
+}
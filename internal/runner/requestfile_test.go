@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+func writeRequestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fn, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestRequestFileEnvGroup(t *testing.T) {
+	rn, a := newJournalRunner(t)
+
+	fn := writeRequestFile(t, "req.yaml", "project: acme\nname: from-file\nenv:\n  A: \"1\"\n  B: two\n")
+	if err := rn.Run("envgroup", "create", "-f", fn); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.groups["from-file"]; got["A"] != "1" || got["B"] != "two" {
+		t.Errorf("from file: %v", a.groups)
+	}
+
+	// explicit flags win over the file's values
+	if err := rn.Run("envgroup", "create", "-f", fn, "-name", "from-flag", "-env", "A=3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.groups["from-flag"]; len(got) != 1 || got["A"] != "3" {
+		t.Errorf("flag override: %v", a.groups)
+	}
+}
+
+func TestRequestFlagArgs(t *testing.T) {
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	var env multiFlag
+	f.String("project", "", "")
+	f.String("time-range", "", "")
+	f.String("roles", "", "")
+	f.String("output", "", "")
+	f.Var(&env, "env", "")
+	m := map[string]any{
+		"project":   "acme",
+		"timeRange": "7d",
+		"roles":     []any{"admin", "viewer"},
+		"env":       map[string]any{"B": 2, "A": "1"},
+		"output":    "json",
+		"unknown":   "x",
+	}
+	got := requestFlagArgs(f, m, nil)
+	want := []string{"-env=A=1", "-env=B=2", "-project=acme", "-roles=admin,viewer", "-time-range=7d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	// a flag the command line sets is left to it, a repeatable one included
+	got = requestFlagArgs(f, m, map[string]string{"env": "A=3", "project": "other"})
+	want = []string{"-roles=admin,viewer", "-time-range=7d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("skipping set flags: got %q; want %q", got, want)
+	}
+
+	// a list item holding a comma cannot be joined, so the decoded value stands
+	if got := requestFlagArgs(f, map[string]any{"roles": []any{"a,b"}}, nil); len(got) != 0 {
+		t.Errorf("comma item: got %q", got)
+	}
+}
+
+func TestArgFlags(t *testing.T) {
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.String("f", "", "")
+	f.String("name", "", "")
+	f.Bool("yes", false, "")
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-f", "a.yaml"}, "a.yaml"},
+		{[]string{"--f=-"}, "-"},
+		{[]string{"-name", "-f", "-f", "b.yaml"}, "b.yaml"},
+		{[]string{"-yes", "-f", "c.yaml", "-f", "d.yaml"}, "d.yaml"},
+		{[]string{"arg", "-f", "e.yaml"}, ""},
+		{[]string{"--", "-f", "e.yaml"}, ""},
+	} {
+		if got := argFlags(f, c.args)["f"]; got != c.want {
+			t.Errorf("argFlags(%q)[f] = %q; want %q", c.args, got, c.want)
+		}
+	}
+}
+
+// A deployment's -ojson output replays as a deploy request: fields the
+// request lacks (revision, status) are ignored and flags still override.
+func TestParseDeploymentDeploy_RequestFile(t *testing.T) {
+	fn := writeRequestFile(t, "web.json", `{
+		"project": "acme", "location": "l", "name": "web",
+		"type": "Worker", "image": "app:1", "revision": 3, "status": "success",
+		"env": {"A": "1"}, "command": ["/bin/app", "serve"],
+		"resources": {"requests": {"cpu": "100m"}}
+	}`)
+	req, _, err := parseDeploymentDeploy(io.Discard, []string{"-f", fn, "-image", "app:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Project != "acme" || req.Name != "web" || req.Type != api.DeploymentTypeWorker {
+		t.Errorf("scalars not read: %+v", req)
+	}
+	if req.Image != "app:2" {
+		t.Errorf("image = %q; want the flag's app:2", req.Image)
+	}
+	if req.Env["A"] != "1" || !slices.Equal(req.Command, []string{"/bin/app", "serve"}) {
+		t.Errorf("env/command = %v / %v", req.Env, req.Command)
+	}
+	if req.Resources == nil || req.Resources.Requests.CPU != "100m" {
+		t.Errorf("resources not read: %+v", req.Resources)
+	}
+
+	if _, _, err := parseDeploymentDeploy(io.Discard, []string{"-f", writeRequestFile(t, "bad.yaml", "name: [")}); err == nil {
+		t.Error("malformed request file: want an error")
+	}
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/deploys-app/api"
+)
+
+// Route matching mirrors the edge: a request is served by a route on its exact
+// host before a wildcard route ("*.example.com", one label deep), and among
+// those by the longest path prefix. A path prefix matches on segment
+// boundaries, so "/api" serves "/api" and "/api/users" but not "/apix"; an
+// empty path or "/" serves everything.
+
+// routeMatchesHost reports whether a route domain serves host, and whether it
+// did so through a wildcard.
+func routeMatchesHost(domain, host string) (ok, wildcard bool) {
+	domain, host = strings.ToLower(domain), strings.ToLower(host)
+	if domain == host {
+		return true, false
+	}
+	if rest, isWildcard := strings.CutPrefix(domain, "*."); isWildcard {
+		label, parent, found := strings.Cut(host, ".")
+		return found && label != "" && parent == rest, true
+	}
+	return false, false
+}
+
+func routeMatchesPath(prefix, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+type routeCandidate struct {
+	Domain     string `json:"domain" yaml:"domain"`
+	Path       string `json:"path" yaml:"path"`
+	Target     string `json:"target" yaml:"target"`
+	Deployment string `json:"deployment,omitempty" yaml:"deployment,omitempty"`
+	Paused     bool   `json:"paused,omitempty" yaml:"paused,omitempty"`
+	Wildcard   bool   `json:"wildcard" yaml:"wildcard"`
+	Result     string `json:"result" yaml:"result"` // winner, or why it lost
+}
+
+// routeMatchResult is the outcome of `route match`: the winning route (nil
+// when none serves the URL) and every matching route, winner first.
+type routeMatchResult struct {
+	URL        string           `json:"url" yaml:"url"`
+	Host       string           `json:"host" yaml:"host"`
+	Path       string           `json:"path" yaml:"path"`
+	Winner     *routeCandidate  `json:"winner" yaml:"winner"`
+	Candidates []routeCandidate `json:"candidates" yaml:"candidates"`
+}
+
+func (r *routeMatchResult) Table() [][]string {
+	table := [][]string{
+		{"DOMAIN", "PATH", "TARGET", "RESULT"},
+	}
+	for _, c := range r.Candidates {
+		table = append(table, []string{c.Domain, c.Path, c.Target, c.Result})
+	}
+	return table
+}
+
+// matchRoute picks the route that serves host+path from routes.
+func matchRoute(routes []*api.RouteItem, rawURL, host, path string) *routeMatchResult {
+	res := &routeMatchResult{URL: rawURL, Host: host, Path: path, Candidates: []routeCandidate{}}
+	for _, x := range routes {
+		ok, wildcard := routeMatchesHost(x.Domain, host)
+		if !ok || !routeMatchesPath(x.Path, path) {
+			continue
+		}
+		c := routeCandidate{
+			Domain:     x.Domain,
+			Path:       x.Path,
+			Target:     x.Target,
+			Deployment: x.Deployment,
+			Paused:     x.Paused,
+			Wildcard:   wildcard,
+		}
+		if c.Target == "" && c.Deployment != "" {
+			c.Target = "deployment://" + c.Deployment
+		}
+		if name, ok := strings.CutPrefix(c.Target, "deployment://"); ok && c.Deployment == "" {
+			c.Deployment = name
+		}
+		res.Candidates = append(res.Candidates, c)
+	}
+	sort.SliceStable(res.Candidates, func(i, j int) bool {
+		a, b := res.Candidates[i], res.Candidates[j]
+		if a.Wildcard != b.Wildcard {
+			return !a.Wildcard
+		}
+		return len(strings.TrimSuffix(a.Path, "/")) > len(strings.TrimSuffix(b.Path, "/"))
+	})
+	for i := range res.Candidates {
+		c := &res.Candidates[i]
+		switch {
+		case i == 0:
+			c.Result = "winner"
+			if c.Paused {
+				c.Result = "winner (paused)"
+			}
+		case c.Wildcard && !res.Candidates[0].Wildcard:
+			c.Result = "lost: wildcard domain (exact domain wins)"
+		default:
+			c.Result = "lost: shorter path prefix"
+		}
+	}
+	if len(res.Candidates) > 0 {
+		w := res.Candidates[0]
+		res.Winner = &w
+	}
+	return res
+}
+
+// routeMatch handles `route match <url>`: list the location's routes once and
+// resolve the URL client-side.
+func (rn *Runner) routeMatch(s api.Route, f *leafFlagSet) func(args []string) error {
+	var (
+		req    api.RouteList
+		rawURL string
+	)
+	f.StringVar(&req.Project, "project", "", "project id")
+	f.StringVar(&req.Location, "location", "", "location")
+	f.Request(&req)
+	return func(args []string) error {
+		// the url may come before or after the flags
+		if len(args) > 0 && !isFlag(args[0]) {
+			rawURL, args = args[0], args[1:]
+		}
+		f.Parse(args)
+		if rawURL == "" {
+			rawURL = f.Arg(0)
+		}
+		if rawURL == "" {
+			return fmt.Errorf("url required (deploys route match <url>)")
+		}
+
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			// accept a bare host/path like example.com/api
+			u, err = url.Parse("https://" + rawURL)
+		}
+		if err != nil || u.Hostname() == "" {
+			return fmt.Errorf("invalid url %q", rawURL)
+		}
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+
+		list, err := s.List(rn.ctx(), &req)
+		if err != nil {
+			return err
+		}
+		res := matchRoute(list.Items, rawURL, u.Hostname(), path)
+		if res.Winner == nil {
+			// still print the (empty) result so -ojson consumers get a document
+			if err := rn.print(res); err != nil {
+				return err
+			}
+			return fmt.Errorf("no route serves %s%s", u.Hostname(), path)
+		}
+		return rn.print(res)
+	}
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+func TestMatchRoute(t *testing.T) {
+	routes := []*api.RouteItem{
+		{Domain: "example.com", Path: "/", Deployment: "web"},
+		{Domain: "example.com", Path: "/api", Target: "deployment://api"},
+		{Domain: "example.com", Path: "/api/v1", Target: "deployment://api-v1"},
+		{Domain: "example.com", Path: "/apix", Target: "deployment://apix"},
+		{Domain: "*.example.com", Path: "/", Target: "redirect://example.com"},
+		{Domain: "other.com", Path: "/", Target: "deployment://other"},
+	}
+	cases := []struct {
+		host, path string
+		winner     string // target
+		candidates int
+	}{
+		{"example.com", "/api/v1/users", "deployment://api-v1", 3},
+		{"example.com", "/api", "deployment://api", 2},
+		{"example.com", "/apix/a", "deployment://apix", 2},
+		{"example.com", "/apis", "deployment://web", 1},
+		{"EXAMPLE.com", "/", "deployment://web", 1},
+		{"www.example.com", "/api", "redirect://example.com", 1},
+		{"a.b.example.com", "/", "", 0}, // wildcard covers one label
+	}
+	for _, c := range cases {
+		res := matchRoute(routes, "", c.host, c.path)
+		got := ""
+		if res.Winner != nil {
+			got = res.Winner.Target
+		}
+		if got != c.winner || len(res.Candidates) != c.candidates {
+			t.Errorf("%s%s: winner %q with %d candidates; want %q with %d", c.host, c.path, got, len(res.Candidates), c.winner, c.candidates)
+		}
+	}
+
+	res := matchRoute(routes, "", "example.com", "/api/v1/users")
+	if res.Winner.Deployment != "api-v1" || res.Candidates[2].Deployment != "web" || res.Candidates[1].Result != "lost: shorter path prefix" {
+		t.Errorf("candidates = %+v", res.Candidates)
+	}
+}
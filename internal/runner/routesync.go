@@ -0,0 +1,323 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deploys-app/api"
+	"gopkg.in/yaml.v2"
+)
+
+// routeTable is the file form of a location's routes, written by `route
+// export` and applied by `route sync`. Project and location are optional in
+// the file; the -project/-location flags override them, while context
+// defaults only fill what the file leaves out.
+type routeTable struct {
+	Project  string           `yaml:"project,omitempty"`
+	Location string           `yaml:"location,omitempty"`
+	Routes   []routeTableItem `yaml:"routes"`
+}
+
+// routeTableItem is one route: its key (domain, path), target, and config
+// flattened so unset options stay out of the file.
+type routeTableItem struct {
+	Domain      string                      `yaml:"domain"`
+	Path        string                      `yaml:"path"`
+	Target      string                      `yaml:"target"`
+	Host        string                      `yaml:"host,omitempty"`
+	BasicAuth   *api.RouteConfigBasicAuth   `yaml:"basicAuth,omitempty"`
+	ForwardAuth *api.RouteConfigForwardAuth `yaml:"forwardAuth,omitempty"`
+}
+
+func (r routeTableItem) key() string {
+	return r.Domain + r.Path
+}
+
+func (r routeTableItem) config() api.RouteConfig {
+	return api.RouteConfig{BasicAuth: r.BasicAuth, ForwardAuth: r.ForwardAuth, Host: r.Host}
+}
+
+// normalized returns r in the form the api stores it, so that routes that mean
+// the same compare equal: a trimmed host, and no empty header lists.
+func (r routeTableItem) normalized() routeTableItem {
+	r.Host = strings.TrimSpace(r.Host)
+	if fa := r.ForwardAuth; fa != nil {
+		c := *fa
+		if len(c.AuthRequestHeaders) == 0 {
+			c.AuthRequestHeaders = nil
+		}
+		if len(c.AuthResponseHeaders) == 0 {
+			c.AuthResponseHeaders = nil
+		}
+		r.ForwardAuth = &c
+	}
+	return r
+}
+
+// withoutSecrets returns r with its basic-auth password left out.
+func (r routeTableItem) withoutSecrets() routeTableItem {
+	if r.BasicAuth != nil {
+		r.BasicAuth = &api.RouteConfigBasicAuth{User: r.BasicAuth.User}
+	}
+	return r
+}
+
+// routeTableItemOf converts a live route. A v1 route carries only its
+// deployment; it is written as the equivalent deployment:// target.
+func routeTableItemOf(x *api.RouteItem) routeTableItem {
+	target := x.Target
+	if target == "" && x.Deployment != "" {
+		target = "deployment://" + x.Deployment
+	}
+	return routeTableItem{
+		Domain:      x.Domain,
+		Path:        x.Path,
+		Target:      target,
+		Host:        x.Config.Host,
+		BasicAuth:   x.Config.BasicAuth,
+		ForwardAuth: x.Config.ForwardAuth,
+	}
+}
+
+func sortRouteTable(items []routeTableItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Domain != items[j].Domain {
+			return items[i].Domain < items[j].Domain
+		}
+		return items[i].Path < items[j].Path
+	})
+}
+
+// routeChange is one step of a sync plan.
+type routeChange struct {
+	Action string `json:"action" yaml:"action"` // create|update|delete|unmanaged
+	Domain string `json:"domain" yaml:"domain"`
+	Path   string `json:"path" yaml:"path"`
+	Target string `json:"target" yaml:"target"`
+}
+
+type routeSyncResult struct {
+	Project  string        `json:"project" yaml:"project"`
+	Location string        `json:"location" yaml:"location"`
+	DryRun   bool          `json:"dryRun" yaml:"dryRun"`
+	Changes  []routeChange `json:"changes" yaml:"changes"`
+}
+
+func (r *routeSyncResult) Table() [][]string {
+	table := [][]string{
+		{"ACTION", "DOMAIN", "PATH", "TARGET"},
+	}
+	for _, c := range r.Changes {
+		table = append(table, []string{c.Action, c.Domain, c.Path, c.Target})
+	}
+	return table
+}
+
+// planRouteSync diffs the wanted routes against the live ones. Routes only
+// live are deleted with prune and reported as unmanaged otherwise; unchanged
+// routes are left out of the plan.
+func planRouteSync(want []routeTableItem, live []*api.RouteItem, prune bool) ([]routeChange, error) {
+	wantByKey := map[string]routeTableItem{}
+	for _, w := range want {
+		if _, dup := wantByKey[w.key()]; dup {
+			return nil, fmt.Errorf("duplicate route %s%s", w.Domain, w.Path)
+		}
+		wantByKey[w.key()] = w
+	}
+	liveByKey := map[string]routeTableItem{}
+	for _, x := range live {
+		item := routeTableItemOf(x).normalized()
+		liveByKey[item.key()] = item
+	}
+
+	changes := []routeChange{}
+	sorted := append([]routeTableItem(nil), want...)
+	sortRouteTable(sorted)
+	for _, w := range sorted {
+		cur, ok := liveByKey[w.key()]
+		switch {
+		case !ok:
+			changes = append(changes, routeChange{Action: "create", Domain: w.Domain, Path: w.Path, Target: w.Target})
+		case !reflect.DeepEqual(cur, w.normalized()):
+			changes = append(changes, routeChange{Action: "update", Domain: w.Domain, Path: w.Path, Target: w.Target})
+		}
+	}
+	var extra []routeTableItem
+	for k, cur := range liveByKey {
+		if _, ok := wantByKey[k]; !ok {
+			extra = append(extra, cur)
+		}
+	}
+	sortRouteTable(extra)
+	for _, cur := range extra {
+		action := "unmanaged"
+		if prune {
+			action = "delete"
+		}
+		changes = append(changes, routeChange{Action: action, Domain: cur.Domain, Path: cur.Path, Target: cur.Target})
+	}
+	return changes, nil
+}
+
+// keepRouteSecrets fills in the basic-auth password of each wanted route that
+// names only the user, as export writes it, from the live route with that user.
+func keepRouteSecrets(want []routeTableItem, live []*api.RouteItem) error {
+	liveByKey := map[string]*api.RouteItem{}
+	for _, x := range live {
+		liveByKey[x.Domain+x.Path] = x
+	}
+	for i, w := range want {
+		ba := w.BasicAuth
+		if ba == nil || ba.Password != "" {
+			continue
+		}
+		cur := liveByKey[w.key()]
+		if cur == nil || cur.Config.BasicAuth == nil || cur.Config.BasicAuth.User != ba.User {
+			return fmt.Errorf("route %s%s: basicAuth password required (no live password to keep for user %q)", w.Domain, w.Path, ba.User)
+		}
+		want[i].BasicAuth = &api.RouteConfigBasicAuth{User: ba.User, Password: cur.Config.BasicAuth.Password}
+	}
+	return nil
+}
+
+// routeExport handles `route export`: the location's routes as a routeTable.
+// The table is meant for git, so basic-auth passwords are left out (sync keeps
+// the live ones) unless -include-secrets asks for them.
+func (rn *Runner) routeExport(s api.Route, f *leafFlagSet) func(args []string) error {
+	var (
+		req     api.RouteList
+		out     string
+		force   bool
+		secrets bool
+	)
+	f.StringVar(&req.Project, "project", "", "project id")
+	f.StringVar(&req.Location, "location", "", "location")
+	f.StringVar(&out, "o", "", "write the table to this file (default stdout)")
+	f.BoolVar(&force, "force", false, "overwrite an existing -o file")
+	f.BoolVar(&secrets, "include-secrets", false, "write basic-auth passwords too (the file is then a secret)")
+	return func(args []string) error {
+		f.Parse(args)
+
+		if req.Location == "" {
+			// a table is applied to one location, so it must come from one
+			return fmt.Errorf("location required")
+		}
+		res, err := s.List(rn.ctx(), &req)
+		if err != nil {
+			return err
+		}
+		t := routeTable{Project: req.Project, Location: req.Location, Routes: []routeTableItem{}}
+		for _, x := range res.Items {
+			item := routeTableItemOf(x)
+			if !secrets {
+				item = item.withoutSecrets()
+			}
+			t.Routes = append(t.Routes, item)
+		}
+		sortRouteTable(t.Routes)
+		return rn.writeSpecFile(out, force, &t, strconv.Itoa(len(t.Routes))+" routes", "deploys route sync")
+	}
+}
+
+// routeSync handles `route sync`: apply a routeTable to its location. Creates
+// and updates go first (route.create replaces the route at a domain+path), then
+// deletes, so a route moved between paths is never briefly missing.
+func (rn *Runner) routeSync(s api.Route, f *leafFlagSet) func(args []string) error {
+	var (
+		fn       string
+		project  string
+		location string
+		prune    bool
+		dryRun   bool
+	)
+	f.StringVar(&fn, "f", "", "route table file (yaml, as written by route export)")
+	f.StringVar(&project, "project", "", "project id")
+	f.StringVar(&location, "location", "", "location")
+	f.BoolVar(&prune, "prune", false, "delete live routes that are not in the file")
+	f.BoolVar(&dryRun, "dry-run", false, "print the plan without applying it")
+	return func(args []string) error {
+		f.Parse(args)
+
+		if fn == "" {
+			return fmt.Errorf("route table file required (-f)")
+		}
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		var t routeTable
+		if err := yaml.UnmarshalStrict(b, &t); err != nil {
+			return fmt.Errorf("parse %s: %w", fn, err)
+		}
+		f.override("project", &t.Project, project)
+		f.override("location", &t.Location, location)
+		if t.Location == "" {
+			return fmt.Errorf("location required")
+		}
+
+		live, err := s.List(rn.ctx(), &api.RouteList{Project: t.Project, Location: t.Location})
+		if err != nil {
+			return err
+		}
+		if err := keepRouteSecrets(t.Routes, live.Items); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		changes, err := planRouteSync(t.Routes, live.Items, prune)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		res := &routeSyncResult{Project: t.Project, Location: t.Location, DryRun: dryRun, Changes: changes}
+		if dryRun {
+			return rn.print(res)
+		}
+
+		o := rn.journalBegin("route sync", journalTarget{Project: t.Project, Location: t.Location}, res)
+		err = applyRouteSync(rn.ctx(), s, &t, changes)
+		o.done(err)
+		if err != nil {
+			return err
+		}
+		return rn.print(res)
+	}
+}
+
+// applyRouteSync makes the planned changes: creates and updates first, then
+// deletes.
+func applyRouteSync(ctx context.Context, s api.Route, t *routeTable, changes []routeChange) error {
+	want := map[string]routeTableItem{}
+	for _, w := range t.Routes {
+		want[w.key()] = w
+	}
+	for _, c := range changes {
+		if c.Action != "create" && c.Action != "update" {
+			continue
+		}
+		w := want[c.Domain+c.Path]
+		_, err := s.CreateV2(ctx, &api.RouteCreateV2{
+			Project:  t.Project,
+			Location: t.Location,
+			Domain:   w.Domain,
+			Path:     w.Path,
+			Target:   w.Target,
+			Config:   w.config(),
+		})
+		if err != nil {
+			return fmt.Errorf("%s %s%s: %w", c.Action, c.Domain, c.Path, err)
+		}
+	}
+	for _, c := range changes {
+		if c.Action != "delete" {
+			continue
+		}
+		_, err := s.Delete(ctx, &api.RouteDelete{Project: t.Project, Location: t.Location, Domain: c.Domain, Path: c.Path})
+		if err != nil {
+			return fmt.Errorf("delete %s%s: %w", c.Domain, c.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPlanRouteSync(t *testing.T) {
+	live := []*api.RouteItem{
+		{Domain: "example.com", Path: "/", Deployment: "web"}, // v1: no target
+		{Domain: "example.com", Path: "/api", Target: "deployment://api"},
+		{Domain: "example.com", Path: "/old", Target: "deployment://old"},
+	}
+	want := []routeTableItem{
+		{Domain: "example.com", Path: "/api", Target: "deployment://api-v2"},
+		{Domain: "example.com", Path: "/", Target: "deployment://web"},
+		{Domain: "example.com", Path: "/docs", Target: "redirect://docs.example.com"},
+	}
+
+	changes, err := planRouteSync(want, live, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []routeChange{
+		{Action: "update", Domain: "example.com", Path: "/api", Target: "deployment://api-v2"},
+		{Action: "create", Domain: "example.com", Path: "/docs", Target: "redirect://docs.example.com"},
+		{Action: "unmanaged", Domain: "example.com", Path: "/old", Target: "deployment://old"},
+	}
+	if !reflect.DeepEqual(changes, got) {
+		t.Errorf("plan = %+v\nwant %+v", changes, got)
+	}
+
+	changes, _ = planRouteSync(want, live, true)
+	if changes[2].Action != "delete" {
+		t.Errorf("with prune, extra route action = %q; want delete", changes[2].Action)
+	}
+
+	if _, err := planRouteSync(append(want, want[0]), live, false); err == nil {
+		t.Error("duplicate domain+path should fail")
+	}
+}
+
+// An exported table synced back unchanged is a no-op.
+func TestRouteTableRoundTrip(t *testing.T) {
+	live := []*api.RouteItem{
+		{Domain: "example.com", Path: "/", Target: "http://203.0.113.7", Config: api.RouteConfig{Host: "origin.example.com"}},
+		{Domain: "example.com", Path: "/admin", Target: "deployment://admin", Config: api.RouteConfig{BasicAuth: &api.RouteConfigBasicAuth{User: "u", Password: "p"}}},
+	}
+	tbl := routeTable{Location: "loc"}
+	for _, x := range live {
+		tbl.Routes = append(tbl.Routes, routeTableItemOf(x).withoutSecrets())
+	}
+	b, err := yaml.Marshal(tbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "password: p") {
+		t.Errorf("export carries the password:\n%s", b)
+	}
+	var back routeTable
+	if err := yaml.UnmarshalStrict(b, &back); err != nil {
+		t.Fatalf("unmarshal:\n%s\n%v", b, err)
+	}
+	if err := keepRouteSecrets(back.Routes, live); err != nil {
+		t.Fatal(err)
+	}
+	if back.Routes[1].BasicAuth.Password != "p" {
+		t.Errorf("password not kept: %+v", back.Routes[1].BasicAuth)
+	}
+	changes, err := planRouteSync(back.Routes, live, true)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("round trip plan = %+v, %v; want no changes", changes, err)
+	}
+}
+
+// Routes that differ only in how empty fields are spelled are unchanged, and a
+// password-less user with no live password to keep is an error.
+func TestPlanRouteSyncNormalized(t *testing.T) {
+	live := []*api.RouteItem{
+		{Domain: "example.com", Path: "/", Target: "http://203.0.113.7", Config: api.RouteConfig{
+			Host:        "origin.example.com",
+			ForwardAuth: &api.RouteConfigForwardAuth{Target: "https://auth.example.com"},
+		}},
+	}
+	want := []routeTableItem{{
+		Domain: "example.com", Path: "/", Target: "http://203.0.113.7", Host: " origin.example.com ",
+		ForwardAuth: &api.RouteConfigForwardAuth{Target: "https://auth.example.com", AuthRequestHeaders: []string{}, AuthResponseHeaders: []string{}},
+	}}
+	changes, err := planRouteSync(want, live, false)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("plan = %+v, %v; want no changes", changes, err)
+	}
+
+	want = []routeTableItem{{Domain: "example.com", Path: "/new", Target: "deployment://web", BasicAuth: &api.RouteConfigBasicAuth{User: "u"}}}
+	if err := keepRouteSecrets(want, live); err == nil {
+		t.Error("a new route without a password should fail")
+	}
+}
@@ -2,14 +2,16 @@ package runner
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
-	"unicode/utf8"
 
 	"github.com/deploys-app/api"
 	"github.com/moonrhythm/toon"
@@ -32,6 +34,24 @@ type Runner struct {
 	// DEPLOYS_ACCOUNT env var when this is empty; main threads the same value
 	// into the API client for normal commands.
 	Account string
+	// Endpoint is the api endpoint main resolved: DEPLOYS_ENDPOINT, else the
+	// active context's, else empty for the default. Login, auth status, the
+	// journal, and the completion cache key on it, so they all agree with the
+	// api client.
+	Endpoint string
+
+	// base is the command's context (see ctx); Run sets it and ends it when
+	// the command returns.
+	base context.Context
+	// scope fills unset -project/-location flags (see scopeDefaults); Run
+	// resolves it for commands that talk to the api.
+	scope *scopeDefaults
+	// table holds the -columns/-sort-by/-no-headers/-wide flags that shape
+	// tabular output (see shapeTable).
+	table tableOptions
+	// watch is set by Run; a watchable leaf's -watch turns it on (see
+	// runWatch).
+	watch *watchState
 }
 
 func (rn Runner) output() *os.File {
@@ -41,7 +61,28 @@ func (rn Runner) output() *os.File {
 	return rn.Output
 }
 
+// leafTimeout bounds the calls of a leaf run outside Run (a helper called on
+// its own), which has no command context to end them.
+const leafTimeout = time.Minute
+
+// ctx is the context the command's api calls run under. Each call's own
+// deadline comes from main's retrying transport, which times every attempt and
+// bounds the whole call by its retry budget (see wire.Retrier).
+func (rn Runner) ctx() context.Context {
+	if rn.base == nil {
+		// the timer ends (and frees) the context at its deadline
+		ctx, cancel := context.WithTimeout(context.Background(), leafTimeout)
+		time.AfterFunc(leafTimeout, cancel)
+		return ctx
+	}
+	return rn.base
+}
+
 func (rn Runner) print(v any) error {
+	if rn.watching() {
+		rn.watch.v, rn.watch.captured, rn.watch.rn = v, true, rn
+		return nil
+	}
 	switch rn.OutputMode {
 	case "", "table":
 		tp, ok := v.(tablePrinter)
@@ -49,7 +90,11 @@ func (rn Runner) print(v any) error {
 			// no table representation, fall back to yaml
 			return yaml.NewEncoder(rn.output()).Encode(v)
 		}
-		rn.printTable(tp.Table())
+		table, err := rn.shapeTable(v, tp)
+		if err != nil {
+			return err
+		}
+		rn.printTable(table)
 		return nil
 	case "yaml":
 		return yaml.NewEncoder(rn.output()).Encode(v)
@@ -64,34 +109,98 @@ func (rn Runner) print(v any) error {
 		}
 		_, err = fmt.Fprintln(rn.output(), string(b))
 		return err
+	case "csv", "tsv", "markdown":
+		tp, ok := v.(tablePrinter)
+		if !ok {
+			return fmt.Errorf("-output %s: this command has no table form (use yaml or json)", rn.OutputMode)
+		}
+		table, err := rn.shapeTable(v, tp)
+		if err != nil {
+			return err
+		}
+		switch rn.OutputMode {
+		case "csv":
+			return writeCSV(rn.output(), table)
+		case "tsv":
+			return writeTSV(rn.output(), table)
+		default:
+			return writeMarkdownTable(rn.output(), table)
+		}
 	default:
+		if templateOutput(rn.OutputMode) {
+			return rn.printExpr(v)
+		}
 		return fmt.Errorf("invalid output")
 	}
 }
 
-func (rn Runner) printTable(table [][]string) {
-	// find longest for each column
-	ll := make([]int, len(table[0]))
-	for i := range table {
-		for l := range ll {
-			c := utf8.RuneCountInString(table[i][l]) + 3
-			if c > ll[l] {
-				ll[l] = c
-			}
+// writeCSV writes table as RFC 4180 CSV: fields holding a comma, quote, or
+// line break are quoted, with quotes doubled.
+func writeCSV(w io.Writer, table [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(table)
+	return cw.Error()
+}
+
+// tsvEscaper keeps every TSV record on one line with one tab per column
+// boundary: tab, newline, carriage return, and backslash are written as \t,
+// \n, \r, and \\ (the escaping PostgreSQL COPY and MySQL read back).
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeTSV writes table as tab-separated values, one record per line.
+func writeTSV(w io.Writer, table [][]string) error {
+	for _, row := range table {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = tsvEscaper.Replace(c)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(cells, "\t")); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	output := rn.output()
-	for _, rows := range table {
-		for l, cell := range rows {
-			fmt.Fprintf(output, fmt.Sprintf("%%-%ds", ll[l]), cell)
+// writeMarkdownTable writes table as a GitHub-flavored Markdown table, its
+// first row the header. Cells are escaped (see mdCell) so a pipe or markup in a
+// value cannot break the table.
+func writeMarkdownTable(w io.Writer, table [][]string) error {
+	if len(table) == 0 {
+		return nil
+	}
+	row := func(cells []string) error {
+		var b strings.Builder
+		b.WriteString("|")
+		for _, c := range cells {
+			b.WriteString(" " + mdCell(c) + " |")
+		}
+		_, err := fmt.Fprintln(w, b.String())
+		return err
+	}
+	if err := row(table[0]); err != nil {
+		return err
+	}
+	sep := make([]string, len(table[0]))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	if _, err := fmt.Fprintln(w, "| "+strings.Join(sep, " | ")+" |"); err != nil {
+		return err
+	}
+	for _, r := range table[1:] {
+		if err := row(r); err != nil {
+			return err
 		}
-		fmt.Fprintln(output)
 	}
+	return nil
 }
 
 func (rn *Runner) registerFlags(f *flag.FlagSet) {
-	f.StringVar(&rn.OutputMode, "output", "table", "output mode: table, yaml, json, toon")
+	f.StringVar(&rn.OutputMode, "output", "table", "output mode: table, yaml, json, toon, csv, tsv, markdown, template=<tmpl>, jsonpath=<expr>")
+	f.StringVar(&rn.table.columns, "columns", "", "table columns to show, comma-separated header names (e.g. name,status,image)")
+	f.StringVar(&rn.table.sortBy, "sort-by", "", "sort table rows by a column; prefix - for descending (e.g. -sort-by -age)")
+	f.BoolVar(&rn.table.noHeaders, "no-headers", false, "omit the table header row (table, csv, tsv)")
+	f.BoolVar(&rn.table.wide, "wide", false, "show extra table columns, and do not truncate cells to the terminal width")
 }
 
 func (rn *Runner) replaceShortFlag(args []string) {
@@ -105,6 +214,12 @@ func (rn *Runner) replaceShortFlag(args []string) {
 			args[i] = "--output=table"
 		case "-otoon":
 			args[i] = "--output=toon"
+		case "-ocsv":
+			args[i] = "--output=csv"
+		case "-otsv":
+			args[i] = "--output=tsv"
+		case "-omarkdown":
+			args[i] = "--output=markdown"
 		}
 	}
 }
@@ -116,6 +231,23 @@ func (rn Runner) Run(args ...string) error {
 
 	rn.replaceShortFlag(args)
 
+	if rn.base == nil {
+		var cancel context.CancelFunc
+		rn.base, cancel = context.WithCancel(context.Background())
+		defer cancel()
+	}
+
+	if rn.scope == nil && !IsLocalCommand(args[0]) && !IsAuthCommand(args[0]) && !IsCompleteCommand(args[0]) {
+		var err error
+		rn.scope, err = loadScopeDefaults()
+		if err != nil {
+			return err
+		}
+	}
+	if rn.watch == nil {
+		return rn.runWatch(args)
+	}
+
 	switch args[0] {
 	default:
 		return fmt.Errorf("invalid command: %q (run \"deploys help\")", args[0])
@@ -177,6 +309,28 @@ func (rn Runner) Run(args ...string) error {
 		return rn.scheduler(args[1:]...)
 	case "notification":
 		return rn.notification(args[1:]...)
+	case "config":
+		return rn.config(args[1:]...)
+	case "context":
+		return rn.context(args[1:]...)
+	case "refs":
+		return rn.refs(args[1:]...)
+	case "api":
+		return rn.api(args[1:]...)
+	case "dev-server":
+		return rn.devServer(args[1:]...)
+	case "mcp":
+		return rn.mcp(args[1:]...)
+	case "history":
+		return rn.history(args[1:]...)
+	case "undo":
+		return rn.undo(args[1:]...)
+	case "completion":
+		return rn.completion(args[1:]...)
+	case "docs":
+		return rn.docs(args[1:]...)
+	case completeCommand:
+		return rn.complete(args[1:]...)
 	case "check-update":
 		return rn.checkUpdate(args[1:]...)
 	case "version":
@@ -185,24 +339,17 @@ func (rn Runner) Run(args ...string) error {
 }
 
 func (rn Runner) me(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("me")
-	}
+	return rn.runGroup("me", args, rn.meLeaf)
+}
 
+func (rn *Runner) meLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Me()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("me", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("me", args[0])
+	switch sub {
 	case "get":
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &api.Empty{})
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &api.Empty{})
+		}
 	case "authorized":
 		var (
 			req         api.MeAuthorized
@@ -210,14 +357,20 @@ func (rn Runner) me(args ...string) error {
 		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&permissions, "permissions", "", "permissions (comma separated values)")
-		f.Parse(args[1:])
-		req.Permissions = splitComma(permissions)
-		resp, err = s.Authorized(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.Permissions = splitComma(permissions)
+			return s.Authorized(rn.ctx(), &req)
+		}
 	case "permissions":
 		var req api.MePermissions
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Permissions(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Permissions(rn.ctx(), &req)
+		}
 	case "generate-token", "generateToken":
 		var (
 			req         api.MeGenerateToken
@@ -227,91 +380,99 @@ func (rn Runner) me(args ...string) error {
 		f.StringVar(&permissions, "permissions", "", "permissions (comma separated; any you hold except wildcards and role.*/serviceaccount.key.*/billing.*/pullsecret.get)")
 		f.IntVar(&req.TTLSeconds, "ttl", 0, "token lifetime in seconds (60-3600, default 900)")
 		f.StringVar(&req.Label, "label", "", "optional attribution label for the agent session (e.g. claude-code:pr-42)")
-		f.Parse(args[1:])
-		req.Permissions = splitComma(permissions)
-		resp, err = s.GenerateToken(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Permissions = splitComma(permissions)
+			o := rn.journalBegin("me generate-token", journalTarget{Project: req.Project, Name: req.Label}, &req)
+			resp, err = s.GenerateToken(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list-tokens", "listTokens":
 		var req api.MeListTokens
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.ListTokens(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.ListTokens(rn.ctx(), &req)
+		}
 	case "revoke-token", "revokeToken":
 		var req api.MeRevokeToken
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.ID, "id", "", "scoped token id (from list-tokens)")
-		f.Parse(args[1:])
-		resp, err = s.RevokeToken(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("me revoke-token", journalTarget{Project: req.Project, Name: req.ID}, &req)
+			resp, err = s.RevokeToken(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) location(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("location")
-	}
+	return rn.runGroup("location", args, rn.locationLeaf)
+}
 
+func (rn *Runner) locationLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Location()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("location", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("location", args[0])
+	switch sub {
 	case "list":
 		var req api.LocationList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.LocationGet
 		f.StringVar(&req.ID, "id", "", "location id")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) project(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("project")
-	}
+	return rn.runGroup("project", args, rn.projectLeaf)
+}
 
+func (rn *Runner) projectLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Project()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("project", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("project", args[0])
+	switch sub {
 	case "create":
 		var req api.ProjectCreate
 		f.StringVar(&req.SID, "id", "", "project id")
 		f.StringVar(&req.Name, "name", "", "project name")
 		f.Int64Var(&req.BillingAccount, "billingaccount", 0, "billing account id")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("project create", journalTarget{Project: req.SID}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list":
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &api.Empty{})
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &api.Empty{})
+		}
 	case "get":
 		var req api.ProjectGet
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "update":
 		var (
 			req            api.ProjectUpdate
@@ -321,49 +482,64 @@ func (rn Runner) project(args ...string) error {
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&name, "name", "", "project name")
 		f.Int64Var(&billingAccount, "billingaccount", 0, "billing account id")
-		f.Parse(args[1:])
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		if name != "" {
-			req.Name = &name
-		}
-		if billingAccount > 0 {
-			req.BillingAccount = &billingAccount
-		}
+			if name != "" {
+				req.Name = &name
+			}
+			if billingAccount > 0 {
+				req.BillingAccount = &billingAccount
+			}
 
-		resp, err = s.Update(context.Background(), &req)
+			o := rn.journalBegin("project update", journalTarget{Project: req.Project}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "delete":
-		var req api.ProjectDelete
+		var (
+			req   api.ProjectDelete
+			yes   bool
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+		f.BoolVar(&force, "force", false, "allow deleting a protected project")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if req.Project == "" {
+				return nil, fmt.Errorf("project required")
+			}
+			if err := rn.confirmDestructive("project", "delete", req.Project, req.Project, yes, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("project delete", journalTarget{Project: req.Project}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "usage":
 		var req api.ProjectUsage
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Usage(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Usage(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) role(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("role")
-	}
+	return rn.runGroup("role", args, rn.roleLeaf)
+}
 
+func (rn *Runner) roleLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Role()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("role", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("role", args[0])
+	switch sub {
 	case "create":
 		var (
 			req         api.RoleCreate
@@ -373,45 +549,78 @@ func (rn Runner) role(args ...string) error {
 		f.StringVar(&req.Role, "role", "", "role id")
 		f.StringVar(&req.Name, "name", "", "role name")
 		f.StringVar(&permissions, "permissions", "", "permissions")
-		f.Parse(args[1:])
-		req.Permissions = splitComma(permissions)
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Permissions = splitComma(permissions)
+			o := rn.journalBegin("role create", journalTarget{Project: req.Project, Name: req.Role}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list":
 		var req api.RoleList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.RoleGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Role, "role", "", "role id")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "delete":
 		var req api.RoleDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Role, "role", "", "role id")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("role delete", journalTarget{Project: req.Project, Name: req.Role}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "grant":
 		var req api.RoleGrant
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Role, "role", "", "role id")
 		f.StringVar(&req.Email, "email", "", "email")
-		f.Parse(args[1:])
-		resp, err = s.Grant(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("role grant", journalTarget{Project: req.Project, Name: req.Role, Email: req.Email}, &req)
+			resp, err = s.Grant(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "revoke":
 		var req api.RoleRevoke
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Role, "role", "", "role id")
 		f.StringVar(&req.Email, "email", "", "email")
-		f.Parse(args[1:])
-		resp, err = s.Revoke(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("role revoke", journalTarget{Project: req.Project, Name: req.Role, Email: req.Email}, &req)
+			resp, err = s.Revoke(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "users":
 		var req api.RoleUsers
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.Users(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Users(rn.ctx(), &req)
+		}
 	case "bind":
 		var (
 			req   api.RoleBind
@@ -420,103 +629,149 @@ func (rn Runner) role(args ...string) error {
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Email, "email", "", "email")
 		f.StringVar(&roles, "roles", "", "roles")
-		f.Parse(args[1:])
-		req.Roles = splitComma(roles)
-		resp, err = s.Bind(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Roles = splitComma(roles)
+			o := rn.journalBegin("role bind", journalTarget{Project: req.Project, Email: req.Email}, &req)
+			resp, err = s.Bind(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "permissions":
-		f.Parse(args[1:])
-		resp, err = s.Permissions(context.Background(), &api.Empty{})
-	}
-	if err != nil {
-		return err
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Permissions(rn.ctx(), &api.Empty{})
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) deployment(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("deployment")
-	}
-
-	// deploy and set own their flag handling (including -h); the shared
-	// subFlagSet below is only for the simple lifecycle subcommands. Error issues
-	// now live in their own top-level `error` group (backed by the api `error.*`
-	// resource), no longer under `deployment errors`.
-	switch args[0] {
-	case "deploy":
-		return rn.deploymentDeploy(args[1:]...)
-	case "set":
-		return rn.deploymentSet(args[1:]...)
-	}
+	return rn.runGroup("deployment", args, rn.deploymentLeaf)
+}
 
+func (rn *Runner) deploymentLeaf(f *leafFlagSet, sub string) leafRun {
+	// Error issues now live in their own top-level `error` group (backed by the
+	// api `error.*` resource), no longer under `deployment errors`.
 	s := rn.API.Deployment()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("deployment", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("deployment", args[0])
+	switch sub {
+	case "deploy":
+		return rn.deploymentDeployLeaf(f)
+	case "set", "set image":
+		// deploy and set own their flag sets (including -h)
+		return rn.deploymentSetLeaf(f)
 	case "list":
 		var req api.DeploymentList
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.DeploymentGet
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.IntVar(&req.Revision, "revision", 0, "deployment revision")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "delete":
-		var req api.DeploymentDelete
+		var (
+			req   api.DeploymentDelete
+			yes   bool
+			force bool
+		)
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+		f.BoolVar(&force, "force", false, "delete even if other resources still reference it, or it is protected")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.checkRefs("deployment", req.Project, req.Location, req.Name, force); err != nil {
+				return nil, err
+			}
+			if err := rn.confirmDestructive("deployment", "delete", req.Project, req.Name, yes, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("deployment delete", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "revisions":
 		var req api.DeploymentRevisions
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Revisions(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Revisions(rn.ctx(), &req)
+		}
 	case "pause":
 		var req api.DeploymentPause
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Pause(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("deployment pause", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Pause(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "resume":
 		var req api.DeploymentResume
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Resume(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("deployment resume", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Resume(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "restart":
 		var req api.DeploymentRestart
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Restart(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("deployment restart", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Restart(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "rollback":
 		var req api.DeploymentRollback
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.IntVar(&req.Revision, "revision", 0, "revision to rollback to")
-		f.Parse(args[1:])
-		resp, err = s.Rollback(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("deployment rollback", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Rollback(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "metrics":
 		var (
 			req       api.DeploymentMetrics
@@ -526,16 +781,22 @@ func (rn Runner) deployment(args ...string) error {
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.StringVar(&timeRange, "time-range", "1h", "time range (1h, 6h, 12h, 1d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.DeploymentMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.TimeRange = api.DeploymentMetricsTimeRange(timeRange)
+			return s.Metrics(rn.ctx(), &req)
+		}
 	case "status":
 		var req api.DeploymentStatus
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
-		f.Parse(args[1:])
-		resp, err = s.Status(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Status(rn.ctx(), &req)
+		}
 	case "logs":
 		var (
 			req    api.DeploymentLogs
@@ -548,14 +809,17 @@ func (rn Runner) deployment(args ...string) error {
 		f.BoolVar(&req.Previous, "previous", false, "read the last crashed container (crash post-mortem)")
 		f.IntVar(&req.TailLines, "tail", 0, "lines per pod (default 200, max 1000)")
 		f.BoolVar(&follow, "follow", false, "re-poll for new lines (client-side; the API stays snapshot-only)")
-		f.Parse(args[1:])
-		if follow {
-			// --follow is a CLI-only convenience: re-poll the bounded snapshot on
-			// an interval and print lines not seen before. The API/MCP contract
-			// stays snapshot-only.
-			return rn.deploymentLogsFollow(s, &req)
-		}
-		resp, err = s.Logs(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			if follow {
+				// --follow is a CLI-only convenience: re-poll the bounded snapshot on
+				// an interval and print lines not seen before. The API/MCP contract
+				// stays snapshot-only.
+				return nil, rn.deploymentLogsFollow(s, &req)
+			}
+			return s.Logs(rn.ctx(), &req)
+		}
 	case "logsHistory", "logs-history":
 		var (
 			req          api.DeploymentLogsHistory
@@ -570,27 +834,34 @@ func (rn Runner) deployment(args ...string) error {
 		f.IntVar(&req.Limit, "limit", 0, "max lines per page (default 200, max 1000)")
 		f.BoolVar(&req.Reverse, "reverse", false, "return newest-first and page backward into the past")
 		f.StringVar(&req.Cursor, "cursor", "", "opaque page cursor from a previous response's nextCursor")
-		f.Parse(args[1:])
-		if req.Since, err = parseHistoryTime(since); err != nil {
-			return fmt.Errorf("invalid -since: %w", err)
-		}
-		if req.Until, err = parseHistoryTime(until); err != nil {
-			return fmt.Errorf("invalid -until: %w", err)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			var err error
+			if req.Since, err = parseHistoryTime(since); err != nil {
+				return nil, fmt.Errorf("invalid -since: %w", err)
+			}
+			if req.Until, err = parseHistoryTime(until); err != nil {
+				return nil, fmt.Errorf("invalid -until: %w", err)
+			}
+			return s.LogsHistory(rn.ctx(), &req)
 		}
-		resp, err = s.LogsHistory(context.Background(), &req)
 	case "extend-ttl", "extendTTL":
 		var req api.DeploymentExtendTTL
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "deployment name")
 		f.Int64Var(&req.TTL, "ttl", 0, "seconds from now until auto-delete (must be > 0)")
-		f.Parse(args[1:])
-		resp, err = s.ExtendTTL(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("deployment extend-ttl", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.ExtendTTL(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 // parseHistoryTime parses a -since/-until flag value for deployment logs
@@ -621,7 +892,7 @@ func (rn Runner) deploymentLogsFollow(s api.Deployment, req *api.DeploymentLogs)
 	seen := map[string]bool{}
 	out := rn.output()
 	for {
-		res, err := s.Logs(context.Background(), req)
+		res, err := s.Logs(rn.ctx(), req)
 		if err != nil {
 			return err
 		}
@@ -641,35 +912,32 @@ func (rn Runner) deploymentLogsFollow(s api.Deployment, req *api.DeploymentLogs)
 }
 
 func (rn Runner) route(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("route")
-	}
+	return rn.runGroup("route", args, rn.routeLeaf)
+}
 
+func (rn *Runner) routeLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Route()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("route", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("route", args[0])
+	switch sub {
 	case "list":
 		var req api.RouteList
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.RouteGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Domain, "domain", "", "domain")
 		f.StringVar(&req.Path, "path", "", "path")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "create":
 		var (
 			req        api.RouteCreateV2
@@ -682,44 +950,66 @@ func (rn Runner) route(args ...string) error {
 		f.StringVar(&req.Target, "target", "", "target (for v2)")
 		f.StringVar(&deployment, "deployment", "", "deployment name (for v1)")
 		f.StringVar(&req.Config.Host, "host", "", "override the Host header sent upstream (external http:// targets only)")
-		f.Parse(args[1:])
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		if req.Target == "" && deployment != "" {
-			req.Target = "deployment://" + deployment
+			if req.Target == "" && deployment != "" {
+				req.Target = "deployment://" + deployment
+			}
+			o := rn.journalBegin("route create", journalTarget{Project: req.Project, Location: req.Location, Name: req.Domain + req.Path}, &req)
+			resp, err = s.CreateV2(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		resp, err = s.CreateV2(context.Background(), &req)
 	case "delete":
 		var req api.RouteDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Domain, "domain", "", "domain")
 		f.StringVar(&req.Path, "path", "", "path")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("route delete", journalTarget{Project: req.Project, Location: req.Location, Name: req.Domain + req.Path}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
+	case "export":
+		return printed(rn.routeExport(s, f))
+	case "sync":
+		return printed(rn.routeSync(s, f))
+	case "match":
+		return printed(rn.routeMatch(s, f))
 	}
-	return rn.print(resp)
+	return nil
 }
 
-func (rn Runner) deploymentDeploy(args ...string) error {
+// deploymentDeployLeaf runs `deployment deploy` on its own flag set, which
+// deploymentDeployFlags shares with the unit tests.
+func (rn *Runner) deploymentDeployLeaf(f *leafFlagSet) leafRun {
 	// Pass the runner's output so the -h banner honors Runner.Output like every
 	// other subcommand (subFlagSet's Usage targets rn.output()).
-	req, outputMode, err := parseDeploymentDeploy(rn.output(), args)
-	if errors.Is(err, flag.ErrHelp) {
-		return nil // usage already printed; -h is a clean exit, matching ExitOnError
-	}
-	if err != nil {
-		return err
-	}
-	rn.OutputMode = outputMode
+	var parse func(args []string) (api.DeploymentDeploy, string, error)
+	f.FlagSet, parse = deploymentDeployFlags(rn.output())
+	return func(args []string) (resp any, err error) {
+		args = withDefaultFlags(rn.scope.defaultFlags("deployment deploy"), args)
+		req, outputMode, err := parse(args)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, nil // usage already printed; -h is a clean exit, matching ExitOnError
+		}
+		if err != nil {
+			return nil, err
+		}
+		rn.OutputMode = outputMode
+		rn.scope.fillFields("deployment", &req.Project, &req.Location, &req.Name)
 
-	resp, err := rn.API.Deployment().Deploy(context.Background(), &req)
-	if err != nil {
-		return err
+		o := rn.journalBegin("deployment deploy", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+		resp, err = rn.API.Deployment().Deploy(rn.ctx(), &req)
+		o.done(err)
+		return resp, err
 	}
-	return rn.print(resp)
 }
 
 // parseDeploymentDeploy maps the full api.DeploymentDeploy surface to flags. It
@@ -734,6 +1024,13 @@ func (rn Runner) deploymentDeploy(args ...string) error {
 // helpOut receives the -h/-help banner (so it can be redirected and asserted in
 // tests); all other output is discarded and surfaced to the caller as an error.
 func parseDeploymentDeploy(helpOut io.Writer, args []string) (api.DeploymentDeploy, string, error) {
+	_, parse := deploymentDeployFlags(helpOut)
+	return parse(args)
+}
+
+// deploymentDeployFlags declares the flags of parseDeploymentDeploy and
+// returns them with the parse that reads args through them.
+func deploymentDeployFlags(helpOut io.Writer) (*flag.FlagSet, func(args []string) (api.DeploymentDeploy, string, error)) {
 	var (
 		req         api.DeploymentDeploy
 		outputMode  string
@@ -774,6 +1071,8 @@ func parseDeploymentDeploy(helpOut io.Writer, args []string) (api.DeploymentDepl
 		allowedEmails      string
 		allowedDomains     string
 		sidecarsFile       string
+
+		requestFile string
 	)
 
 	// ContinueOnError (not ExitOnError) so a parse error returns instead of
@@ -781,7 +1080,7 @@ func parseDeploymentDeploy(helpOut io.Writer, args []string) (api.DeploymentDepl
 	// the error. Output is discarded so the error is reported once, by main.
 	f := flag.NewFlagSet("deployment deploy", flag.ContinueOnError)
 	f.SetOutput(io.Discard)
-	f.StringVar(&outputMode, "output", "table", "output mode: table, yaml, json, toon")
+	f.StringVar(&outputMode, "output", "table", "output mode: table, yaml, json, toon, csv, tsv, markdown, template=<tmpl>, jsonpath=<expr>")
 	f.StringVar(&req.Location, "location", "", "location")
 	f.StringVar(&req.Project, "project", "", "project id")
 	f.StringVar(&req.Name, "name", "", "deployment name")
@@ -817,187 +1116,239 @@ func parseDeploymentDeploy(helpOut io.Writer, args []string) (api.DeploymentDepl
 	f.StringVar(&allowedEmails, "allowedEmails", "", "allowed emails for access (comma separated)")
 	f.StringVar(&allowedDomains, "allowedDomains", "", "allowed domains for access (comma separated)")
 	f.StringVar(&sidecarsFile, "sidecarsFile", "", "path to a YAML/JSON file with the sidecars list")
-	if err := f.Parse(args); err != nil {
-		// -h/-help: render the same banner as the other subcommands, then let
-		// the caller treat it as a clean (non-error) exit. Other parse errors
-		// stay quiet here (output is discarded) and are surfaced by the caller.
-		if errors.Is(err, flag.ErrHelp) {
-			writeSubUsage(helpOut, f, "deployment", "deploy")
+	f.StringVar(&requestFile, "f", "", requestFileUsage)
+	return f, func(args []string) (api.DeploymentDeploy, string, error) {
+		fileArgs, err := applyRequestFile(f, args, &req)
+		if err != nil {
+			return req, "", err
+		}
+		if err := f.Parse(append(fileArgs, args...)); err != nil {
+			// -h/-help: render the same banner as the other subcommands, then let
+			// the caller treat it as a clean (non-error) exit. Other parse errors
+			// stay quiet here (output is discarded) and are surfaced by the caller.
+			if errors.Is(err, flag.ErrHelp) {
+				writeSubUsage(helpOut, f, "deployment", "deploy")
+			}
+			return req, "", err
 		}
-		return req, "", err
-	}
-
-	set := visitedFlags(f)
 
-	req.Type = api.ParseDeploymentTypeString(typ)
-	if port > 0 {
-		req.Port = &port
-	}
-	if minReplicas > 0 {
-		req.MinReplicas = &minReplicas
-	}
-	if maxReplicas > 0 {
-		req.MaxReplicas = &maxReplicas
-	}
-	if set["protocol"] {
-		p := api.DeploymentProtocol(protocol)
-		req.Protocol = &p
-	}
-	if set["internal"] {
-		req.Internal = &internal
-	}
-	if set["workloadIdentity"] {
-		req.WorkloadIdentity = &workloadIdentity
-	}
-	if set["pullSecret"] {
-		req.PullSecret = &pullSecret
-	}
-	if set["schedule"] {
-		req.Schedule = &schedule
-	}
-	if set["ttl"] {
-		req.TTL = &ttl
-	}
+		set := visitedFlags(f)
 
-	var err error
-	if req.Env, err = parseKV(env); err != nil {
-		return req, "", err
-	}
-	if req.AddEnv, err = parseKV(addEnv); err != nil {
-		return req, "", err
-	}
-	if req.MountData, err = parseKV(mountData); err != nil {
-		return req, "", err
-	}
-	req.RemoveEnv = splitComma(removeEnv)
-	req.EnvGroups = splitComma(envGroups)
-	req.AddEnvGroups = splitComma(addEnvGroups)
-	req.RemoveEnvGroups = splitComma(removeEnvGroups)
-	req.Command = splitComma(command)
-	req.Args = splitComma(cmdArgs)
-
-	if diskName != "" {
-		req.Disk = &api.DeploymentDisk{
-			Name:      diskName,
-			MountPath: diskMountPath,
-			SubPath:   diskSubPath,
+		// the flags leave what a -f request file set alone unless passed
+		if set["type"] {
+			req.Type = api.ParseDeploymentTypeString(typ)
 		}
-	}
-	if cpuRequest != "" || memRequest != "" || cpuLimit != "" || memLimit != "" {
-		req.Resources = &api.DeploymentResource{
-			Requests: api.ResourceItem{CPU: cpuRequest, Memory: memRequest},
-			Limits:   api.ResourceItem{CPU: cpuLimit, Memory: memLimit},
+		if port > 0 {
+			req.Port = &port
 		}
-	}
-	if requireGoogleLogin || allowedEmails != "" || allowedDomains != "" {
-		req.Access = &api.DeploymentAccessConfig{
-			RequireGoogleLogin: requireGoogleLogin,
-			AllowedEmails:      splitComma(allowedEmails),
-			AllowedDomains:     splitComma(allowedDomains),
+		if minReplicas > 0 {
+			req.MinReplicas = &minReplicas
 		}
-	}
-	if sidecarsFile != "" {
-		b, err := os.ReadFile(sidecarsFile)
-		if err != nil {
-			return req, "", err
+		if maxReplicas > 0 {
+			req.MaxReplicas = &maxReplicas
 		}
-		if err := yaml.Unmarshal(b, &req.Sidecars); err != nil {
-			return req, "", fmt.Errorf("invalid sidecars file %q: %w", sidecarsFile, err)
+		if set["protocol"] {
+			p := api.DeploymentProtocol(protocol)
+			req.Protocol = &p
+		}
+		if set["internal"] {
+			req.Internal = &internal
+		}
+		if set["workloadIdentity"] {
+			req.WorkloadIdentity = &workloadIdentity
+		}
+		if set["pullSecret"] {
+			req.PullSecret = &pullSecret
+		}
+		if set["schedule"] {
+			req.Schedule = &schedule
+		}
+		if set["ttl"] {
+			req.TTL = &ttl
 		}
-	}
 
-	return req, outputMode, nil
-}
+		if len(env) > 0 {
+			if req.Env, err = parseKV(env); err != nil {
+				return req, "", err
+			}
+		}
+		if len(addEnv) > 0 {
+			if req.AddEnv, err = parseKV(addEnv); err != nil {
+				return req, "", err
+			}
+		}
+		if len(mountData) > 0 {
+			if req.MountData, err = parseKV(mountData); err != nil {
+				return req, "", err
+			}
+		}
+		if set["removeEnv"] {
+			req.RemoveEnv = splitComma(removeEnv)
+		}
+		if set["envGroups"] {
+			req.EnvGroups = splitComma(envGroups)
+		}
+		if set["addEnvGroups"] {
+			req.AddEnvGroups = splitComma(addEnvGroups)
+		}
+		if set["removeEnvGroups"] {
+			req.RemoveEnvGroups = splitComma(removeEnvGroups)
+		}
+		if set["command"] {
+			req.Command = splitComma(command)
+		}
+		if set["args"] {
+			req.Args = splitComma(cmdArgs)
+		}
 
-func (rn Runner) deploymentSet(args ...string) error {
-	// set currently has a single leaf, `image`; its flag set doubles as the
-	// help banner for `set`, `set -h`, and `set image -h`.
-	newImageFlags := func() (*flag.FlagSet, *api.DeploymentDeploy) {
-		req := &api.DeploymentDeploy{}
-		f := rn.subFlagSet("deployment", "set image")
-		f.StringVar(&req.Location, "location", "", "location")
-		f.StringVar(&req.Project, "project", "", "project id")
-		f.StringVar(&req.Image, "image", "", "deployment image")
-		return f, req
-	}
+		if diskName != "" {
+			req.Disk = &api.DeploymentDisk{
+				Name:      diskName,
+				MountPath: diskMountPath,
+				SubPath:   diskSubPath,
+			}
+		}
+		if cpuRequest != "" || memRequest != "" || cpuLimit != "" || memLimit != "" {
+			req.Resources = &api.DeploymentResource{
+				Requests: api.ResourceItem{CPU: cpuRequest, Memory: memRequest},
+				Limits:   api.ResourceItem{CPU: cpuLimit, Memory: memLimit},
+			}
+		}
+		if requireGoogleLogin || allowedEmails != "" || allowedDomains != "" {
+			req.Access = &api.DeploymentAccessConfig{
+				RequireGoogleLogin: requireGoogleLogin,
+				AllowedEmails:      splitComma(allowedEmails),
+				AllowedDomains:     splitComma(allowedDomains),
+			}
+		}
+		if sidecarsFile != "" {
+			b, err := os.ReadFile(sidecarsFile)
+			if err != nil {
+				return req, "", err
+			}
+			if err := yaml.Unmarshal(b, &req.Sidecars); err != nil {
+				return req, "", fmt.Errorf("invalid sidecars file %q: %w", sidecarsFile, err)
+			}
+		}
 
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		f, _ := newImageFlags()
-		f.Usage()
-		return nil
+		return req, outputMode, nil
 	}
+}
 
-	switch args[0] {
-	default:
-		return rn.unknownSub("deployment set", args[0])
-	case "image":
-		f, req := newImageFlags()
+// deploymentSetLeaf runs `deployment set`. set currently has a single leaf,
+// `image`; its flag set doubles as the help banner for `set`, `set -h`, and
+// `set image -h`.
+func (rn *Runner) deploymentSetLeaf(f *leafFlagSet) leafRun {
+	var req api.DeploymentDeploy
+	g := rn.subFlagSet("deployment", "set image")
+	g.StringVar(&req.Location, "location", "", "location")
+	g.StringVar(&req.Project, "project", "", "project id")
+	g.StringVar(&req.Image, "image", "", "deployment image")
+	g.Request(&req)
+	f.FlagSet = g.FlagSet
+	return func(args []string) (resp any, err error) {
+		if len(args) == 0 || IsHelpArg(args[0]) {
+			g.Usage()
+			return nil, nil
+		}
+		if args[0] != "image" {
+			return nil, rn.unknownSub("deployment set", args[0])
+		}
 		if len(args) < 2 || IsHelpArg(args[1]) {
-			f.Usage()
-			return nil
+			g.Usage()
+			return nil, nil
 		}
+		g.Parse(args[2:])
 		req.Name = args[1]
-		f.Parse(args[2:])
-		resp, err := rn.API.Deployment().Deploy(context.Background(), req)
-		if err != nil {
-			return err
-		}
-		return rn.print(resp)
+		o := rn.journalBegin("deployment set image", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+		resp, err = rn.API.Deployment().Deploy(rn.ctx(), &req)
+		o.done(err)
+		return resp, err
 	}
 }
 
 func (rn Runner) disk(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("disk")
-	}
+	return rn.runGroup("disk", args, rn.diskLeaf)
+}
 
+func (rn *Runner) diskLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Disk()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("disk", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("disk", args[0])
+	switch sub {
 	case "create":
 		var req api.DiskCreate
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "disk name")
 		f.Int64Var(&req.Size, "size", 1, "disk size (Gi)")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("disk create", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "get":
 		var req api.DiskGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "disk name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.DiskList
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "update":
 		var req api.DiskUpdate
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "disk name")
 		f.Int64Var(&req.Size, "size", 0, "disk size (Gi)")
-		f.Parse(args[1:])
-		resp, err = s.Update(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("disk update", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "delete":
-		var req api.DiskDelete
+		var (
+			req   api.DiskDelete
+			yes   bool
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "disk name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+		f.BoolVar(&force, "force", false, "delete even if other resources still reference it, or it is protected")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.checkRefs("disk", req.Project, req.Location, req.Name, force); err != nil {
+				return nil, err
+			}
+			if err := rn.confirmDestructive("disk", "delete", req.Project, req.Name, yes, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("disk delete", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "metrics":
 		var (
 			req       api.DiskMetrics
@@ -1007,32 +1358,23 @@ func (rn Runner) disk(args ...string) error {
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "disk name")
 		f.StringVar(&timeRange, "time-range", "1h", "time range (1h, 6h, 12h, 1d, 2d, 7d, 30d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.DiskMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.TimeRange = api.DiskMetricsTimeRange(timeRange)
+			return s.Metrics(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) pullSecret(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("pullsecret")
-	}
+	return rn.runGroup("pullsecret", args, rn.pullSecretLeaf)
+}
 
+func (rn *Runner) pullSecretLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.PullSecret()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("pullsecret", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("pullsecret", args[0])
+	switch sub {
 	case "create":
 		var req api.PullSecretCreate
 		f.StringVar(&req.Location, "location", "", "location")
@@ -1041,171 +1383,227 @@ func (rn Runner) pullSecret(args ...string) error {
 		f.StringVar(&req.Spec.Server, "server", "", "server")
 		f.StringVar(&req.Spec.Username, "username", "", "username")
 		f.StringVar(&req.Spec.Password, "password", "", "password")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("pullsecret create", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list":
 		var req api.PullSecretList
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.PullSecretGet
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "delete":
-		var req api.PullSecretDelete
+		var (
+			req   api.PullSecretDelete
+			force bool
+		)
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.BoolVar(&force, "force", false, "delete even if other resources still reference it")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.checkRefs("pullsecret", req.Project, req.Location, req.Name, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("pullsecret delete", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) workloadIdentity(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("workloadidentity")
-	}
+	return rn.runGroup("workloadidentity", args, rn.workloadIdentityLeaf)
+}
 
+func (rn *Runner) workloadIdentityLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.WorkloadIdentity()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("workloadidentity", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("workloadidentity", args[0])
+	switch sub {
 	case "create":
 		var req api.WorkloadIdentityCreate
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "workload identity name")
 		f.StringVar(&req.GSA, "gsa", "", "google service account")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("workloadidentity create", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "get":
 		var req api.WorkloadIdentityGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "workload identity name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.WorkloadIdentityList
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "delete":
-		var req api.WorkloadIdentityDelete
+		var (
+			req   api.WorkloadIdentityDelete
+			force bool
+		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&req.Name, "name", "", "workload identity name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.BoolVar(&force, "force", false, "delete even if other resources still reference it")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if err := req.Valid(); err != nil {
+				return nil, err
+			}
+			if err := rn.checkRefs("workloadidentity", req.Project, req.Location, req.Name, force); err != nil {
+				return nil, err
+			}
+			o := rn.journalBegin("workloadidentity delete", journalTarget{Project: req.Project, Location: req.Location, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) serviceAccount(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("serviceaccount")
-	}
+	return rn.runGroup("serviceaccount", args, rn.serviceAccountLeaf)
+}
 
+func (rn *Runner) serviceAccountLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.ServiceAccount()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("serviceaccount", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("serviceaccount", args[0])
+	switch sub {
 	case "create":
 		var req api.ServiceAccountCreate
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.SID, "id", "", "service account id")
 		f.StringVar(&req.Name, "name", "", "name")
 		f.StringVar(&req.Description, "description", "", "description")
-		f.Parse(args[1:])
-		resp, err = s.Create(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("serviceaccount create", journalTarget{Project: req.Project, Name: req.SID}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "list":
 		var req api.ServiceAccountList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.ServiceAccountGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.ID, "id", "", "service account id")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "update":
 		var req api.ServiceAccountUpdate
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.SID, "id", "", "service account id")
 		f.StringVar(&req.Name, "name", "", "name")
 		f.StringVar(&req.Description, "description", "", "description")
-		f.Parse(args[1:])
-		resp, err = s.Update(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("serviceaccount update", journalTarget{Project: req.Project, Name: req.SID}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "delete":
 		var req api.ServiceAccountDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.ID, "id", "", "service account id")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("serviceaccount delete", journalTarget{Project: req.Project, Name: req.ID}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "createkey":
 		var req api.ServiceAccountCreateKey
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.ID, "id", "", "service account id")
-		f.Parse(args[1:])
-		resp, err = s.CreateKey(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("serviceaccount createkey", journalTarget{Project: req.Project, Name: req.ID}, &req)
+			resp, err = s.CreateKey(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "deletekey":
 		var req api.ServiceAccountDeleteKey
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.ID, "id", "", "service account id")
 		f.StringVar(&req.Secret, "secret", "", "secret")
-		f.Parse(args[1:])
-		resp, err = s.DeleteKey(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("serviceaccount deletekey", journalTarget{Project: req.Project, Name: req.ID}, &req)
+			resp, err = s.DeleteKey(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
 
 func (rn Runner) github(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("github")
-	}
+	return rn.runGroup("github", args, rn.githubLeaf)
+}
 
+func (rn *Runner) githubLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.GitHub()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("github", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("github", args[0])
+	switch sub {
 	case "link":
 		var (
 			req        api.GitHubLink
@@ -1217,21 +1615,27 @@ func (rn Runner) github(args ...string) error {
 		f.StringVar(&req.ServiceAccount, "service-account", "", "service account id")
 		f.StringVar(&req.ProductionBranch, "production-branch", "", "production branch (empty = any branch; ignored for -trigger pr)")
 		f.StringVar(&trigger, "trigger", "all", "deploy trigger: all | branch | pr")
-		f.Parse(args[1:])
-		req.Trigger = api.ParseGitHubTriggerString(trigger)
-		// Resolve owner/name to the immutable repository id through the
-		// github app — this also verifies the app is installed on the repo.
-		lookup, lerr := s.LookupRepo(context.Background(), &api.GitHubLookupRepo{
-			Project:    req.Project,
-			Repository: repository,
-		})
-		if lerr != nil {
-			return lerr
-		}
-		req.RepositoryID = lookup.RepositoryID
-		req.Repository = lookup.Repository
-		req.InstallationID = lookup.InstallationID
-		resp, err = s.Link(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.Trigger = api.ParseGitHubTriggerString(trigger)
+			// Resolve owner/name to the immutable repository id through the
+			// github app — this also verifies the app is installed on the repo.
+			lookup, lerr := s.LookupRepo(rn.ctx(), &api.GitHubLookupRepo{
+				Project:    req.Project,
+				Repository: repository,
+			})
+			if lerr != nil {
+				return nil, lerr
+			}
+			req.RepositoryID = lookup.RepositoryID
+			req.Repository = lookup.Repository
+			req.InstallationID = lookup.InstallationID
+			o := rn.journalBegin("github link", journalTarget{Project: req.Project, Name: req.Repository}, &req)
+			resp, err = s.Link(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "unlink":
 		var (
 			req        api.GitHubUnlink
@@ -1240,18 +1644,24 @@ func (rn Runner) github(args ...string) error {
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&repository, "repository", "", "repository (owner/name)")
 		f.Int64Var(&req.RepositoryID, "repository-id", 0, "github repository id (alternative to -repository)")
-		f.Parse(args[1:])
-		if req.RepositoryID == 0 && repository != "" {
-			lookup, lerr := s.LookupRepo(context.Background(), &api.GitHubLookupRepo{
-				Project:    req.Project,
-				Repository: repository,
-			})
-			if lerr != nil {
-				return lerr
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if req.RepositoryID == 0 && repository != "" {
+				lookup, lerr := s.LookupRepo(rn.ctx(), &api.GitHubLookupRepo{
+					Project:    req.Project,
+					Repository: repository,
+				})
+				if lerr != nil {
+					return nil, lerr
+				}
+				req.RepositoryID = lookup.RepositoryID
 			}
-			req.RepositoryID = lookup.RepositoryID
+			o := rn.journalBegin("github unlink", journalTarget{Project: req.Project, Name: strconv.FormatInt(req.RepositoryID, 10)}, &req)
+			resp, err = s.Unlink(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		resp, err = s.Unlink(context.Background(), &req)
 	case "update":
 		var (
 			req        api.GitHubUpdate
@@ -1264,68 +1674,66 @@ func (rn Runner) github(args ...string) error {
 		f.StringVar(&req.ServiceAccount, "service-account", "", "service account id")
 		f.StringVar(&req.ProductionBranch, "production-branch", "", "production branch (empty = any branch; ignored for -trigger pr)")
 		f.StringVar(&trigger, "trigger", "", "deploy trigger: all | branch | pr")
-		f.Parse(args[1:])
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+
+			if req.RepositoryID == 0 && repository != "" {
+				lookup, lerr := s.LookupRepo(rn.ctx(), &api.GitHubLookupRepo{
+					Project:    req.Project,
+					Repository: repository,
+				})
+				if lerr != nil {
+					return nil, lerr
+				}
+				req.RepositoryID = lookup.RepositoryID
+			}
+			if req.RepositoryID == 0 {
+				return nil, fmt.Errorf("-repository or -repository-id required")
+			}
 
-		if req.RepositoryID == 0 && repository != "" {
-			lookup, lerr := s.LookupRepo(context.Background(), &api.GitHubLookupRepo{
-				Project:    req.Project,
-				Repository: repository,
-			})
+			// Update is a full replace, so seed every field from the existing link
+			// and override only the flags the user actually passed — omitting a flag
+			// preserves its current value instead of resetting it.
+			list, lerr := s.List(rn.ctx(), &api.GitHubList{Project: req.Project})
 			if lerr != nil {
-				return lerr
+				return nil, lerr
 			}
-			req.RepositoryID = lookup.RepositoryID
-		}
-		if req.RepositoryID == 0 {
-			return fmt.Errorf("-repository or -repository-id required")
-		}
-
-		// Update is a full replace, so seed every field from the existing link
-		// and override only the flags the user actually passed — omitting a flag
-		// preserves its current value instead of resetting it.
-		list, lerr := s.List(context.Background(), &api.GitHubList{Project: req.Project})
-		if lerr != nil {
-			return lerr
-		}
-		var cur *api.GitHubLinkItem
-		for _, it := range list.Items {
-			if it.RepositoryID == req.RepositoryID {
-				cur = it
-				break
+			var cur *api.GitHubLinkItem
+			for _, it := range list.Items {
+				if it.RepositoryID == req.RepositoryID {
+					cur = it
+					break
+				}
 			}
+			if cur == nil {
+				return nil, fmt.Errorf("github: repository link not found for repository id %d", req.RepositoryID)
+			}
+			set := visitedFlags(f.FlagSet)
+			if !set["service-account"] {
+				req.ServiceAccount = cur.ServiceAccount
+			}
+			if !set["production-branch"] {
+				req.ProductionBranch = cur.ProductionBranch
+			}
+			if set["trigger"] {
+				req.Trigger = api.ParseGitHubTriggerString(trigger)
+			} else {
+				req.Trigger = cur.Trigger
+			}
+			o := rn.journalBegin("github update", journalTarget{Project: req.Project, Name: strconv.FormatInt(req.RepositoryID, 10)}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		if cur == nil {
-			return fmt.Errorf("github: repository link not found for repository id %d", req.RepositoryID)
-		}
-		set := visitedFlags(f)
-		if !set["service-account"] {
-			req.ServiceAccount = cur.ServiceAccount
-		}
-		if !set["production-branch"] {
-			req.ProductionBranch = cur.ProductionBranch
-		}
-		if set["trigger"] {
-			req.Trigger = api.ParseGitHubTriggerString(trigger)
-		} else {
-			req.Trigger = cur.Trigger
-		}
-		resp, err = s.Update(context.Background(), &req)
 	case "list":
 		var req api.GitHubList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
-	}
-	if err != nil {
-		return err
-	}
-	return rn.print(resp)
-}
-
-func (rn Runner) collector(args ...string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("invalid command")
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	}
-
 	return nil
 }
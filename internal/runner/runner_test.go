@@ -60,6 +60,63 @@ func TestReplaceShortFlag_Toon(t *testing.T) {
 	}
 }
 
+type testTable [][]string
+
+func (t testTable) Table() [][]string { return t }
+
+// csv, tsv, and markdown render any tablePrinter, escaping the characters that
+// would otherwise break their structure.
+func TestPrint_Delimited(t *testing.T) {
+	table := testTable{
+		{"NAME", "NOTE"},
+		{"web", `say "hi", then|leave`},
+		{"tab\tbed", "two\nlines \\ slash"},
+		{"my_app", "*bold*"},
+	}
+	cases := map[string]string{
+		"csv": "NAME,NOTE\n" +
+			"web,\"say \"\"hi\"\", then|leave\"\n" +
+			"tab\tbed,\"two\nlines \\ slash\"\n" +
+			"my_app,*bold*\n",
+		"tsv": "NAME\tNOTE\n" +
+			"web\tsay \"hi\", then|leave\n" +
+			"tab\\tbed\ttwo\\nlines \\\\ slash\n" +
+			"my_app\t*bold*\n",
+		"markdown": "| NAME | NOTE |\n" +
+			"| --- | --- |\n" +
+			"| web | say \"hi\", then\\|leave |\n" +
+			"| tab\tbed | two lines \\\\ slash |\n" +
+			"| my\\_app | \\*bold\\* |\n",
+	}
+	for mode, want := range cases {
+		out := tempOut(t)
+		rn := Runner{Output: out, OutputMode: mode}
+		if err := rn.print(table); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if got := readOut(t, out); got != want {
+			t.Errorf("print(%s) = %q; want %q", mode, got, want)
+		}
+	}
+
+	// a result without a table form is an error, not a silent yaml fallback
+	rn := Runner{Output: tempOut(t), OutputMode: "csv"}
+	if err := rn.print(struct{ A int }{1}); err == nil {
+		t.Error("csv of a non-table value: want error")
+	}
+}
+
+func TestReplaceShortFlag_Delimited(t *testing.T) {
+	var rn Runner
+	args := []string{"-ocsv", "-otsv", "-omarkdown"}
+	rn.replaceShortFlag(args)
+	for i, want := range []string{"--output=csv", "--output=tsv", "--output=markdown"} {
+		if args[i] != want {
+			t.Errorf("replaceShortFlag = %q; want %q", args[i], want)
+		}
+	}
+}
+
 // Omitted optional flags must leave their request fields nil/empty so a deploy
 // is a merge that preserves the previous revision's values.
 func TestParseDeploymentDeploy_OmittedStayNil(t *testing.T) {
@@ -1,42 +1,36 @@
 package runner
 
 import (
-	"context"
+	"cmp"
 	"time"
 
 	"github.com/deploys-app/api"
 )
 
 func (rn Runner) scheduler(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("scheduler")
-	}
+	return rn.runGroup("scheduler", args, rn.schedulerLeaf)
+}
 
+func (rn *Runner) schedulerLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Scheduler()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("scheduler", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("scheduler", args[0])
-
+	switch sub {
 	case "list":
 		var req api.SchedulerList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "get":
 		var req api.SchedulerGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "scheduler job name")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "create":
 		var (
 			req      api.SchedulerCreate
@@ -58,16 +52,23 @@ func (rn Runner) scheduler(args ...string) error {
 		f.StringVar(&authPass, "auth-secret", "", "basic auth password or bearer token")
 		f.BoolVar(&req.InsecureSkipVerify, "insecure-tls", false, "skip TLS verification for HTTPS targets")
 		f.BoolVar(&req.Paused, "paused", false, "create the job paused")
-		f.Parse(args[1:])
-		req.Headers, err = parseKV(header)
-		if err != nil {
-			return err
-		}
-		if authType != "" {
-			req.Auth = api.SchedulerAuth{Type: authType, Username: authUser, Secret: authPass}
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			if len(header) > 0 {
+				req.Headers, err = parseKV(header)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if authType != "" {
+				req.Auth = api.SchedulerAuth{Type: authType, Username: authUser, Secret: authPass}
+			}
+			o := rn.journalBegin("scheduler create", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Create(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		resp, err = s.Create(context.Background(), &req)
-
 	case "update":
 		// Merge semantics: seed from the existing job, override only the flags
 		// the user explicitly passed (visitedFlags). The auth secret is never
@@ -98,88 +99,118 @@ func (rn Runner) scheduler(args ...string) error {
 		f.StringVar(&authUser, "auth-user", "", "basic auth username")
 		f.StringVar(&authPass, "auth-secret", "", "basic auth password or bearer token (omit to keep existing)")
 		f.BoolVar(&insecureTLS, "insecure-tls", false, "skip TLS verification for HTTPS targets")
-		f.Parse(args[1:])
-		set := visitedFlags(f)
-
-		// A distinct name avoids shadowing the outer err in this case block —
-		// otherwise the s.Update below would assign the shadowed err and the
-		// after-switch error check would silently miss a failed update.
-		cur, getErr := s.Get(context.Background(), &api.SchedulerGet{Project: req.Project, Name: req.Name})
-		if getErr != nil {
-			return getErr
-		}
-		req.Schedule = cur.Schedule
-		req.Timezone = cur.Timezone
-		req.Method = cur.Method
-		req.URL = cur.URL
-		req.Headers = cur.Headers
-		req.Body = cur.Body
-		req.Auth = cur.Auth // Type + Username; Secret stays empty so it is preserved
-		req.InsecureSkipVerify = cur.InsecureSkipVerify
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			set := visitedFlags(f.FlagSet)
+
+			// A distinct name avoids shadowing the outer err in this case block —
+			// otherwise the s.Update below would assign the shadowed err and the
+			// after-switch error check would silently miss a failed update.
+			cur, getErr := s.Get(rn.ctx(), &api.SchedulerGet{Project: req.Project, Name: req.Name})
+			if getErr != nil {
+				return nil, getErr
+			}
+			// fields a -f request file set are kept over the existing job's
+			req.Schedule = cmp.Or(req.Schedule, cur.Schedule)
+			req.Timezone = cmp.Or(req.Timezone, cur.Timezone)
+			req.Method = cmp.Or(req.Method, cur.Method)
+			req.URL = cmp.Or(req.URL, cur.URL)
+			if req.Headers == nil {
+				req.Headers = cur.Headers
+			}
+			req.Body = cmp.Or(req.Body, cur.Body)
+			if req.Auth == (api.SchedulerAuth{}) {
+				req.Auth = cur.Auth // Type + Username; Secret stays empty so it is preserved
+			}
+			req.InsecureSkipVerify = req.InsecureSkipVerify || cur.InsecureSkipVerify
 
-		if set["schedule"] {
-			req.Schedule = schedule
-		}
-		if set["timezone"] {
-			req.Timezone = timezone
-		}
-		if set["method"] {
-			req.Method = method
-		}
-		if set["url"] {
-			req.URL = url
-		}
-		if set["body"] {
-			req.Body = body
-		}
-		if set["insecure-tls"] {
-			req.InsecureSkipVerify = insecureTLS
-		}
-		if len(header) > 0 {
-			req.Headers, err = parseKV(header)
-			if err != nil {
-				return err
+			if set["schedule"] {
+				req.Schedule = schedule
 			}
+			if set["timezone"] {
+				req.Timezone = timezone
+			}
+			if set["method"] {
+				req.Method = method
+			}
+			if set["url"] {
+				req.URL = url
+			}
+			if set["body"] {
+				req.Body = body
+			}
+			if set["insecure-tls"] {
+				req.InsecureSkipVerify = insecureTLS
+			}
+			if len(header) > 0 {
+				req.Headers, err = parseKV(header)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if set["auth-type"] {
+				req.Auth.Type = authType
+			}
+			if set["auth-user"] {
+				req.Auth.Username = authUser
+			}
+			if set["auth-secret"] {
+				req.Auth.Secret = authPass
+			}
+			o := rn.journalBegin("scheduler update", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Update(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
 		}
-		if set["auth-type"] {
-			req.Auth.Type = authType
-		}
-		if set["auth-user"] {
-			req.Auth.Username = authUser
-		}
-		if set["auth-secret"] {
-			req.Auth.Secret = authPass
-		}
-		resp, err = s.Update(context.Background(), &req)
-
 	case "delete":
 		var req api.SchedulerDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "scheduler job name")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("scheduler delete", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "pause":
 		var req api.SchedulerPause
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "scheduler job name")
-		f.Parse(args[1:])
-		resp, err = s.Pause(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("scheduler pause", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Pause(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "resume":
 		var req api.SchedulerResume
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "scheduler job name")
-		f.Parse(args[1:])
-		resp, err = s.Resume(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("scheduler resume", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Resume(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "trigger":
 		var req api.SchedulerTrigger
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Name, "name", "", "scheduler job name")
-		f.Parse(args[1:])
-		resp, err = s.Trigger(context.Background(), &req)
-
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("scheduler trigger", journalTarget{Project: req.Project, Name: req.Name}, &req)
+			resp, err = s.Trigger(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "logs":
 		var (
 			req    api.SchedulerLogs
@@ -191,13 +222,13 @@ func (rn Runner) scheduler(args ...string) error {
 		f.IntVar(&req.Limit, "limit", 0, "max log entries (default 50, max 100)")
 		f.Var(timeFlag{&after}, "after", "only entries after this time (RFC3339 or YYYY-MM-DD)")
 		f.Var(timeFlag{&before}, "before", "only entries before this time (RFC3339 or YYYY-MM-DD)")
-		f.Parse(args[1:])
-		req.After = after
-		req.Before = before
-		resp, err = s.Logs(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			req.After = after
+			req.Before = before
+			return s.Logs(rn.ctx(), &req)
+		}
 	}
-	return rn.print(resp)
+	return nil
 }
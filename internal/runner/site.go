@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -13,15 +12,11 @@ import (
 // filesystem and uploads via the raw-HTTP /sites/* endpoints, so it uses the
 // concrete *client.Client helper (PublishSite) rather than the api.Interface.
 func (rn Runner) site(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("site")
-	}
-
-	f := rn.subFlagSet("site", args[0])
+	return rn.runGroup("site", args, rn.siteLeaf)
+}
 
-	switch args[0] {
-	default:
-		return rn.unknownSub("site", args[0])
+func (rn *Runner) siteLeaf(f *leafFlagSet, sub string) leafRun {
+	switch sub {
 	case "publish":
 		var opts client.SitePublishOptions
 		f.StringVar(&opts.Project, "project", "", "project id")
@@ -30,21 +25,23 @@ func (rn Runner) site(args ...string) error {
 		f.StringVar(&opts.Environment, "environment", "", "release environment: production (default) or pr-<n>")
 		f.BoolVar(&opts.SPA, "spa", false, "serve index.html for unmatched paths (single-page app)")
 		f.StringVar(&opts.NotFound, "notFound", "", "custom 404 document path (e.g. 404.html)")
-		f.Parse(args[1:])
+		return func(args []string) (any, error) {
+			f.Parse(args)
 
-		c, ok := rn.API.(*client.Client)
-		if !ok {
-			return fmt.Errorf("site publish requires the default api client")
-		}
+			c, ok := rn.API.(*client.Client)
+			if !ok {
+				return nil, fmt.Errorf("site publish requires the default api client")
+			}
 
-		progress, finish := newPublishProgress(os.Stderr)
-		opts.Progress = progress
-		res, err := c.PublishSite(context.Background(), &opts)
-		finish()
-		if err != nil {
-			return err
+			progress, finish := newPublishProgress(os.Stderr)
+			opts.Progress = progress
+			res, err := c.PublishSite(rn.ctx(), &opts)
+			finish()
+			if err != nil {
+				return nil, err
+			}
+			return nil, rn.print(res)
 		}
-		return rn.print(res)
 	case "deploy":
 		// deploy wraps publish → deploy → get into one command: it publishes the
 		// local directory and deploys it as a permanent (non-TTL) Static
@@ -62,18 +59,20 @@ func (rn Runner) site(args ...string) error {
 		f.StringVar(&opts.Environment, "environment", "", "release environment: production (default) or pr-<n>")
 		f.BoolVar(&opts.SPA, "spa", false, "serve index.html for unmatched paths (single-page app)")
 		f.StringVar(&opts.NotFound, "notFound", "", "custom 404 document path (e.g. 404.html)")
-		f.Parse(args[1:])
+		return func(args []string) (any, error) {
+			f.Parse(args)
 
-		c, ok := rn.API.(*client.Client)
-		if !ok {
-			return fmt.Errorf("site deploy requires the default api client")
-		}
+			c, ok := rn.API.(*client.Client)
+			if !ok {
+				return nil, fmt.Errorf("site deploy requires the default api client")
+			}
 
-		// Permanent deploy: pass an explicit TTL of 0 so a deployment of the same
-		// name that was previously a throwaway preview is promoted to a
-		// non-expiring one (a nil TTL would leave its auto-delete in place).
-		zero := int64(0)
-		return rn.deployPublishedStatic(c, &opts, location, &zero)
+			// Permanent deploy: pass an explicit TTL of 0 so a deployment of the same
+			// name that was previously a throwaway preview is promoted to a
+			// non-expiring one (a nil TTL would leave its auto-delete in place).
+			zero := int64(0)
+			return nil, rn.deployPublishedStatic(c, &opts, location, &zero)
+		}
 	case "preview":
 		// preview wraps publish → deploy(ttl) → get into one throwaway-preview
 		// step: it publishes the local directory, deploys it as a TTL'd Static
@@ -97,36 +96,39 @@ func (rn Runner) site(args ...string) error {
 		f.StringVar(&opts.NotFound, "notFound", "", "custom 404 document path (e.g. 404.html)")
 		f.Int64Var(&ttl, "ttl", 7200, "seconds until the preview auto-deletes (0 = no auto-delete)")
 		f.BoolVar(&force, "force", false, "overwrite an existing non-preview (production) deployment of the same name")
-		f.Parse(args[1:])
+		return func(args []string) (any, error) {
+			f.Parse(args)
 
-		c, ok := rn.API.(*client.Client)
-		if !ok {
-			return fmt.Errorf("site preview requires the default api client")
-		}
+			c, ok := rn.API.(*client.Client)
+			if !ok {
+				return nil, fmt.Errorf("site preview requires the default api client")
+			}
 
-		// Safety: a throwaway preview must not silently convert an existing
-		// non-preview (production) deployment into an auto-deleting one. If the
-		// target already exists and has no TTL, refuse unless -force. Fail open on
-		// any lookup error (not-found, forbidden, etc.) so the guard never blocks a
-		// legitimate preview — it only trips on a clean "exists and is permanent".
-		if !force {
-			if existing, gerr := c.Deployment().Get(context.Background(), &api.DeploymentGet{
-				Project:  opts.Project,
-				Location: location,
-				Name:     opts.Name,
-			}); gerr == nil && existing.TTL == 0 {
-				return fmt.Errorf("deployment %q already exists and is not a preview (no ttl); pass -force to overwrite it as a throwaway preview", opts.Name)
+			// Safety: a throwaway preview must not silently convert an existing
+			// non-preview (production) deployment into an auto-deleting one. If the
+			// target already exists and has no TTL, refuse unless -force. Fail open on
+			// any lookup error (not-found, forbidden, etc.) so the guard never blocks a
+			// legitimate preview — it only trips on a clean "exists and is permanent".
+			if !force {
+				if existing, gerr := c.Deployment().Get(rn.ctx(), &api.DeploymentGet{
+					Project:  opts.Project,
+					Location: location,
+					Name:     opts.Name,
+				}); gerr == nil && existing.TTL == 0 {
+					return nil, fmt.Errorf("deployment %q already exists and is not a preview (no ttl); pass -force to overwrite it as a throwaway preview", opts.Name)
+				}
 			}
-		}
 
-		// ttl > 0 sets an auto-delete; ttl <= 0 (the documented "0 = no
-		// auto-delete") leaves the deployment's TTL untouched.
-		var ttlPtr *int64
-		if ttl > 0 {
-			ttlPtr = &ttl
+			// ttl > 0 sets an auto-delete; ttl <= 0 (the documented "0 = no
+			// auto-delete") leaves the deployment's TTL untouched.
+			var ttlPtr *int64
+			if ttl > 0 {
+				ttlPtr = &ttl
+			}
+			return nil, rn.deployPublishedStatic(c, &opts, location, ttlPtr)
 		}
-		return rn.deployPublishedStatic(c, &opts, location, ttlPtr)
 	}
+	return nil
 }
 
 // deployPublishedStatic publishes opts.Dir (rendering an upload progress bar)
@@ -139,7 +141,7 @@ func (rn Runner) deployPublishedStatic(c *client.Client, opts *client.SitePublis
 	// 1. Publish the local directory → a content-addressed site ref.
 	progress, finish := newPublishProgress(os.Stderr)
 	opts.Progress = progress
-	pub, err := c.PublishSite(context.Background(), opts)
+	pub, err := c.PublishSite(rn.ctx(), opts)
 	finish()
 	if err != nil {
 		return err
@@ -154,12 +156,15 @@ func (rn Runner) deployPublishedStatic(c *client.Client, opts *client.SitePublis
 		Site:     pub.SiteRef,
 		TTL:      ttl,
 	}
-	if _, err := c.Deployment().Deploy(context.Background(), deploy); err != nil {
+	o := rn.journalBegin("deployment deploy", journalTarget{Project: deploy.Project, Location: deploy.Location, Name: deploy.Name}, deploy)
+	_, err = c.Deployment().Deploy(rn.ctx(), deploy)
+	o.done(err)
+	if err != nil {
 		return err
 	}
 
 	// 3. Read back the rolling url, the immutable releaseUrl, and expiresAt.
-	got, err := c.Deployment().Get(context.Background(), &api.DeploymentGet{
+	got, err := c.Deployment().Get(rn.ctx(), &api.DeploymentGet{
 		Project:  opts.Project,
 		Location: location,
 		Name:     opts.Name,
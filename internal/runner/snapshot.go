@@ -0,0 +1,304 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/deploys-app/deploys/internal/auth"
+)
+
+// snapshotLayout names a zone snapshot file. It sorts lexically in time order,
+// carries no ':' (so the name is valid on Windows), and has millisecond
+// precision so two sets in the same second don't collide.
+const snapshotLayout = "20060102T150405.000Z"
+
+// defaultSnapshotKeep is how many snapshots a `set`/`restore` retains per
+// (zone kind, project, location) when -keep is not given.
+const defaultSnapshotKeep = 20
+
+// zoneSnapshot is one saved zone: the yaml form of its `get` result, taken just
+// before a `set` replaced it.
+type zoneSnapshot struct {
+	At   string    `json:"at" yaml:"at"`
+	Time time.Time `json:"time" yaml:"time"`
+	Size int64     `json:"size" yaml:"size"`
+	Path string    `json:"path" yaml:"path"`
+}
+
+// zoneSnapshotList is the result of `waf|cache|transform history`.
+type zoneSnapshotList struct {
+	Kind     string         `json:"kind" yaml:"kind"`
+	Project  string         `json:"project" yaml:"project"`
+	Location string         `json:"location" yaml:"location"`
+	Items    []zoneSnapshot `json:"items" yaml:"items"`
+}
+
+func (l zoneSnapshotList) Table() [][]string {
+	table := [][]string{
+		{"AT", "TIME", "SIZE"},
+	}
+	for _, x := range l.Items {
+		table = append(table, []string{
+			x.At,
+			x.Time.Local().Format(time.RFC3339),
+			humanByteSize(x.Size),
+		})
+	}
+	return table
+}
+
+// zoneSnapshotDir resolves <config dir>/snapshots/<kind>/<project>/<location>.
+// project and location become path elements, so anything that could escape the
+// snapshot tree is rejected rather than cleaned.
+func zoneSnapshotDir(kind, project, location string) (string, error) {
+	if project == "" || location == "" {
+		return "", fmt.Errorf("snapshots need -project and -location")
+	}
+	for _, p := range []string{project, location} {
+		if p == "." || p == ".." || strings.ContainsAny(p, `/\`) {
+			return "", fmt.Errorf("invalid snapshot path element %q", p)
+		}
+	}
+	d, err := auth.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "snapshots", kind, project, location), nil
+}
+
+// saveZoneSnapshot writes v as a new timestamped snapshot and prunes the oldest
+// ones beyond keep (keep <= 0 keeps every snapshot). It returns the snapshot's
+// timestamp, the value `restore -at` takes.
+func saveZoneSnapshot(kind, project, location string, v any, keep int) (string, error) {
+	dir, err := zoneSnapshotDir(kind, project, location)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	at := time.Now().UTC().Format(snapshotLayout)
+	if err := os.WriteFile(filepath.Join(dir, at+".yaml"), b, 0o600); err != nil {
+		return "", err
+	}
+	if keep > 0 {
+		if err := pruneZoneSnapshots(dir, keep); err != nil {
+			return "", err
+		}
+	}
+	return at, nil
+}
+
+// listZoneSnapshots returns a zone's snapshots, newest first. A zone that was
+// never snapshotted has an empty history, not an error.
+func listZoneSnapshots(kind, project, location string) ([]zoneSnapshot, error) {
+	dir, err := zoneSnapshotDir(kind, project, location)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res []zoneSnapshot
+	for _, e := range entries {
+		at, ok := strings.CutSuffix(e.Name(), ".yaml")
+		if !ok || e.IsDir() {
+			continue
+		}
+		t, err := time.Parse(snapshotLayout, at)
+		if err != nil {
+			continue // not ours
+		}
+		var size int64
+		if fi, err := e.Info(); err == nil {
+			size = fi.Size()
+		}
+		res = append(res, zoneSnapshot{
+			At:   at,
+			Time: t,
+			Size: size,
+			Path: filepath.Join(dir, e.Name()),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].At > res[j].At })
+	return res, nil
+}
+
+// readZoneSnapshot decodes the snapshot taken at `at` into into (non-strict, so
+// the read-only fields of the saved `get` result are ignored by the set
+// request). "latest" selects the newest snapshot.
+func readZoneSnapshot(kind, project, location, at string, into any) error {
+	if at == "" {
+		return fmt.Errorf("snapshot timestamp required (-at; see history)")
+	}
+	list, err := listZoneSnapshots(kind, project, location)
+	if err != nil {
+		return err
+	}
+	var snap *zoneSnapshot
+	for i := range list {
+		if list[i].At == at || (at == "latest" && i == 0) {
+			snap = &list[i]
+			break
+		}
+	}
+	if snap == nil {
+		return fmt.Errorf("no %s snapshot %q for %s/%s (run \"deploys %s history\")", kind, at, project, location, kind)
+	}
+	b, err := os.ReadFile(snap.Path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, into); err != nil {
+		return fmt.Errorf("parse %s: %w", snap.Path, err)
+	}
+	return nil
+}
+
+// pruneZoneSnapshots removes all but the newest keep snapshots in dir.
+func pruneZoneSnapshots(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		at, ok := strings.CutSuffix(e.Name(), ".yaml")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(snapshotLayout, at); err != nil {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	sort.Strings(names)
+	for _, n := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, n)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotZone saves the zone's current state (via get) before a set or
+// restore replaces it. A zone that does not exist yet (notFound) has nothing to
+// lose, so it is skipped. The saved timestamp goes to stderr so it never mixes
+// with -output on stdout.
+func snapshotZone(kind, project, location string, keep int, get func() (any, error), notFound error) error {
+	cur, err := get()
+	if errors.Is(err, notFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot %s before set: %w", kind, err)
+	}
+	at, err := saveZoneSnapshot(kind, project, location, cur, keep)
+	if err != nil {
+		return fmt.Errorf("snapshot %s before set: %w", kind, err)
+	}
+	fmt.Fprintf(os.Stderr, "saved %s snapshot %s (restore with: deploys %s restore -project %s -location %s -at %s)\n",
+		kind, at, kind, project, location, at)
+	return nil
+}
+
+// snapshotKeepUsage is the shared -keep flag description.
+const snapshotKeepUsage = "snapshots to retain per project/location (0 keeps all)"
+
+// zoneSnapshots is what the set, history, and restore commands of a zone kind
+// (waf, cache, transform) need from its service. The zone a set or restore
+// replaces is saved as a local snapshot first (see history and restore), so a
+// bad apply never loses the previous good set.
+type zoneSnapshots struct {
+	kind     string
+	notFound error // the service's error for a zone that does not exist yet
+	get      func(project, location string) (any, error)
+}
+
+// apply snapshots the zone at project/location, then replaces it by calling
+// set, journaled as "<kind> <op>" with req, the set request.
+func (z zoneSnapshots) apply(rn *Runner, op, project, location string, keep int, req any, set func() (any, error)) (any, error) {
+	if err := z.snapshot(project, location, keep); err != nil {
+		return nil, err
+	}
+	o := rn.journalBegin(z.kind+" "+op, journalTarget{Project: project, Location: location}, req)
+	resp, err := set()
+	o.done(err)
+	return resp, err
+}
+
+// snapshot saves the zone at project/location before a change replaces it.
+func (z zoneSnapshots) snapshot(project, location string, keep int) error {
+	return snapshotZone(z.kind, project, location, keep, func() (any, error) {
+		return z.get(project, location)
+	}, z.notFound)
+}
+
+// zoneSnapshotsFor returns the zoneSnapshots of a zone kind, so an undo that
+// replaces or deletes a zone snapshots it like set and restore do.
+func (rn Runner) zoneSnapshotsFor(kind string) (zoneSnapshots, bool) {
+	switch kind {
+	case "waf":
+		return rn.wafZone(), true
+	case "cache":
+		return rn.cacheZone(), true
+	case "transform":
+		return rn.transformZone(), true
+	}
+	return zoneSnapshots{}, false
+}
+
+// history lists the zone's snapshots, newest first.
+func (z zoneSnapshots) history(f *leafFlagSet) leafRun {
+	res := zoneSnapshotList{Kind: z.kind}
+	f.StringVar(&res.Project, "project", "", "project id")
+	f.StringVar(&res.Location, "location", "", "location")
+	return func(args []string) (any, error) {
+		f.Parse(args)
+		var err error
+		res.Items, err = listZoneSnapshots(res.Kind, res.Project, res.Location)
+		return res, err
+	}
+}
+
+// restore is a set from a local snapshot, so the zone it replaces is
+// snapshotted first too (a restore can itself be undone). req is the kind's
+// set request, project and location point at its fields, and set sends it.
+func (z zoneSnapshots) restore(rn *Runner, f *leafFlagSet, req any, project, location *string, set func() (any, error)) leafRun {
+	var (
+		at   string
+		keep int
+	)
+	f.StringVar(project, "project", "", "project id")
+	f.StringVar(location, "location", "", "location")
+	f.StringVar(&at, "at", "", "snapshot timestamp from history (or latest)")
+	f.IntVar(&keep, "keep", defaultSnapshotKeep, snapshotKeepUsage)
+	f.Request(req)
+	return func(args []string) (any, error) {
+		f.Parse(args)
+		p, l := *project, *location
+		if err := readZoneSnapshot(z.kind, p, l, at, req); err != nil {
+			return nil, err
+		}
+		*project, *location = p, l
+		return z.apply(rn, "restore", p, l, keep, req, set)
+	}
+}
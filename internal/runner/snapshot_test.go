@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+// A snapshot round-trips the yaml of a `get` result into the matching set
+// request, and retention keeps only the newest -keep files.
+func TestZoneSnapshotSaveListRestore(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+
+	var ats []string
+	for i := range 3 {
+		item := &api.WAFItem{
+			Project:     "acme",
+			Location:    "gke.cluster-rcf2",
+			Description: "v" + strconv.Itoa(i),
+			Rules:       []api.WAFRule{{ID: "r1", Expression: "true", Action: api.WAFActionBlock}},
+			Status:      api.Success,
+		}
+		at, err := saveZoneSnapshot("waf", "acme", "gke.cluster-rcf2", item, 2)
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		ats = append(ats, at)
+		time.Sleep(2 * time.Millisecond) // distinct millisecond timestamps
+	}
+
+	list, err := listZoneSnapshots("waf", "acme", "gke.cluster-rcf2")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("retention kept %d snapshots; want 2", len(list))
+	}
+	if list[0].At != ats[2] || list[1].At != ats[1] {
+		t.Errorf("list order = %s, %s; want newest first %s, %s", list[0].At, list[1].At, ats[2], ats[1])
+	}
+
+	var req api.WAFSet
+	if err := readZoneSnapshot("waf", "acme", "gke.cluster-rcf2", "latest", &req); err != nil {
+		t.Fatalf("read latest: %v", err)
+	}
+	if req.Description != "v2" || len(req.Rules) != 1 || req.Rules[0].Action != api.WAFActionBlock {
+		t.Errorf("restored request = %+v", req)
+	}
+	if err := readZoneSnapshot("waf", "acme", "gke.cluster-rcf2", ats[0], &req); err == nil {
+		t.Error("a pruned snapshot should not be found")
+	}
+}
+
+func TestZoneSnapshotEmptyHistory(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	list, err := listZoneSnapshots("cache", "acme", "loc")
+	if err != nil || len(list) != 0 {
+		t.Errorf("empty history = %v, %v; want none, nil", list, err)
+	}
+}
+
+func TestZoneSnapshotRejectsPathEscape(t *testing.T) {
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	for _, p := range [][2]string{{"..", "loc"}, {"acme", "a/b"}, {"", "loc"}} {
+		if _, err := zoneSnapshotDir("waf", p[0], p[1]); err == nil {
+			t.Errorf("zoneSnapshotDir(%q, %q) should fail", p[0], p[1])
+		}
+	}
+}
+
+// A zone that does not exist yet is not an error: there is nothing to save.
+func TestSnapshotZoneSkipsMissingZone(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DEPLOYS_CONFIG_DIR", dir)
+	err := snapshotZone("waf", "acme", "loc", 0, func() (any, error) {
+		return nil, api.ErrWAFZoneNotFound
+	}, api.ErrWAFZoneNotFound)
+	if err != nil {
+		t.Fatalf("snapshotZone: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snapshots")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("no snapshot dir should be created for a missing zone (stat err %v)", err)
+	}
+}
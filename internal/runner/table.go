@@ -0,0 +1,339 @@
+package runner
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deploys-app/api"
+)
+
+// tableOptions are the flags that shape tabular output, shared by every
+// command through registerFlags.
+type tableOptions struct {
+	columns   string // comma-separated header names, in display order
+	sortBy    string // header name; a leading "-" sorts descending
+	noHeaders bool
+	wide      bool
+}
+
+// shapeTable returns v's table as the table flags ask: the wide form when one
+// exists (see wideTable), rows sorted by -sort-by, then the -columns selection
+// (by default the plain columns, or every wide one under -wide), and the
+// header dropped under -no-headers. -sort-by and -columns may name a wide
+// column without -wide.
+func (rn Runner) shapeTable(v any, tp tablePrinter) ([][]string, error) {
+	t := tp.Table()
+	if len(t) == 0 {
+		return t, nil
+	}
+	visible := len(t[0])
+	if wt := wideTable(v); wt != nil {
+		if rn.table.wide {
+			visible = len(wt[0])
+		}
+		t = wt
+	}
+
+	if rn.table.sortBy != "" {
+		name, desc := strings.CutPrefix(rn.table.sortBy, "-")
+		i, err := columnIndex(t[0], name)
+		if err != nil {
+			return nil, fmt.Errorf("-sort-by: %w", err)
+		}
+		rows := slices.Clone(t[1:])
+		slices.SortStableFunc(rows, func(a, b []string) int {
+			c := compareCells(cell(a, i), cell(b, i))
+			if desc {
+				return -c
+			}
+			return c
+		})
+		t = append([][]string{t[0]}, rows...)
+	}
+
+	idx := make([]int, visible)
+	for i := range idx {
+		idx[i] = i
+	}
+	if rn.table.columns != "" {
+		idx = idx[:0]
+		for _, name := range strings.Split(rn.table.columns, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			i, err := columnIndex(t[0], name)
+			if err != nil {
+				return nil, fmt.Errorf("-columns: %w", err)
+			}
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) != len(t[0]) || !slices.IsSorted(idx) {
+		shaped := make([][]string, len(t))
+		for r, row := range t {
+			shaped[r] = make([]string, len(idx))
+			for j, i := range idx {
+				shaped[r][j] = cell(row, i)
+			}
+		}
+		t = shaped
+	}
+
+	// markdown needs its header row to be a table at all
+	if rn.table.noHeaders && rn.OutputMode != "markdown" {
+		t = t[1:]
+	}
+	return t, nil
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// columnIndex finds a column by header name, ignoring case and the spaces,
+// hyphens, and underscores between words ("created-by" is "CREATED BY").
+func columnIndex(header []string, name string) (int, error) {
+	for i, h := range header {
+		if columnKey(h) == columnKey(name) {
+			return i, nil
+		}
+	}
+	have := make([]string, len(header))
+	for i, h := range header {
+		have[i] = strings.ToLower(strings.ReplaceAll(h, " ", "-"))
+	}
+	return 0, fmt.Errorf("no column %q (have %s)", name, strings.Join(have, ", "))
+}
+
+func columnKey(s string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(s))
+}
+
+// compareCells orders two cells the way a reader would: ages ("5m" before
+// "2d") and numbers sharing a unit ("9Gi" before "10Gi") by value, anything
+// else as text.
+func compareCells(a, b string) int {
+	if da, ok := ageValue(a); ok {
+		if db, ok := ageValue(b); ok {
+			return cmp.Compare(da, db)
+		}
+	}
+	na, ua, oka := leadingNumber(a)
+	nb, ub, okb := leadingNumber(b)
+	if oka && okb && ua == ub {
+		return cmp.Compare(na, nb)
+	}
+	return strings.Compare(a, b)
+}
+
+// ageValue parses an age cell as the api renders it: a count of s, m, h, or d.
+func ageValue(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 's':
+		return time.Duration(n) * time.Second, true
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// leadingNumber splits s into its leading decimal number and the unit after it.
+func leadingNumber(s string) (float64, string, bool) {
+	i := 0
+	if strings.HasPrefix(s, "-") {
+		i++
+	}
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return n, s[i:], true
+}
+
+// wideTable returns the -wide form of an api result: its Table() with extra
+// columns appended, so the plain columns stay a prefix. It is nil for results
+// without a wide form. The api types' Table() is fixed upstream; the extra
+// columns are read off the same items.
+func wideTable(v any) [][]string {
+	switch v := v.(type) {
+	case *api.DeploymentListResult:
+		return widen(v.Table(), len(v.Items), []string{"REVISION", "IMAGE", "REPLICAS", "CPU", "MEMORY", "URL"}, func(i int) []string {
+			x := v.Items[i]
+			image := x.Image
+			if image == "" {
+				image = x.Site
+			}
+			replicas := strconv.Itoa(x.MinReplicas)
+			if x.MaxReplicas > x.MinReplicas {
+				replicas += "-" + strconv.Itoa(x.MaxReplicas)
+			}
+			return []string{
+				strconv.FormatInt(x.Revision, 10),
+				image,
+				replicas,
+				resourcePair(x.Resources.Requests.CPU, x.Resources.Limits.CPU),
+				resourcePair(x.Resources.Requests.Memory, x.Resources.Limits.Memory),
+				x.URL,
+			}
+		})
+	case *api.DiskListResult:
+		return widen(v.Table(), len(v.Items), []string{"STATUS", "CREATED BY"}, func(i int) []string {
+			x := v.Items[i]
+			return []string{x.Status.Text(), x.CreatedBy}
+		})
+	}
+	return nil
+}
+
+// widen appends extra columns to a table holding a header and one row per item.
+func widen(t [][]string, items int, header []string, extra func(i int) []string) [][]string {
+	if len(t) != items+1 {
+		return nil
+	}
+	wt := make([][]string, len(t))
+	wt[0] = append(slices.Clip(t[0]), header...)
+	for i := range items {
+		wt[i+1] = append(slices.Clip(t[i+1]), extra(i)...)
+	}
+	return wt
+}
+
+// resourcePair renders a request and its limit as "request/limit", or just
+// the one that is set.
+func resourcePair(req, limit string) string {
+	switch {
+	case limit == "" || limit == req:
+		return req
+	case req == "":
+		return "/" + limit
+	}
+	return req + "/" + limit
+}
+
+// minColumnWidth is the narrowest a column is squeezed to (padding included)
+// when the table does not fit the terminal.
+const minColumnWidth = 10
+
+func (rn Runner) printTable(table [][]string) {
+	writeTable(rn.output(), table, rn.tableWidth(), nil)
+}
+
+// tableWidth is the width a table is fitted to: the terminal's, when the
+// output is one and -wide is off, else 0 (no limit). COLUMNS overrides the
+// detected width.
+func (rn Runner) tableWidth() int {
+	if rn.table.wide || !isTerminal(rn.output()) {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return terminalWidth(rn.output())
+}
+
+// writeTable writes table as space-padded columns, 3 spaces between, in a
+// single width pass. With a width, the widest columns are narrowed until a
+// line fits and their longer cells cut with "…". Rows marked in highlight are
+// drawn bold (for a terminal).
+func writeTable(w io.Writer, table [][]string, width int, highlight map[int]bool) {
+	var ll []int
+	for _, row := range table {
+		for i, c := range row {
+			if i == len(ll) {
+				ll = append(ll, 0)
+			}
+			ll[i] = max(ll[i], utf8.RuneCountInString(c)+3)
+		}
+	}
+	if width > 0 {
+		ll = fitColumns(ll, width)
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	for r, row := range table {
+		if highlight[r] {
+			bw.WriteString(highlightOn)
+		}
+		for i, c := range row {
+			n := utf8.RuneCountInString(c)
+			if n > ll[i]-3 {
+				c = truncateCell(c, ll[i]-3)
+				n = ll[i] - 3
+			}
+			bw.WriteString(c)
+			for range ll[i] - n {
+				bw.WriteByte(' ')
+			}
+		}
+		if highlight[r] {
+			bw.WriteString(highlightOff)
+		}
+		bw.WriteByte('\n')
+	}
+}
+
+// fitColumns caps the column widths so a line, less the last column's
+// padding, fits width: the largest cap that fits, but not below
+// minColumnWidth (a table that cannot fit then wraps).
+func fitColumns(ll []int, width int) []int {
+	total := func(limit int) int {
+		n := -3
+		for _, l := range ll {
+			n += min(l, limit)
+		}
+		return n
+	}
+	hi := slices.Max(ll)
+	if total(hi) <= width {
+		return ll
+	}
+	lo := minColumnWidth
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if total(mid) <= width {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	fit := make([]int, len(ll))
+	for i, l := range ll {
+		fit[i] = min(l, lo)
+	}
+	return fit
+}
+
+// truncateCell cuts s to n runes, the last one an ellipsis.
+func truncateCell(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
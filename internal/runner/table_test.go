@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deploys-app/api"
+)
+
+func testDeployments() *api.DeploymentListResult {
+	now := time.Now()
+	item := func(name string, rev int64, age time.Duration, cpu string) *api.DeploymentListItem {
+		x := &api.DeploymentListItem{Name: name, Revision: rev, Image: name + ":v1", MinReplicas: 1, MaxReplicas: 3, CreatedAt: now.Add(-age)}
+		x.Resources.Requests.CPU = cpu
+		return x
+	}
+	return &api.DeploymentListResult{Items: []*api.DeploymentListItem{
+		item("web", 12, 3*24*time.Hour, "100m"),
+		item("worker", 9, 2*time.Hour, "250m"),
+		item("api", 100, 20*time.Minute, "1"),
+	}}
+}
+
+func TestShapeTable(t *testing.T) {
+	res := testDeployments()
+	cases := []struct {
+		opts tableOptions
+		want [][]string
+	}{
+		{tableOptions{columns: "name,image"}, [][]string{{"NAME", "IMAGE"}, {"web", "web:v1"}, {"worker", "worker:v1"}, {"api", "api:v1"}}},
+		{tableOptions{columns: "NAME", sortBy: "age"}, [][]string{{"NAME"}, {"api"}, {"worker"}, {"web"}}},
+		{tableOptions{columns: "name", sortBy: "-revision"}, [][]string{{"NAME"}, {"api"}, {"web"}, {"worker"}}},
+		{tableOptions{columns: "name", sortBy: "name", noHeaders: true}, [][]string{{"api"}, {"web"}, {"worker"}}},
+		{tableOptions{columns: "replicas"}, [][]string{{"REPLICAS"}, {"1-3"}, {"1-3"}, {"1-3"}}},
+	}
+	for _, c := range cases {
+		got, err := (Runner{table: c.opts}).shapeTable(res, res)
+		if err != nil {
+			t.Errorf("%+v: %v", c.opts, err)
+			continue
+		}
+		if !slices.EqualFunc(got, c.want, slices.Equal) {
+			t.Errorf("%+v = %q; want %q", c.opts, got, c.want)
+		}
+	}
+
+	// the plain columns by default, the wide ones appended under -wide
+	plain, _ := (Runner{}).shapeTable(res, res)
+	if !slices.Equal(plain[0], res.Table()[0]) {
+		t.Errorf("default header = %q; want %q", plain[0], res.Table()[0])
+	}
+	wide, _ := (Runner{table: tableOptions{wide: true}}).shapeTable(res, res)
+	if !slices.Equal(wide[0][:len(plain[0])], plain[0]) || !slices.Contains(wide[0], "REVISION") {
+		t.Errorf("wide header = %q", wide[0])
+	}
+
+	for _, opts := range []tableOptions{{columns: "name,nope"}, {sortBy: "nope"}} {
+		_, err := (Runner{table: opts}).shapeTable(res, res)
+		if err == nil || !strings.Contains(err.Error(), "have name, type") {
+			t.Errorf("%+v: err = %v; want the available columns", opts, err)
+		}
+	}
+}
+
+func TestCompareCells(t *testing.T) {
+	sorted := []string{"30s", "5m", "2h", "1d", "10d"}
+	if !slices.IsSortedFunc(sorted, compareCells) {
+		t.Errorf("ages not in order: %q", sorted)
+	}
+	sorted = []string{"9Gi", "10Gi", "100Gi"}
+	if !slices.IsSortedFunc(sorted, compareCells) {
+		t.Errorf("sizes not in order: %q", sorted)
+	}
+	if compareCells("b", "a") <= 0 {
+		t.Error("text compares as text")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	table := [][]string{{"NAME", "URL"}, {"web", "https://web.example.com/some/long/path"}}
+	var b strings.Builder
+	writeTable(&b, table, 0, nil)
+	want := "NAME   URL                                      \n" +
+		"web    https://web.example.com/some/long/path   \n"
+	if b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+
+	b.Reset()
+	writeTable(&b, table, 24, nil)
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		if n := len([]rune(strings.TrimRight(line, " "))); n > 24 {
+			t.Errorf("line %q is %d wide; want at most 24", line, n)
+		}
+	}
+	if !strings.Contains(b.String(), "https://web.exam…") {
+		t.Errorf("long cell not cut with an ellipsis:\n%s", b.String())
+	}
+}
+
+func TestFitColumns(t *testing.T) {
+	if got := fitColumns([]int{7, 43}, 80); !slices.Equal(got, []int{7, 43}) {
+		t.Errorf("fitting table changed: %v", got)
+	}
+	if got := fitColumns([]int{7, 43, 30}, 50); !slices.Equal(got, []int{7, 23, 23}) {
+		t.Errorf("got %v; want the wide columns capped equally", got)
+	}
+	if got := fitColumns([]int{40, 40, 40}, 10); !slices.Equal(got, []int{minColumnWidth, minColumnWidth, minColumnWidth}) {
+		t.Errorf("got %v; want the minimum width", got)
+	}
+}
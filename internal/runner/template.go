@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateOutput reports whether mode is one of the expression output modes:
+// template=<go template> (or go-template=) and jsonpath=<expr>.
+func templateOutput(mode string) bool {
+	for _, p := range []string{"template=", "go-template=", "jsonpath="} {
+		if strings.HasPrefix(mode, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// printExpr renders v through a -output template=... or jsonpath=...
+// expression. Both see the value -ojson prints (fields by their json names,
+// e.g. {{.items}}), not the Go structs. Like kubectl, no newline is added; end
+// the expression with {{"\n"}} or {"\n"} for one.
+func (rn Runner) printExpr(v any) error {
+	data, err := jsonData(v)
+	if err != nil {
+		return err
+	}
+	kind, expr, _ := strings.Cut(rn.OutputMode, "=")
+	if kind == "jsonpath" {
+		p, err := parseJSONPath(expr)
+		if err != nil {
+			return err
+		}
+		return p.execute(rn.output(), data)
+	}
+	t, err := template.New("output").Parse(expr)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return t.Execute(rn.output(), data)
+}
+
+// jsonData converts v to the generic value its JSON encoding decodes to:
+// objects keyed by json name, with whole numbers kept as int64 so ids and
+// counts print exactly.
+func jsonData(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	return jsonNumbers(data), nil
+}
+
+func jsonNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			v[k] = jsonNumbers(x)
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = jsonNumbers(x)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package runner
+
+import "os"
+
+// terminalWidth is unknown here without a terminal dependency; tables fit
+// COLUMNS when it is set and are not truncated otherwise.
+func terminalWidth(*os.File) int {
+	return 0
+}
@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -10,32 +9,30 @@ import (
 )
 
 func (rn Runner) transform(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("transform")
-	}
+	return rn.runGroup("transform", args, rn.transformLeaf)
+}
 
+func (rn *Runner) transformLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.Transform()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("transform", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("transform", args[0])
+	zone := rn.transformZone()
+	switch sub {
 	case "get":
 		var req api.TransformGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.TransformList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "set":
 		// Set replaces the whole zone (all rules) all-or-nothing, so it takes a
 		// spec file rather than per-rule flags. The file is the yaml form of
@@ -46,46 +43,66 @@ func (rn Runner) transform(args ...string) error {
 			project     string
 			location    string
 			description string
+			keep        int
 		)
 		f.StringVar(&fn, "f", "", "spec file (yaml: description, transforms)")
 		f.StringVar(&project, "project", "", "project id")
 		f.StringVar(&location, "location", "", "location")
 		f.StringVar(&description, "description", "", "zone description")
-		f.Parse(args[1:])
+		f.IntVar(&keep, "keep", defaultSnapshotKeep, snapshotKeepUsage)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		if fn == "" {
-			return fmt.Errorf("spec file required (-f)")
+			if fn == "" {
+				return nil, fmt.Errorf("spec file required (-f)")
+			}
+			b, ferr := os.ReadFile(fn)
+			if ferr != nil {
+				return nil, ferr
+			}
+			// non-strict so the yaml output of `transform get` (which carries extra
+			// read-only fields) can be edited and fed back in
+			var req api.TransformSet
+			ferr = yaml.Unmarshal(b, &req)
+			if ferr != nil {
+				return nil, fmt.Errorf("parse %s: %w", fn, ferr)
+			}
+			f.override("project", &req.Project, project)
+			f.override("location", &req.Location, location)
+			if description != "" {
+				req.Description = description
+			}
+			resp, err = zone.apply(rn, "set", req.Project, req.Location, keep, &req, func() (any, error) {
+				return s.Set(rn.ctx(), &req)
+			})
+			return resp, err
 		}
-		b, ferr := os.ReadFile(fn)
-		if ferr != nil {
-			return ferr
-		}
-		// non-strict so the yaml output of `transform get` (which carries extra
-		// read-only fields) can be edited and fed back in
+	case "history":
+		return zone.history(f)
+	case "restore":
 		var req api.TransformSet
-		ferr = yaml.Unmarshal(b, &req)
-		if ferr != nil {
-			return fmt.Errorf("parse %s: %w", fn, ferr)
-		}
-		if project != "" {
-			req.Project = project
-		}
-		if location != "" {
-			req.Location = location
-		}
-		if description != "" {
-			req.Description = description
-		}
-		resp, err = s.Set(context.Background(), &req)
+		return zone.restore(rn, f, &req, &req.Project, &req.Location, func() (any, error) {
+			return s.Set(rn.ctx(), &req)
+		})
 	case "delete":
 		var req api.TransformDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("transform delete", journalTarget{Project: req.Project, Location: req.Location}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
+}
+
+// transformZone snapshots transform zones through their get.
+func (rn Runner) transformZone() zoneSnapshots {
+	return zoneSnapshots{kind: "transform", notFound: api.ErrTransformZoneNotFound, get: func(project, location string) (any, error) {
+		return rn.API.Transform().Get(rn.ctx(), &api.TransformGet{Project: project, Location: location})
+	}}
 }
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"golang.org/x/mod/semver"
+
+	"github.com/deploys-app/deploys/internal/wire"
 )
 
 // githubLatestURL returns the latest *stable* release of the cli. The rolling
@@ -26,7 +28,7 @@ var githubLatestURL = "https://api.github.com/repos/deploys-app/deploys/releases
 // credentials) for it.
 func IsLocalCommand(name string) bool {
 	switch name {
-	case "check-update", "version":
+	case "check-update", "version", "history", "config", "context", "completion", "docs", "dev-server":
 		return true
 	}
 	return false
@@ -58,25 +60,31 @@ func (v versionInfo) Table() [][]string {
 // version prints this binary's version. The default view is the bare version
 // string; -ojson/-oyaml wrap it as a {version: ...} object for scripting.
 func (rn Runner) version(args ...string) error {
-	if len(args) > 0 && IsHelpArg(args[0]) {
-		writeVersionUsage(rn.output())
-		return nil
-	}
+	_, run := rn.versionLeaf()
+	return run(args)
+}
 
+func (rn *Runner) versionLeaf() (*flag.FlagSet, func(args []string) error) {
 	f := flag.NewFlagSet("deploys version", flag.ExitOnError)
 	f.SetOutput(rn.output())
 	rn.registerFlags(f)
 	f.Usage = func() { writeVersionUsage(rn.output()) }
-	if err := f.Parse(args); err != nil {
-		return err
-	}
-
-	v := displayVersion(rn.Version)
-	if rn.OutputMode == "" || rn.OutputMode == "table" {
-		fmt.Fprintln(rn.output(), v)
-		return nil
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeVersionUsage(rn.output())
+			return nil
+		}
+		if err := f.Parse(args); err != nil {
+			return err
+		}
+
+		v := displayVersion(rn.Version)
+		if rn.OutputMode == "" || rn.OutputMode == "table" {
+			fmt.Fprintln(rn.output(), v)
+			return nil
+		}
+		return rn.print(versionInfo{Version: v})
 	}
-	return rn.print(versionInfo{Version: v})
 }
 
 func writeVersionUsage(w io.Writer) {
@@ -101,42 +109,48 @@ func (u updateCheck) Table() [][]string {
 // checkUpdate compares this binary's version against the latest stable release
 // and reports whether a newer one is available.
 func (rn Runner) checkUpdate(args ...string) error {
-	if len(args) > 0 && IsHelpArg(args[0]) {
-		writeCheckUpdateUsage(rn.output())
-		return nil
-	}
+	_, run := rn.checkUpdateLeaf()
+	return run(args)
+}
 
+func (rn *Runner) checkUpdateLeaf() (*flag.FlagSet, func(args []string) error) {
 	f := flag.NewFlagSet("deploys check-update", flag.ExitOnError)
 	f.SetOutput(rn.output())
 	rn.registerFlags(f)
 	f.Usage = func() { writeCheckUpdateUsage(rn.output()) }
-	if err := f.Parse(args); err != nil {
-		return err
-	}
-
-	latest, err := fetchLatestVersion(context.Background(), githubLatestURL)
-	if err != nil {
-		return fmt.Errorf("check-update: %w", err)
-	}
-
-	res := updateCheck{
-		Current:         displayVersion(rn.Version),
-		Latest:          displayVersion(latest),
-		UpdateAvailable: updateAvailable(rn.Version, latest),
-	}
-	if err := rn.print(res); err != nil {
-		return err
-	}
-
-	// In the default human view, follow up with how to upgrade.
-	if res.UpdateAvailable && (rn.OutputMode == "" || rn.OutputMode == "table") {
-		fmt.Fprintf(rn.output(),
-			"\nA newer release (%s) is available. Upgrade with:\n"+
-				"  go install github.com/deploys-app/deploys@latest\n"+
-				"or download it from https://github.com/deploys-app/deploys/releases\n",
-			res.Latest)
+	return f, func(args []string) error {
+		if len(args) > 0 && IsHelpArg(args[0]) {
+			writeCheckUpdateUsage(rn.output())
+			return nil
+		}
+		if err := f.Parse(args); err != nil {
+			return err
+		}
+
+		latest, err := fetchLatestVersion(rn.ctx(), githubLatestURL)
+		if err != nil {
+			return fmt.Errorf("check-update: %w", err)
+		}
+
+		res := updateCheck{
+			Current:         displayVersion(rn.Version),
+			Latest:          displayVersion(latest),
+			UpdateAvailable: updateAvailable(rn.Version, latest),
+		}
+		if err := rn.print(res); err != nil {
+			return err
+		}
+
+		// In the default human view, follow up with how to upgrade.
+		if res.UpdateAvailable && (rn.OutputMode == "" || rn.OutputMode == "table") {
+			fmt.Fprintf(rn.output(),
+				"\nA newer release (%s) is available. Upgrade with:\n"+
+					"  go install github.com/deploys-app/deploys@latest\n"+
+					"or download it from https://github.com/deploys-app/deploys/releases\n",
+				res.Latest)
+		}
+		return nil
 	}
-	return nil
 }
 
 // fetchLatestVersion reads the latest release tag from the GitHub releases API.
@@ -152,7 +166,7 @@ func fetchLatestVersion(ctx context.Context, url string) (string, error) {
 	// GitHub rejects API requests without a User-Agent.
 	req.Header.Set("User-Agent", "deploys-cli")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := (&http.Client{Transport: wire.Transport(nil)}).Do(req)
 	if err != nil {
 		return "", err
 	}
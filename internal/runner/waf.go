@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -10,32 +9,30 @@ import (
 )
 
 func (rn Runner) waf(args ...string) error {
-	if len(args) == 0 || IsHelpArg(args[0]) {
-		return rn.groupUsage("waf")
-	}
+	return rn.runGroup("waf", args, rn.wafLeaf)
+}
 
+func (rn *Runner) wafLeaf(f *leafFlagSet, sub string) leafRun {
 	s := rn.API.WAF()
-
-	var (
-		resp any
-		err  error
-	)
-
-	f := rn.subFlagSet("waf", args[0])
-	switch args[0] {
-	default:
-		return rn.unknownSub("waf", args[0])
+	zone := rn.wafZone()
+	switch sub {
 	case "get":
 		var req api.WAFGet
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Get(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.Get(rn.ctx(), &req)
+		}
 	case "list":
 		var req api.WAFList
 		f.StringVar(&req.Project, "project", "", "project id")
-		f.Parse(args[1:])
-		resp, err = s.List(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (any, error) {
+			f.Parse(args)
+			return s.List(rn.ctx(), &req)
+		}
 	case "set":
 		// Set replaces the whole zone (rules and limits) all-or-nothing, so it
 		// takes a spec file rather than per-rule flags. The file is the yaml
@@ -46,68 +43,115 @@ func (rn Runner) waf(args ...string) error {
 			project     string
 			location    string
 			description string
+			keep        int
 		)
 		f.StringVar(&fn, "f", "", "spec file (yaml: description, rules, limits)")
 		f.StringVar(&project, "project", "", "project id")
 		f.StringVar(&location, "location", "", "location")
 		f.StringVar(&description, "description", "", "zone description")
-		f.Parse(args[1:])
+		f.IntVar(&keep, "keep", defaultSnapshotKeep, snapshotKeepUsage)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
 
-		if fn == "" {
-			return fmt.Errorf("spec file required (-f)")
-		}
-		b, ferr := os.ReadFile(fn)
-		if ferr != nil {
-			return ferr
+			if fn == "" {
+				return nil, fmt.Errorf("spec file required (-f)")
+			}
+			b, ferr := os.ReadFile(fn)
+			if ferr != nil {
+				return nil, ferr
+			}
+			// non-strict so the yaml output of `waf get` (which carries extra
+			// read-only fields) can be edited and fed back in
+			var req api.WAFSet
+			ferr = yaml.Unmarshal(b, &req)
+			if ferr != nil {
+				return nil, fmt.Errorf("parse %s: %w", fn, ferr)
+			}
+			f.override("project", &req.Project, project)
+			f.override("location", &req.Location, location)
+			if description != "" {
+				req.Description = description
+			}
+			resp, err = zone.apply(rn, "set", req.Project, req.Location, keep, &req, func() (any, error) {
+				return s.Set(rn.ctx(), &req)
+			})
+			return resp, err
 		}
-		// non-strict so the yaml output of `waf get` (which carries extra
-		// read-only fields) can be edited and fed back in
+	case "init":
+		// init writes a spec file for `set`; it makes no change to the zone.
+		return printed(rn.wafInit(s, f))
+	case "history":
+		return zone.history(f)
+	case "restore":
 		var req api.WAFSet
-		ferr = yaml.Unmarshal(b, &req)
-		if ferr != nil {
-			return fmt.Errorf("parse %s: %w", fn, ferr)
-		}
-		if project != "" {
-			req.Project = project
-		}
-		if location != "" {
-			req.Location = location
-		}
-		if description != "" {
-			req.Description = description
-		}
-		resp, err = s.Set(context.Background(), &req)
+		return zone.restore(rn, f, &req, &req.Project, &req.Location, func() (any, error) {
+			return s.Set(rn.ctx(), &req)
+		})
 	case "delete":
 		var req api.WAFDelete
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
-		f.Parse(args[1:])
-		resp, err = s.Delete(context.Background(), &req)
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			o := rn.journalBegin("waf delete", journalTarget{Project: req.Project, Location: req.Location}, &req)
+			resp, err = s.Delete(rn.ctx(), &req)
+			o.done(err)
+			return resp, err
+		}
 	case "metrics":
 		var (
 			req       api.WAFMetrics
 			timeRange string
+			report    bool
 		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&timeRange, "time-range", "1h", "time range (1h, 6h, 12h, 1d, 7d, 30d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.WAFMetricsTimeRange(timeRange)
-		resp, err = s.Metrics(context.Background(), &req)
+		f.BoolVar(&report, "report", false, "summarize blocked vs allowed and top rules instead of raw series")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.TimeRange = api.WAFMetricsTimeRange(timeRange)
+			var res *api.WAFMetricsResult
+			res, err = s.Metrics(rn.ctx(), &req)
+			resp = res
+			if err == nil && report {
+				resp = newWAFReport(req.TimeRange, res)
+			}
+			return resp, err
+		}
 	case "limitmetrics":
 		var (
 			req       api.WAFLimitMetrics
 			timeRange string
+			report    bool
+			threshold float64
 		)
 		f.StringVar(&req.Project, "project", "", "project id")
 		f.StringVar(&req.Location, "location", "", "location")
 		f.StringVar(&timeRange, "time-range", "1h", "time range (1h, 6h, 12h, 1d, 7d, 30d)")
-		f.Parse(args[1:])
-		req.TimeRange = api.WAFMetricsTimeRange(timeRange)
-		resp, err = s.LimitMetrics(context.Background(), &req)
-	}
-	if err != nil {
-		return err
+		f.BoolVar(&report, "report", false, "summarize limited ratios per limit instead of raw series")
+		f.Float64Var(&threshold, "threshold", 0.05, "limited ratio (0-1) at which -report flags a limit as frequently hit")
+		f.Request(&req)
+		return func(args []string) (resp any, err error) {
+			f.Parse(args)
+			req.TimeRange = api.WAFMetricsTimeRange(timeRange)
+			var res *api.WAFLimitMetricsResult
+			res, err = s.LimitMetrics(rn.ctx(), &req)
+			resp = res
+			if err == nil && report {
+				resp = newWAFLimitReport(req.TimeRange, threshold, res)
+			}
+			return resp, err
+		}
 	}
-	return rn.print(resp)
+	return nil
+}
+
+// wafZone snapshots waf zones through their get.
+func (rn Runner) wafZone() zoneSnapshots {
+	return zoneSnapshots{kind: "waf", notFound: api.ErrWAFZoneNotFound, get: func(project, location string) (any, error) {
+		return rn.API.WAF().Get(rn.ctx(), &api.WAFGet{Project: project, Location: location})
+	}}
 }
@@ -0,0 +1,293 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/moonrhythm/toon"
+	"gopkg.in/yaml.v2"
+)
+
+// watchSubs are the read-only leaves of the api groups that take -watch.
+var watchSubs = map[string]bool{"get": true, "list": true, "status": true, "metrics": true}
+
+// watchable reports whether the canonical "<group> <sub>" command takes
+// -watch: a get, list, status, or metrics leaf of a group that calls the api.
+func watchable(command string) bool {
+	group, sub, _ := strings.Cut(command, " ")
+	return watchSubs[sub] && !IsLocalCommand(group) && !IsAuthCommand(group)
+}
+
+// watchState carries -watch through a command: the leaf's flags turn it on,
+// and while it is on print hands the result here instead of rendering it, so
+// Run can re-issue the command and render what changed (see runWatch).
+type watchState struct {
+	enabled  bool
+	interval time.Duration
+	v        any
+	captured bool
+	// rn is the runner that printed v: the leaf's copy, holding the -output
+	// and table flags it parsed.
+	rn Runner
+}
+
+func (rn Runner) watching() bool {
+	return rn.watch != nil && rn.watch.enabled
+}
+
+// registerWatchFlags adds -watch and -interval to a watchable leaf.
+func (rn *Runner) registerWatchFlags(f *leafFlagSet) {
+	if !watchable(f.command) {
+		return
+	}
+	ws := rn.watch
+	if ws == nil {
+		ws = &watchState{} // a leaf run outside Run: -watch is accepted but inert
+	}
+	f.BoolVar(&ws.enabled, "watch", false, "re-issue the request every -interval and show what changed")
+	f.DurationVar(&ws.interval, "interval", 2*time.Second, "time between -watch refreshes")
+}
+
+// watchContext ends a watch; a var so tests can stop one.
+var watchContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// runWatch runs the command once; when its flags asked for -watch, it keeps
+// re-running it every -interval until interrupted and renders each refresh.
+// A failed refresh is reported and the watch goes on.
+func (rn Runner) runWatch(args []string) error {
+	ws := &watchState{}
+	rn.watch = ws
+	if err := rn.Run(args...); err != nil || !ws.enabled {
+		return err
+	}
+	if !ws.captured {
+		return fmt.Errorf("-watch: deploys %s has no result to watch", strings.Join(args[:min(2, len(args))], " "))
+	}
+	if ws.interval <= 0 {
+		return fmt.Errorf("-watch: -interval must be positive")
+	}
+
+	ctx, cancel := watchContext()
+	defer cancel()
+
+	w := &watchRenderer{rn: ws.rn, title: "deploys " + strings.Join(args, " "), interval: ws.interval}
+	w.rn.watch = nil
+	w.tty = isTerminal(w.rn.output())
+	if err := w.render(ws.v); err != nil {
+		return err
+	}
+	if rn.scope != nil {
+		// the scope notes were shown on the first run
+		q := *rn.scope
+		q.quiet = true
+		rn.scope = &q
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(ws.interval):
+		}
+		ws.v, ws.captured = nil, false
+		if err := rn.Run(args...); err != nil {
+			fmt.Fprintf(os.Stderr, "watch: %v\n", err)
+			continue
+		}
+		if err := w.render(ws.v); err != nil {
+			return err
+		}
+	}
+}
+
+// watchRenderer draws each refresh of a watch. On a terminal it redraws the
+// screen, highlighting table rows that changed since the last refresh. Piped,
+// it writes only what changed: new or changed table rows, or records (a list's
+// items, else the whole result) as NDJSON under -ojson and as one document
+// each under yaml and toon. Other modes print the whole result again when it
+// changed.
+type watchRenderer struct {
+	rn       Runner // renders; its watch is off
+	tty      bool
+	title    string
+	interval time.Duration
+	seen     map[string]bool // rows or records of the last refresh
+	last     string          // the whole last result, for the other modes
+	yaml     *yaml.Encoder   // one stream, so documents are separated
+	n        int             // refreshes rendered
+}
+
+func (w *watchRenderer) render(v any) error {
+	defer func() { w.n++ }()
+	if w.tty {
+		return w.redraw(v)
+	}
+	switch w.rn.OutputMode {
+	case "", "table", "csv", "tsv":
+		if tp, ok := v.(tablePrinter); ok {
+			return w.writeRows(v, tp)
+		}
+	case "json", "yaml", "toon":
+		return w.writeRecords(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if string(b) == w.last {
+		return nil
+	}
+	w.last = string(b)
+	return w.rn.print(v)
+}
+
+const (
+	clearScreen   = "\x1b[H\x1b[2J"
+	highlightOn   = "\x1b[1m"
+	highlightOff  = "\x1b[0m"
+	watchTimeForm = "15:04:05"
+)
+
+func (w *watchRenderer) redraw(v any) error {
+	out := w.rn.output()
+	fmt.Fprintf(out, "%sEvery %s: %s   %s\n\n", clearScreen, w.interval, w.title, time.Now().Format(watchTimeForm))
+	tp, ok := v.(tablePrinter)
+	if !ok || (w.rn.OutputMode != "" && w.rn.OutputMode != "table") {
+		return w.rn.print(v)
+	}
+	t, changed, err := w.changedRows(v, tp)
+	if err != nil || len(t) == 0 {
+		return err
+	}
+	first := 1 // the row index of the first item
+	if w.rn.table.noHeaders {
+		t, first = t[1:], 0
+	}
+	highlight := map[int]bool{}
+	for i, c := range changed {
+		highlight[first+i] = c && w.n > 0
+	}
+	writeTable(out, t, w.rn.tableWidth(), highlight)
+	return nil
+}
+
+// writeRows writes the table rows that are new since the last refresh, with
+// the header the first time.
+func (w *watchRenderer) writeRows(v any, tp tablePrinter) error {
+	t, changed, err := w.changedRows(v, tp)
+	if err != nil || len(t) == 0 {
+		return err
+	}
+	var batch [][]string
+	if w.n == 0 && !w.rn.table.noHeaders {
+		batch = append(batch, t[0])
+	}
+	for i, row := range t[1:] {
+		if changed[i] {
+			batch = append(batch, row)
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	out := w.rn.output()
+	switch w.rn.OutputMode {
+	case "csv":
+		return writeCSV(out, batch)
+	case "tsv":
+		return writeTSV(out, batch)
+	}
+	writeTable(out, batch, 0, nil)
+	return nil
+}
+
+// changedRows shapes v's table, header included, and marks the rows not seen
+// in the last refresh. An AGE column ticks on its own, so it does not count
+// as a change.
+func (w *watchRenderer) changedRows(v any, tp tablePrinter) ([][]string, []bool, error) {
+	rn := w.rn
+	rn.table.noHeaders = false
+	t, err := rn.shapeTable(v, tp)
+	if err != nil || len(t) == 0 {
+		return t, nil, err
+	}
+	seen := map[string]bool{}
+	changed := make([]bool, len(t)-1)
+	for i, row := range t[1:] {
+		var key strings.Builder
+		for j, c := range row {
+			if cell(t[0], j) != "AGE" {
+				key.WriteString(c)
+				key.WriteByte(0)
+			}
+		}
+		k := key.String()
+		seen[k] = true
+		changed[i] = !w.seen[k]
+	}
+	w.seen = seen
+	return t, changed, nil
+}
+
+// writeRecords writes the records that are new since the last refresh.
+func (w *watchRenderer) writeRecords(v any) error {
+	records := watchRecords(v)
+	out := w.rn.output()
+	seen := map[string]bool{}
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		k := string(b) // also the NDJSON line, in the -ojson field order
+		seen[k] = true
+		if w.seen[k] {
+			continue
+		}
+		switch w.rn.OutputMode {
+		case "json":
+			_, err = fmt.Fprintln(out, k)
+		case "yaml":
+			if w.yaml == nil {
+				w.yaml = yaml.NewEncoder(out)
+			}
+			err = w.yaml.Encode(r)
+		case "toon":
+			if b, err = toon.Marshal(r); err == nil {
+				_, err = fmt.Fprintln(out, string(b))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.seen = seen
+	return nil
+}
+
+// watchRecords splits a result into the records a piped watch compares: the
+// elements of its "items" list, else the result itself.
+func watchRecords(v any) []any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return []any{v}
+	}
+	for i := range rv.NumField() {
+		name, _, _ := strings.Cut(rv.Type().Field(i).Tag.Get("json"), ",")
+		if f := rv.Field(i); name == "items" && f.Kind() == reflect.Slice {
+			records := make([]any, f.Len())
+			for j := range records {
+				records[j] = f.Index(j).Interface()
+			}
+			return records
+		}
+	}
+	return []any{v}
+}
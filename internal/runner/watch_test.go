@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/deploys-app/api"
+)
+
+// watchAPI serves a deployment list whose web revision goes up on the second
+// call, and ends the watch after the third.
+type watchAPI struct {
+	api.Interface
+	calls  int
+	cancel context.CancelFunc
+}
+
+func (a *watchAPI) Deployment() api.Deployment { return watchDeployment{a: a} }
+
+type watchDeployment struct {
+	api.Deployment
+	a *watchAPI
+}
+
+func (s watchDeployment) List(context.Context, *api.DeploymentList) (*api.DeploymentListResult, error) {
+	s.a.calls++
+	rev := int64(1)
+	if s.a.calls > 1 {
+		rev = 2
+	}
+	if s.a.calls == 3 {
+		s.a.cancel()
+	}
+	return &api.DeploymentListResult{Items: []*api.DeploymentListItem{
+		{Name: "web", Revision: rev},
+		{Name: "worker", Revision: 1},
+	}}, nil
+}
+
+func runWatched(t *testing.T, args ...string) string {
+	t.Helper()
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+	t.Chdir(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orig := watchContext
+	watchContext = func() (context.Context, context.CancelFunc) { return ctx, cancel }
+	defer func() { watchContext = orig }()
+
+	a := &watchAPI{cancel: cancel}
+	out := tempOut(t)
+	args = append([]string{"deployment", "list", "-project", "acme", "-location", "l", "-watch", "-interval", "1ms"}, args...)
+	if err := (Runner{API: a, Output: out}).Run(args...); err != nil {
+		t.Fatal(err)
+	}
+	if a.calls < 3 {
+		t.Fatalf("api called %d times, want 3", a.calls)
+	}
+	return readOut(t, out)
+}
+
+// Piped, a watch writes only the records that changed since the last refresh.
+func TestWatchPipedJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(runWatched(t, "-ojson")), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3 (two items, then the changed one):\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, want := range []string{`"name":"web","type":`, `"name":"worker"`, `"revision":2`} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d = %s; want it to contain %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestWatchPipedTable(t *testing.T) {
+	got := runWatched(t, "-columns", "name,revision")
+	want := "NAME     REVISION   \n" +
+		"web      1          \n" +
+		"worker   1          \n" +
+		"web   2   \n"
+	if got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestWatchFlags(t *testing.T) {
+	for _, c := range [][2]string{{"deployment", "list"}, {"deployment", "get"}, {"deployment", "status"}, {"disk", "metrics"}} {
+		if f := leafFlags(c[0], c[1]); f == nil || f.Lookup("watch") == nil || f.Lookup("interval") == nil {
+			t.Errorf("deploys %s %s: no -watch/-interval", c[0], c[1])
+		}
+	}
+	for _, c := range [][2]string{{"deployment", "delete"}, {"config", "list"}, {"auth", "list"}} {
+		if f := leafFlags(c[0], c[1]); f != nil && f.Lookup("watch") != nil {
+			t.Errorf("deploys %s %s: has -watch", c[0], c[1])
+		}
+	}
+}
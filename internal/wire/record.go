@@ -0,0 +1,259 @@
+// Package wire records, replays, and traces the cli's HTTP traffic.
+//
+// A Recorder saves each request/response pair it carries as a numbered JSON
+// file in a directory, with credential headers and secret values redacted. A
+// Replayer answers requests from such a directory without the network, in
+// the order they were recorded. Together they make a session reproducible:
+// attach the directory to a bug report, or replay it as a golden test. A
+// Tracer logs each call as it happens, redacted the same way.
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Exchange is one recorded request and its response.
+type Exchange struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Body holds a JSON body, Text any other.
+type Request struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// Response is a recorded response. Body holds a JSON body, Text any other.
+type Response struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// setBody stores b redacted: as JSON when it is, else as text.
+func setBody(body *json.RawMessage, text *string, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if json.Valid(b) {
+		*body = RedactJSON(b)
+		return
+	}
+	*text = string(b)
+}
+
+func getBody(body json.RawMessage, text string) []byte {
+	if len(body) > 0 {
+		return body
+	}
+	return []byte(text)
+}
+
+// Recorder is an http.RoundTripper that saves every exchange it carries
+// into Dir as NNNN.json, continuing after the files already there so several
+// commands can record one session.
+type Recorder struct {
+	Dir       string
+	Transport http.RoundTripper // nil means http.DefaultTransport
+
+	mu sync.Mutex
+}
+
+func (rec *Recorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if r.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	t := rec.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	x := Exchange{
+		Request:  Request{Method: r.Method, URL: r.URL.String(), Header: RedactHeader(r.Header)},
+		Response: Response{Status: resp.StatusCode, Header: RedactHeader(resp.Header)},
+	}
+	setBody(&x.Request.Body, &x.Request.Text, reqBody)
+	setBody(&x.Response.Body, &x.Response.Text, respBody)
+	if err := rec.save(&x); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return resp, nil
+}
+
+// save writes x as the next numbered file in the directory.
+func (rec *Recorder) save(x *Exchange) error {
+	b, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err := os.MkdirAll(rec.Dir, 0700); err != nil {
+		return err
+	}
+	names, err := exchangeFiles(rec.Dir)
+	if err != nil {
+		return err
+	}
+	n := len(names) + 1
+	for {
+		// O_EXCL keeps another recording process from being overwritten
+		f, err := os.OpenFile(filepath.Join(rec.Dir, fmt.Sprintf("%04d.json", n)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			n++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(b)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+// exchangeFiles lists dir's NNNN.json files in order.
+func exchangeFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		name string
+		n    int
+	}
+	var files []file
+	for _, e := range entries {
+		s, ok := strings.CutSuffix(e.Name(), ".json")
+		if n, err := strconv.Atoi(s); ok && err == nil && !e.IsDir() {
+			files = append(files, file{e.Name(), n})
+		}
+	}
+	slices.SortFunc(files, func(a, b file) int { return a.n - b.n })
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	return names, nil
+}
+
+// LoadExchanges reads the exchanges recorded in dir, in order.
+func LoadExchanges(dir string) ([]*Exchange, error) {
+	names, err := exchangeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var xs []*Exchange
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var x Exchange
+		if err := json.Unmarshal(b, &x); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		xs = append(xs, &x)
+	}
+	return xs, nil
+}
+
+// Replayer is an http.RoundTripper that answers from the exchanges recorded
+// in Dir, never touching the network. Each request gets the next recorded
+// exchange with the same method and path (query included), so the calls a
+// command makes come back in the order they were recorded. A request with no
+// such exchange left fails.
+type Replayer struct {
+	Dir string
+
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	xs   []*Exchange
+	next int
+}
+
+func (rep *Replayer) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	rep.once.Do(func() { rep.xs, rep.err = LoadExchanges(rep.Dir) })
+	if rep.err != nil {
+		return nil, fmt.Errorf("replay: %w", rep.err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for i := rep.next; i < len(rep.xs); i++ {
+		x := rep.xs[i]
+		if !matches(x, r) {
+			continue
+		}
+		rep.next = i + 1
+		// the stored body is re-encoded, so the recorded length no longer holds
+		h := x.Response.Header.Clone()
+		h.Del("Content-Length")
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", x.Response.Status, http.StatusText(x.Response.Status)),
+			StatusCode:    x.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        h,
+			Body:          io.NopCloser(bytes.NewReader(getBody(x.Response.Body, x.Response.Text))),
+			ContentLength: -1,
+			Request:       r,
+		}, nil
+	}
+	return nil, fmt.Errorf("replay: no recorded %s %s left in %s", r.Method, r.URL.RequestURI(), rep.Dir)
+}
+
+// matches reports whether x recorded r: the same method and path. The host is
+// not compared, so a session replays under any endpoint.
+func matches(x *Exchange, r *http.Request) bool {
+	if x.Request.Method != r.Method {
+		return false
+	}
+	u, err := r.URL.Parse(x.Request.URL)
+	return err == nil && u.RequestURI() == r.URL.RequestURI()
+}
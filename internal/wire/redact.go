@@ -0,0 +1,102 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces every secret value the package writes out.
+const Redacted = "REDACTED"
+
+// secretHeaders are the headers whose values are always redacted.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// secretKeys are the JSON field names, lowercased with "_" and "-" dropped,
+// whose string values are redacted wherever they appear: pull-secret and
+// basic-auth passwords, webhook and scheduler secrets, service-account keys,
+// api and oauth tokens, and sidecar credentials.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"idtoken":       true,
+	"clientsecret":  true,
+	"codeverifier":  true,
+	"credentials":   true,
+	"authorization": true,
+}
+
+// envKeys are the JSON fields holding env maps: their keys are kept and every
+// value is redacted.
+var envKeys = map[string]bool{
+	"env":    true,
+	"addenv": true,
+}
+
+func normalizeKey(k string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(k))
+}
+
+// RedactHeader returns a copy of h with the credential headers' values
+// replaced.
+func RedactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range secretHeaders {
+		if vs := h.Values(k); len(vs) > 0 {
+			h[http.CanonicalHeaderKey(k)] = []string{Redacted}
+		}
+	}
+	return h
+}
+
+// RedactJSON returns body with the secrets in it replaced, or body unchanged
+// when it is not JSON.
+func RedactJSON(body []byte) []byte {
+	var v any
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil || d.More() {
+		return body
+	}
+	b, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		// a pull secret carries its encoded registry credentials in value
+		_, pullSecret := v["spec"]
+		for k, x := range v {
+			nk := normalizeKey(k)
+			switch {
+			case secretKeys[nk] || (pullSecret && nk == "value"):
+				if s, ok := x.(string); ok && s != "" {
+					v[k] = Redacted
+				} else {
+					v[k] = redactValue(x)
+				}
+			case envKeys[nk]:
+				if env, ok := x.(map[string]any); ok {
+					for ek := range env {
+						env[ek] = Redacted
+					}
+				}
+			default:
+				v[k] = redactValue(x)
+			}
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = redactValue(x)
+		}
+	}
+	return v
+}
@@ -0,0 +1,219 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retrier is an http.RoundTripper that gives each attempt Timeout and retries
+// a failed call up to Retries times, waiting Backoff, then twice that, and so
+// on up to MaxBackoff (each wait jittered). It retries:
+//
+//   - a 429, after the Retry-After the server asked for when it gave one;
+//   - a connection that could not be made, since nothing was sent;
+//   - for an idempotent request, a 5xx or a connection lost midway.
+//
+// With a Timeout, each call also carries a deadline of its Budget (or its
+// context's, if sooner). A call never waits past that deadline: a retry that
+// would, a long Retry-After among them, is not made, and the last response or
+// error is returned instead.
+type Retrier struct {
+	Timeout    time.Duration // per attempt; 0 means none
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Idempotent reports whether r may be sent again after it could have
+	// taken effect. Nil means the http methods that are: GET, HEAD, OPTIONS,
+	// TRACE, PUT, and DELETE.
+	Idempotent func(r *http.Request) bool
+	Transport  http.RoundTripper // nil means http.DefaultTransport
+}
+
+// Budget is the longest a call can take through r: every attempt's timeout
+// plus the backoff between them, or 0 (unbounded) without a Timeout.
+func (r *Retrier) Budget() time.Duration {
+	if r.Timeout <= 0 {
+		return 0
+	}
+	d := r.Timeout * time.Duration(r.Retries+1)
+	for i := range r.Retries {
+		d += r.backoff(i)
+	}
+	return d
+}
+
+// backoff is the longest wait before retry i (0 for the first).
+func (r *Retrier) backoff(i int) time.Duration {
+	d := r.Backoff
+	for range i {
+		d *= 2
+		if r.MaxBackoff > 0 && d >= r.MaxBackoff {
+			break
+		}
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+func (r *Retrier) idempotent(req *http.Request) bool {
+	if r.Idempotent != nil {
+		return r.Idempotent(req)
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (r *Retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	ctx, done := req.Context(), context.CancelFunc(func() {})
+	if b := r.Budget(); b > 0 {
+		ctx, done = context.WithTimeout(ctx, b)
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// keep the body to send it again
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			done()
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	}
+
+	for i := 0; ; i++ {
+		attempt := req.WithContext(ctx)
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				done()
+				return nil, err
+			}
+			attempt = req.Clone(ctx)
+			attempt.Body = body
+		}
+		release := func() {}
+		if r.Timeout > 0 {
+			actx, c := context.WithTimeout(ctx, r.Timeout)
+			attempt, release = attempt.WithContext(actx), c
+		}
+		cancel := func() { release(); done() }
+
+		resp, err := t.RoundTrip(attempt)
+		wait, retry := r.retry(req, i, resp, err)
+		if retry && ctx.Err() == nil {
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > wait {
+				if resp != nil {
+					io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+					resp.Body.Close()
+				}
+				release()
+				if err := sleep(ctx, wait); err != nil {
+					done()
+					return nil, err
+				}
+				continue
+			}
+		}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// the call's and the attempt's deadlines cover reading the body too
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+}
+
+// retry reports whether attempt i, which ended in resp or err, is retried,
+// and how long to wait first.
+func (r *Retrier) retry(req *http.Request, i int, resp *http.Response, err error) (time.Duration, bool) {
+	if i >= r.Retries {
+		return 0, false
+	}
+	wait := jitter(r.backoff(i))
+	switch {
+	case err != nil:
+		if req.Context().Err() != nil {
+			return 0, false
+		}
+		var op *net.OpError
+		if errors.As(err, &op) && op.Op == "dial" {
+			return wait, true
+		}
+		var ne net.Error
+		transient := errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded) ||
+			errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		return wait, transient && r.idempotent(req)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			wait = d
+		}
+		return wait, true
+	case resp.StatusCode >= 500:
+		return wait, r.idempotent(req)
+	}
+	return 0, false
+}
+
+// retryAfter parses a Retry-After header: seconds, or an http date.
+func retryAfter(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// jitter spreads d over [d/2, d) so clients that failed together do not retry
+// together.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// cancelBody releases an attempt's context once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
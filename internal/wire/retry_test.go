@@ -0,0 +1,187 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport counts the attempts a Retrier makes.
+type countingTransport struct{ n atomic.Int32 }
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRetrier(t *testing.T) {
+	var (
+		calls  atomic.Int32
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/limited":
+			if n == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "/slow":
+			if n == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	do := func(r *Retrier, method, path, body string) (int, int32, error) {
+		t.Helper()
+		calls.Store(0)
+		mu.Lock()
+		bodies = nil
+		mu.Unlock()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := (&http.Client{Transport: r}).Do(req)
+		if err != nil {
+			return 0, calls.Load(), err
+		}
+		defer resp.Body.Close()
+		io.ReadAll(resp.Body)
+		return resp.StatusCode, calls.Load(), nil
+	}
+	r := &Retrier{Timeout: 100 * time.Millisecond, Retries: 2, Backoff: time.Millisecond}
+
+	// a 429 is retried even for a call that is not idempotent, body and all
+	if code, n, err := do(r, http.MethodPost, "/limited", "payload"); err != nil || code != 200 || n != 2 {
+		t.Errorf("429: %d after %d calls, %v", code, n, err)
+	}
+	mu.Lock()
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Errorf("bodies: %q", bodies)
+	}
+	mu.Unlock()
+
+	// a 5xx only when it is idempotent, and the last one is returned
+	if code, n, _ := do(r, http.MethodPost, "/down", ""); code != 503 || n != 1 {
+		t.Errorf("5xx post: %d after %d calls", code, n)
+	}
+	if code, n, _ := do(r, http.MethodGet, "/down", ""); code != 503 || n != 3 {
+		t.Errorf("5xx get: %d after %d calls", code, n)
+	}
+	ri := &Retrier{Timeout: r.Timeout, Retries: 2, Backoff: time.Millisecond, Idempotent: func(*http.Request) bool { return true }}
+	if code, n, _ := do(ri, http.MethodPost, "/down", ""); code != 503 || n != 3 {
+		t.Errorf("5xx idempotent post: %d after %d calls", code, n)
+	}
+
+	// an attempt that runs out of time is cut off and tried again
+	if code, n, err := do(r, http.MethodGet, "/slow", ""); err != nil || code != 200 || n != 2 {
+		t.Errorf("timeout: %d after %d calls, %v", code, n, err)
+	}
+	if _, n, err := do(r, http.MethodPost, "/slow", ""); !errors.Is(err, context.DeadlineExceeded) || n != 1 {
+		t.Errorf("timeout post: %d calls, %v", n, err)
+	}
+}
+
+func TestRetrierDial(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	// nothing was sent, so even a post is tried again
+	ct := &countingTransport{}
+	r := &Retrier{Retries: 2, Backoff: time.Millisecond, Transport: ct}
+	req, _ := http.NewRequest(http.MethodPost, url+"/deployment.deploy", strings.NewReader("{}"))
+	if _, err := (&http.Client{Transport: r}).Do(req); err == nil {
+		t.Fatal("no error")
+	}
+	if n := ct.n.Load(); n != 3 {
+		t.Errorf("%d attempts", n)
+	}
+}
+
+func TestRetrierDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	// a wait past the call's deadline is not made
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	resp, err := (&http.Client{Transport: &Retrier{Retries: 3}}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("%d after %s", resp.StatusCode, time.Since(start))
+	}
+}
+
+func TestRetrierBudget(t *testing.T) {
+	r := &Retrier{Timeout: time.Second, Retries: 3, Backoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond}
+	if got, want := r.Budget(), 4*time.Second+400*time.Millisecond; got != want {
+		t.Errorf("Budget = %s; want %s", got, want)
+	}
+	if got := (&Retrier{Retries: 3}).Budget(); got != 0 {
+		t.Errorf("no timeout: Budget = %s", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("7"); !ok || d != 7*time.Second {
+		t.Errorf("seconds: %s %v", d, ok)
+	}
+	if d, ok := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); !ok || d <= 50*time.Second || d > time.Minute {
+		t.Errorf("date: %s %v", d, ok)
+	}
+	for _, s := range []string{"", "soon", "-1"} {
+		if _, ok := retryAfter(s); ok {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
+
+func TestRetrierBudgetDeadline(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	// without a deadline of its own, a call is still bounded by the budget, so
+	// a Retry-After past it is not waited for
+	r := &Retrier{Timeout: 200 * time.Millisecond, Retries: 3, Backoff: time.Millisecond}
+	start := time.Now()
+	resp, err := (&http.Client{Transport: r}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 || time.Since(start) > r.Budget() {
+		t.Errorf("%d after %d calls in %s", resp.StatusCode, calls.Load(), time.Since(start))
+	}
+}
@@ -0,0 +1,172 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTraceBody caps how much of a body a level-2 trace prints.
+const maxTraceBody = 64 << 10
+
+// requestIDHeaders are the response headers a request id is read from, first
+// match wins.
+var requestIDHeaders = []string{"X-Request-Id", "X-Cloud-Trace-Context", "X-Github-Request-Id", "Cf-Ray"}
+
+// Tracer is an http.RoundTripper that logs every call it carries to Out. At
+// level 1 it writes one line per call: method, url, status, latency, and the
+// request id when the server sent one. Level 2 adds the headers and bodies,
+// redacted as a Recorder redacts them.
+type Tracer struct {
+	Level     int
+	Out       io.Writer
+	Transport http.RoundTripper // nil means http.DefaultTransport
+}
+
+var trace struct {
+	mu    sync.Mutex
+	level int
+	out   io.Writer
+}
+
+// SetTrace turns tracing on at level (0 turns it off) for every client built
+// with Transport from now on, logging to out.
+func SetTrace(level int, out io.Writer) {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.level, trace.out = level, out
+}
+
+// Transport returns t wrapped in a Tracer when tracing is on, else t as is.
+// Every HTTP client the cli builds goes through it.
+func Transport(t http.RoundTripper) http.RoundTripper {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	if trace.level <= 0 {
+		return t
+	}
+	return &Tracer{Level: trace.level, Out: trace.out, Transport: t}
+}
+
+func (tr *Tracer) RoundTrip(r *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if tr.Level >= 2 && r.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	t := tr.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := t.RoundTrip(r)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	if err != nil {
+		fmt.Fprintf(&b, "%s %s error after %s: %v\n", r.Method, r.URL.Redacted(), latency, err)
+	} else {
+		fmt.Fprintf(&b, "%s %s %d %s", r.Method, r.URL.Redacted(), resp.StatusCode, latency)
+		for _, k := range requestIDHeaders {
+			if id := resp.Header.Get(k); id != "" {
+				fmt.Fprintf(&b, " request-id=%s", id)
+				break
+			}
+		}
+		b.WriteByte('\n')
+	}
+	if tr.Level >= 2 {
+		writeHeader(&b, "> ", r.Header)
+		writeBody(&b, "> ", r.Header.Get("Content-Type"), reqBody)
+		if err == nil {
+			respBody, rerr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			var rest io.Reader = bytes.NewReader(respBody)
+			if rerr != nil {
+				// hand the caller the error where it would have met it
+				rest = io.MultiReader(rest, errReader{rerr})
+			}
+			resp.Body = io.NopCloser(rest)
+			writeHeader(&b, "< ", resp.Header)
+			writeBody(&b, "< ", resp.Header.Get("Content-Type"), respBody)
+		}
+	}
+
+	trace.mu.Lock()
+	io.WriteString(tr.Out, b.String())
+	trace.mu.Unlock()
+	return resp, err
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+func writeHeader(b *strings.Builder, prefix string, h http.Header) {
+	h = RedactHeader(h)
+	for _, k := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[k] {
+			fmt.Fprintf(b, "  %s%s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+// writeBody writes a JSON or form body redacted, text as is, and anything else
+// as its size.
+func writeBody(b *strings.Builder, prefix, contentType string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	var s string
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		s = RedactForm(string(body))
+	case strings.HasSuffix(mt, "json") || (mt == "" && bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))):
+		s = string(RedactJSON(body))
+	case strings.HasPrefix(mt, "text/") && utf8.Valid(body):
+		s = string(body)
+	default:
+		fmt.Fprintf(b, "  %s[%d bytes %s]\n", prefix, len(body), contentType)
+		return
+	}
+	if len(s) > maxTraceBody {
+		s = fmt.Sprintf("%s... (%d more bytes)", s[:maxTraceBody], len(s)-maxTraceBody)
+	}
+	fmt.Fprintf(b, "  %s%s\n", prefix, s)
+}
+
+// formSecretKeys are the form fields redacted besides secretKeys: the oauth
+// authorization code.
+var formSecretKeys = map[string]bool{"code": true}
+
+// RedactForm returns the url-encoded form s with its secret fields replaced,
+// or s unchanged when it does not parse.
+func RedactForm(s string) string {
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return s
+	}
+	for k := range v {
+		if nk := normalizeKey(k); secretKeys[nk] || formSecretKeys[nk] {
+			v[k] = []string{Redacted}
+		}
+	}
+	return v.Encode()
+}
@@ -0,0 +1,92 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-42")
+		w.Write([]byte(`{"ok":true,"result":{"name":"web","env":{"DB_PASSWORD":"hunter2"}}}`))
+	}))
+	defer srv.Close()
+
+	call := func(level int, contentType, body string) (string, string) {
+		t.Helper()
+		var out bytes.Buffer
+		c := &http.Client{Transport: &Tracer{Level: level, Out: &out}}
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/deployment.get", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", contentType)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return out.String(), string(b)
+	}
+
+	got, body := call(1, "application/json", `{"name":"web"}`)
+	if !strings.HasPrefix(got, "POST "+srv.URL+"/deployment.get 200 ") || !strings.HasSuffix(got, " request-id=req-42\n") {
+		t.Errorf("level 1:\n%s", got)
+	}
+	if strings.Count(got, "\n") != 1 {
+		t.Errorf("level 1 logs more than a line:\n%s", got)
+	}
+	// the caller still gets the real body
+	if !strings.Contains(body, "hunter2") {
+		t.Errorf("body: %s", body)
+	}
+
+	got, body = call(2, "application/json", `{"name":"web","addEnv":{"K":"v1"},"spec":{"password":"p"}}`)
+	for _, want := range []string{
+		"  > Authorization: REDACTED\n",
+		`  > {"addEnv":{"K":"REDACTED"},"name":"web","spec":{"password":"REDACTED"}}` + "\n",
+		"  < X-Request-Id: req-42\n",
+		`  < {"ok":true,"result":{"env":{"DB_PASSWORD":"REDACTED"},"name":"web"}}` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("level 2: missing %q in\n%s", want, got)
+		}
+	}
+	for _, secret := range []string{"tok", "v1", `"p"`, "hunter2"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("level 2 logged %s:\n%s", secret, got)
+		}
+	}
+	if !strings.Contains(body, "hunter2") {
+		t.Errorf("body: %s", body)
+	}
+
+	got, _ = call(2, "application/x-www-form-urlencoded", "grant_type=authorization_code&code=abc&code_verifier=xyz&client_id=cli")
+	if !strings.Contains(got, "  > client_id=cli&code=REDACTED&code_verifier=REDACTED&grant_type=authorization_code\n") {
+		t.Errorf("form:\n%s", got)
+	}
+
+	got, _ = call(2, "application/octet-stream", "\x00\x01\x02")
+	if !strings.Contains(got, "  > [3 bytes application/octet-stream]\n") {
+		t.Errorf("binary:\n%s", got)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	defer SetTrace(0, nil)
+
+	base := &Recorder{}
+	if got := Transport(base); got != base {
+		t.Errorf("tracing off: %T", got)
+	}
+	var out bytes.Buffer
+	SetTrace(2, &out)
+	tr, ok := Transport(base).(*Tracer)
+	if !ok || tr.Level != 2 || tr.Out != &out || tr.Transport != base {
+		t.Errorf("tracing on: %+v", tr)
+	}
+}
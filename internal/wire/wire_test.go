@@ -0,0 +1,132 @@
+package wire
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSON(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{`{"name":"web","env":{"A":"1","B":"2"},"addEnv":{"C":"3"},"removeEnv":["D"]}`,
+			`{"addEnv":{"C":"REDACTED"},"env":{"A":"REDACTED","B":"REDACTED"},"name":"web","removeEnv":["D"]}`},
+		{`{"spec":{"server":"r.io","username":"u","password":"p"},"value":"eyJhdXRocyI6e319"}`,
+			`{"spec":{"password":"REDACTED","server":"r.io","username":"u"},"value":"REDACTED"}`},
+		{`{"config":{"url":"https://x","secret":"s"},"auth":{"type":"bearer","secret":""}}`,
+			`{"auth":{"secret":"","type":"bearer"},"config":{"secret":"REDACTED","url":"https://x"}}`},
+		{`{"ok":true,"result":{"items":[{"token":"t","access_token":"a","count":12345678901234567890}]}}`,
+			`{"ok":true,"result":{"items":[{"access_token":"REDACTED","count":12345678901234567890,"token":"REDACTED"}]}}`},
+		{`{"rules":[{"header":"x","value":"kept"}]}`, `{"rules":[{"header":"x","value":"kept"}]}`},
+		{`not json`, `not json`},
+	} {
+		if got := string(RedactJSON([]byte(tc.in))); got != tc.want {
+			t.Errorf("RedactJSON(%s)\n got %s\nwant %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{"Authorization": {"Bearer t"}, "Accept": {"application/json"}}
+	got := RedactHeader(h)
+	if got.Get("Authorization") != Redacted || got.Get("Accept") != "application/json" {
+		t.Errorf("got %v", got)
+	}
+	if h.Get("Authorization") != "Bearer t" {
+		t.Error("the original header changed")
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/envgroup.get":
+			w.Write([]byte(`{"ok":true,"result":{"name":"shared","env":{"A":"1"},"call":` + strconv.Itoa(calls) + `}}`))
+		default:
+			w.Write(b)
+		}
+	}))
+	defer srv.Close()
+	dir := filepath.Join(t.TempDir(), "rec")
+
+	post := func(c *http.Client, path, body string) (string, error) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		resp, err := c.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	// two commands recording into one directory
+	for _, path := range []string{"/envgroup.get", "/envgroup.update"} {
+		rec := &http.Client{Transport: &Recorder{Dir: dir}}
+		got, err := post(rec, path, `{"project":"acme","name":"shared","env":{"A":"2"}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the caller sees the real response
+		if path == "/envgroup.update" && !strings.Contains(got, `"A":"2"`) {
+			t.Errorf("%s: %s", path, got)
+		}
+	}
+	rec := &http.Client{Transport: &Recorder{Dir: dir}}
+	if _, err := post(rec, "/envgroup.get", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	xs, err := LoadExchanges(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xs) != 3 || xs[1].Request.URL != srv.URL+"/envgroup.update" {
+		t.Fatalf("recorded %d exchanges", len(xs))
+	}
+	raw, _ := os.ReadFile(filepath.Join(dir, "0002.json"))
+	if strings.Contains(string(raw), "tok") || strings.Contains(string(raw), `"2"`) {
+		t.Errorf("secrets recorded:\n%s", raw)
+	}
+	if fi, _ := os.Stat(filepath.Join(dir, "0001.json")); fi.Mode().Perm() != 0600 {
+		t.Errorf("mode %v", fi.Mode().Perm())
+	}
+
+	// replay under another host: each request takes the next matching exchange
+	srv.Close()
+	rep := &http.Client{Transport: &Replayer{Dir: dir}}
+	get := func() map[string]any {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "http://replay.invalid/envgroup.get", nil)
+		resp, err := rep.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var v struct{ Result map[string]any }
+		if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v.Result
+	}
+	if got := get(); got["call"] != float64(1) || got["env"].(map[string]any)["A"] != Redacted {
+		t.Errorf("first get: %v", got)
+	}
+	if got := get(); got["call"] != float64(3) {
+		t.Errorf("second get: %v", got)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://replay.invalid/envgroup.get", nil)
+	if _, err := rep.Do(req); err == nil || !strings.Contains(err.Error(), "no recorded POST /envgroup.get left") {
+		t.Errorf("exhausted: %v", err)
+	}
+}
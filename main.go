@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 
 	"github.com/deploys-app/deploys/internal/auth"
 	"github.com/deploys-app/deploys/internal/runner"
+	"github.com/deploys-app/deploys/internal/wire"
 )
 
 // version is set at release time via -ldflags "-X main.version=...". For other
@@ -43,24 +46,59 @@ func main() {
 	if selector == "" {
 		selector = os.Getenv("DEPLOYS_ACCOUNT")
 	}
-	explicit := selector != ""
+	// -v/-vv are global too: every http client built from here on (the api
+	// client, login's oauth calls, check-update) traces through wire.Transport.
+	verbosity, args := extractVerboseFlag(args)
+	wire.SetTrace(max(verbosity, debugLevel(os.Getenv("DEPLOYS_DEBUG"))), os.Stderr)
+	// -timeout, -retries, -backoff, and -max-backoff shape the api client's
+	// transport, so they are pre-scanned as well.
+	retrier, args, err := newRetrier(args)
+	if err != nil {
+		fail(err)
+	}
 	if len(args) == 0 {
-		// -account consumed the only token, e.g. `deploys -account x`.
+		// a global flag consumed the only token, e.g. `deploys -account x`.
 		runner.PrintUsage(os.Stdout)
 		return
 	}
+	// The active context (`deploys context use`, or DEPLOYS_CONTEXT) supplies
+	// the account and endpoint when neither is given. The endpoint resolved
+	// here goes to both the client and the runner, so login, auth status, and
+	// the journal all agree with it. Local commands (config show among them)
+	// resolve their own. A broken contexts file is ignored here and reported
+	// by Run.
+	endpoint := os.Getenv("DEPLOYS_ENDPOINT")
+	if !runner.IsLocalCommand(args[0]) {
+		if _, c, err := auth.ActiveContext(); err == nil && c != nil {
+			if selector == "" {
+				selector = c.Account
+			}
+			if endpoint == "" {
+				endpoint = c.Endpoint
+			}
+		}
+	}
+	explicit := selector != ""
 
 	rn := runner.Runner{
-		Output:  os.Stdout,
-		Version: resolveVersion(),
-		Account: selector,
+		Output:   os.Stdout,
+		Version:  resolveVersion(),
+		Account:  selector,
+		Endpoint: endpoint,
 	}
 
-	// Local utility commands (check-update/version) and the auth surface
+	// Local utility commands (check-update/version/history) and the auth surface
 	// (login/logout/auth) run without the pre-built client: the former are
 	// client-less, the latter establish or read credentials themselves.
-	if !runner.IsLocalCommand(args[0]) && !runner.IsAuthCommand(args[0]) {
-		c, err := newAPIClient(selector, explicit)
+	switch {
+	case runner.IsCompleteCommand(args[0]):
+		// tab completion must never fail the shell: without a usable
+		// credential it still completes commands and flags, just no api values
+		if c, err := newAPIClient(endpoint, selector, explicit, retrier); err == nil {
+			rn.API = c
+		}
+	case !runner.IsLocalCommand(args[0]) && !runner.IsAuthCommand(args[0]):
+		c, err := newAPIClient(endpoint, selector, explicit, retrier)
 		if err != nil {
 			fail(err)
 		}
@@ -110,25 +148,159 @@ func exitCode(err error) int {
 // selector must be known before the api client is built. It extracts only the
 // account email and must never be widened to read a token or any secret.
 func extractAccountFlag(args []string) (string, []string) {
-	var selector string
+	selector, _, rest := extractFlag(args, "account")
+	return selector, rest
+}
+
+// extractFlag pulls every -name/--name (in either "-name v" or "-name=v" form)
+// out of args and returns the last value, whether the flag was there at all,
+// and the remaining args. A trailing -name without a value counts as given
+// with an empty value. Nothing after a "--" is read: those args belong to the
+// command as given.
+func extractFlag(args []string, name string) (string, bool, []string) {
+	var (
+		value string
+		found bool
+	)
 	rest := make([]string, 0, len(args))
 	for i := 0; i < len(args); i++ {
 		a := args[i]
 		switch {
-		case a == "-account" || a == "--account":
+		case a == "--":
+			return value, found, append(rest, args[i:]...)
+		case a == "-"+name || a == "--"+name:
+			found = true
+			value = ""
 			if i+1 < len(args) {
-				selector = args[i+1]
+				value = args[i+1]
 				i++
 			}
-		case strings.HasPrefix(a, "-account="):
-			selector = strings.TrimPrefix(a, "-account=")
-		case strings.HasPrefix(a, "--account="):
-			selector = strings.TrimPrefix(a, "--account=")
+		case strings.HasPrefix(a, "-"+name+"="):
+			found, value = true, strings.TrimPrefix(a, "-"+name+"=")
+		case strings.HasPrefix(a, "--"+name+"="):
+			found, value = true, strings.TrimPrefix(a, "--"+name+"=")
 		default:
 			rest = append(rest, a)
 		}
 	}
-	return selector, rest
+	return value, found, rest
+}
+
+// globalValueFlags are the global flags main pre-scans that take a value.
+var globalValueFlags = []string{"account", "timeout", "retries", "backoff", "max-backoff"}
+
+// commandWord returns the index of the command word in args: the first arg
+// that is neither a flag nor the value of a global flag that takes one
+// (len(args) when there is none).
+func commandWord(args []string) int {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || !strings.HasPrefix(a, "-") {
+			return i
+		}
+		if name := strings.TrimLeft(a, "-"); slices.Contains(globalValueFlags, name) {
+			i++
+		}
+	}
+	return len(args)
+}
+
+// extractVerboseFlag pulls the global -v/-vv (or --v/--vv) out of the args
+// before the command word and returns the trace level they ask for plus the
+// remaining args. After the command word a -v is the command's own, or the
+// value of one of its flags (-args -v), so it is left alone. Like
+// extractAccountFlag it scans by hand, since the level must be set before any
+// client is built.
+func extractVerboseFlag(args []string) (int, []string) {
+	var level int
+	n := commandWord(args)
+	rest := make([]string, 0, len(args))
+	for _, a := range args[:n] {
+		switch a {
+		case "-v", "--v":
+			level = max(level, 1)
+		case "-vv", "--vv":
+			level = 2
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return level, append(rest, args[n:]...)
+}
+
+// debugLevel maps DEPLOYS_DEBUG to a trace level: unset, "0", or "false" is
+// off, "2" is -vv, and anything else is -v.
+func debugLevel(s string) int {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "0", "false":
+		return 0
+	case "2":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Defaults for the api client's transport (see newRetrier).
+const (
+	defaultTimeout    = 15 * time.Second
+	defaultRetries    = 2
+	defaultBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+// newRetrier builds the api client's retrying transport from the global
+// -timeout, -retries, -backoff, and -max-backoff flags, which it pulls out of
+// the args before the command word, else from DEPLOYS_TIMEOUT,
+// DEPLOYS_RETRIES, DEPLOYS_BACKOFF, and DEPLOYS_MAX_BACKOFF, else from the
+// defaults. The same flags after the command word are the command's own
+// (login's -timeout).
+func newRetrier(args []string) (*wire.Retrier, []string, error) {
+	r := &wire.Retrier{
+		Timeout:    defaultTimeout,
+		Retries:    defaultRetries,
+		Backoff:    defaultBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		Idempotent: runner.IdempotentRequest,
+	}
+	for _, o := range []struct {
+		name, env string
+		d         *time.Duration
+		n         *int
+	}{
+		{name: "retries", env: "DEPLOYS_RETRIES", n: &r.Retries},
+		{name: "backoff", env: "DEPLOYS_BACKOFF", d: &r.Backoff},
+		{name: "max-backoff", env: "DEPLOYS_MAX_BACKOFF", d: &r.MaxBackoff},
+		{name: "timeout", env: "DEPLOYS_TIMEOUT", d: &r.Timeout},
+	} {
+		n := commandWord(args)
+		v, found, rest := extractFlag(args[:n], o.name)
+		args = append(rest, args[n:]...)
+		source := "flag -" + o.name
+		if !found {
+			v, found = os.LookupEnv(o.env)
+			source = o.env
+		}
+		if !found || (source == o.env && v == "") {
+			continue
+		}
+		var err error
+		if o.n != nil {
+			*o.n, err = strconv.Atoi(v)
+			if err == nil && *o.n < 0 {
+				err = errors.New("must not be negative")
+			}
+		} else {
+			*o.d, err = time.ParseDuration(v)
+			if err == nil && *o.d < 0 {
+				err = errors.New("must not be negative")
+			}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value %q for %s: %w", v, source, err)
+		}
+	}
+	return r, args, nil
 }
 
 // newAPIClient builds the api client, resolving credentials in order:
@@ -141,22 +313,35 @@ func extractAccountFlag(args []string) (string, []string) {
 // (-account/DEPLOYS_ACCOUNT) that does not resolve is a hard error, not an ADC
 // fallthrough; with nothing configured at all it returns an AuthRequiredError so
 // main can print a login hint instead of a bare "unauthorized" round-trip.
-func newAPIClient(selector string, explicit bool) (*client.Client, error) {
+//
+// DEPLOYS_RECORD=dir saves every call's request and response into dir (see
+// package wire); DEPLOYS_REPLAY=dir answers the calls from such a recording
+// instead of the network, and then needs no credentials at all. Either way the
+// calls go through retrier, which times each attempt. endpoint is the api
+// endpoint main resolved (empty for the default).
+func newAPIClient(endpoint, selector string, explicit bool, retrier *wire.Retrier) (*client.Client, error) {
 	var (
 		token    = os.Getenv("DEPLOYS_TOKEN")
 		authUser = os.Getenv("DEPLOYS_AUTH_USER")
 		authPass = os.Getenv("DEPLOYS_AUTH_PASS")
-		endpoint = os.Getenv("DEPLOYS_ENDPOINT")
 	)
 
 	apiClient := &client.Client{
-		Endpoint: endpoint,
-		Channel:  api.AuditChannelCLI,
-		HTTPClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+		Endpoint:   endpoint,
+		Channel:    api.AuditChannelCLI,
+		HTTPClient: &http.Client{Transport: retrier},
 	}
 
+	// each attempt is traced (and recorded) on its own
+	if dir := os.Getenv("DEPLOYS_REPLAY"); dir != "" {
+		retrier.Transport = wire.Transport(&wire.Replayer{Dir: dir})
+		return apiClient, nil
+	}
+	if dir := os.Getenv("DEPLOYS_RECORD"); dir != "" {
+		retrier.Transport = &wire.Recorder{Dir: dir}
+	}
+	retrier.Transport = wire.Transport(retrier.Transport)
+
 	if authUser != "" && authPass != "" {
 		apiClient.Auth = func(r *http.Request) { r.SetBasicAuth(authUser, authPass) }
 		return apiClient, nil
@@ -181,7 +366,7 @@ func newAPIClient(selector string, explicit bool) (*client.Client, error) {
 		fmt.Fprintf(os.Stderr, "warning: stored session for %s expired — run 'deploys login'\n", acct.Email)
 	}
 
-	if adc, aerr := getDefaultToken(); aerr == nil && adc != "" {
+	if adc, aerr := getDefaultToken(retrier.Timeout); aerr == nil && adc != "" {
 		apiClient.Auth = bearerAuth(adc)
 		return apiClient, nil
 	}
@@ -254,8 +439,18 @@ func vcsVersion(info *debug.BuildInfo) string {
 	return v
 }
 
-func getDefaultToken() (string, error) {
-	cred, err := google.FindDefaultCredentials(context.Background())
+// getDefaultToken reads an access token from the application default
+// credentials. The lookup, and any metadata or token-endpoint fetch behind it,
+// is bounded by timeout (-timeout; 0 means none) like an api call.
+func getDefaultToken(timeout time.Duration) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cred, err := google.FindDefaultCredentials(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/deploys-app/api"
 
@@ -25,6 +26,7 @@ func TestExtractAccountFlag(t *testing.T) {
 		{"after subcommand", []string{"deployment", "list", "-account", "a@x"}, "a@x", []string{"deployment", "list"}},
 		{"only flag", []string{"-account", "a@x"}, "a@x", []string{}},
 		{"last wins", []string{"-account", "a@x", "x", "-account=b@y"}, "b@y", []string{"x"}},
+		{"after terminator", []string{"x", "--", "-account", "a@x"}, "", []string{"x", "--", "-account", "a@x"}},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -39,6 +41,93 @@ func TestExtractAccountFlag(t *testing.T) {
 	}
 }
 
+func TestExtractVerboseFlag(t *testing.T) {
+	cases := []struct {
+		name      string
+		in        []string
+		wantLevel int
+		wantRest  []string
+	}{
+		{"none", []string{"project", "list"}, 0, []string{"project", "list"}},
+		{"v", []string{"-v", "project", "list"}, 1, []string{"project", "list"}},
+		{"vv", []string{"-vv", "deployment", "get"}, 2, []string{"deployment", "get"}},
+		{"double dash", []string{"--v", "me", "get"}, 1, []string{"me", "get"}},
+		{"highest wins", []string{"-vv", "-v", "me"}, 2, []string{"me"}},
+		{"after global value", []string{"-timeout", "30s", "-v", "me"}, 1, []string{"-timeout", "30s", "me"}},
+		{"after command word", []string{"deployment", "get", "-vv"}, 0, []string{"deployment", "get", "-vv"}},
+		{"flag value", []string{"deployment", "deploy", "-args", "-v"}, 0, []string{"deployment", "deploy", "-args", "-v"}},
+		{"after terminator", []string{"--", "-v"}, 0, []string{"--", "-v"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			level, rest := extractVerboseFlag(c.in)
+			if level != c.wantLevel {
+				t.Errorf("level = %d; want %d", level, c.wantLevel)
+			}
+			if !reflect.DeepEqual(rest, c.wantRest) {
+				t.Errorf("rest = %#v; want %#v", rest, c.wantRest)
+			}
+		})
+	}
+
+	for in, want := range map[string]int{"": 0, "0": 0, "false": 0, "1": 1, "true": 1, "2": 2} {
+		if got := debugLevel(in); got != want {
+			t.Errorf("debugLevel(%q) = %d; want %d", in, got, want)
+		}
+	}
+}
+
+func TestNewRetrier(t *testing.T) {
+	for _, k := range []string{"DEPLOYS_TIMEOUT", "DEPLOYS_RETRIES", "DEPLOYS_BACKOFF", "DEPLOYS_MAX_BACKOFF"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("DEPLOYS_CONFIG_DIR", t.TempDir())
+
+	r, rest, err := newRetrier([]string{"project", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Timeout != defaultTimeout || r.Retries != defaultRetries || r.Backoff != defaultBackoff || r.MaxBackoff != defaultMaxBackoff {
+		t.Errorf("defaults: %+v", r)
+	}
+	if !reflect.DeepEqual(rest, []string{"project", "list"}) {
+		t.Errorf("rest = %#v", rest)
+	}
+
+	// flags win over the environment
+	t.Setenv("DEPLOYS_TIMEOUT", "1m")
+	t.Setenv("DEPLOYS_RETRIES", "5")
+	r, rest, err = newRetrier([]string{"-timeout=30s", "--max-backoff", "2s", "project", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Timeout != 30*time.Second || r.Retries != 5 || r.MaxBackoff != 2*time.Second {
+		t.Errorf("flags: %+v", r)
+	}
+	if !reflect.DeepEqual(rest, []string{"project", "list"}) {
+		t.Errorf("rest = %#v", rest)
+	}
+
+	// after the command word the flags are the command's own (login's
+	// -timeout); the global ones come from the environment
+	r, rest, err = newRetrier([]string{"-retries", "0", "login", "-timeout", "5m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Timeout != time.Minute || r.Retries != 0 {
+		t.Errorf("login: %+v", r)
+	}
+	if !reflect.DeepEqual(rest, []string{"login", "-timeout", "5m"}) {
+		t.Errorf("rest = %#v", rest)
+	}
+
+	for _, args := range [][]string{{"-retries", "-1", "me"}, {"-backoff", "soon", "me"}, {"-timeout"}} {
+		if _, _, err := newRetrier(args); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
 func TestExitCode(t *testing.T) {
 	cases := []struct {
 		name string